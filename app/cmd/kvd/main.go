@@ -2,9 +2,12 @@ package main
 
 import (
 	"log"
+	"os"
 	"redis-go/internal/commands"
+	"redis-go/internal/config"
 	"redis-go/internal/db"
 	"redis-go/internal/server"
+	"strconv"
 	"time"
 )
 
@@ -23,13 +26,31 @@ func main() {
 
 	d.Save("./data/store.json")
 
+	// parameters can be overridden on the command line, e.g. --requirepass secret
+	cfg := config.New()
+	if err := cfg.SetArgs(os.Args[1:]); err != nil {
+		log.Fatal(err)
+	}
+
 	// create a new commands registry
-	commands := commands.NewRegistry(d)
+	commands := commands.NewRegistry(d, cfg)
 
-	srv := &server.Server{Address: ":6379",
-		Commands: commands,
+	if aclfile, _ := cfg.Get("aclfile"); aclfile != "" {
+		if err := commands.GetACL().Load(aclfile); err != nil {
+			log.Fatal("error loading ACLs: ", err)
+		}
+	}
+
+	// port 0 disables the plaintext listener, tls-port 0 the TLS one
+	srv := &server.Server{Commands: commands}
+	if port := cfg.Int("port"); port != 0 {
+		srv.Address = ":" + strconv.Itoa(port)
+	}
+	if port := cfg.Int("tls-port"); port != 0 {
+		srv.TLSAddress = ":" + strconv.Itoa(port)
 	}
-	log.Println("starting kv server on port 6379")
+	srv.UnixSocket, _ = cfg.Get("unixsocket")
+	log.Println("starting kv server")
 	if err := srv.ListenAndServe(); err != nil {
 		panic(err)
 	}
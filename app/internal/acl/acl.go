@@ -0,0 +1,267 @@
+// Package acl implements Redis-style access control lists: users with
+// passwords, the commands they may run and the keys and channels they may
+// access.
+package acl
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Categories lists the ACL command categories in the order ACL CAT shows
+// them.
+var Categories = []string{
+	"keyspace", "read", "write", "set", "sortedset", "list", "hash", "string",
+	"bitmap", "hyperloglog", "geo", "stream", "pubsub", "admin", "fast", "slow",
+	"blocking", "dangerous", "connection", "transaction", "scripting",
+}
+
+// Command describes a command for the rules that refer to it.
+type Command struct {
+	Name        string // lower case
+	Categories  []string
+	Subcommands []string // lower case, for container commands like CONFIG
+}
+
+type table struct {
+	commands   map[string]Command
+	categories map[string][]string // category -> commands
+}
+
+// ACL holds the users. The default user starts out on, without a password
+// and allowed to do everything.
+type ACL struct {
+	t *table
+
+	mu    sync.RWMutex
+	users map[string]*User
+
+	log aclLog
+}
+
+func New(commands []Command) *ACL {
+	t := &table{
+		commands:   make(map[string]Command, len(commands)),
+		categories: make(map[string][]string, len(Categories)),
+	}
+	for _, cat := range Categories {
+		t.categories[cat] = nil
+	}
+	for _, cmd := range commands {
+		t.commands[cmd.Name] = cmd
+		for _, cat := range cmd.Categories {
+			if _, ok := t.categories[cat]; !ok {
+				panic("acl: unknown category " + cat + " for " + cmd.Name)
+			}
+			t.categories[cat] = append(t.categories[cat], cmd.Name)
+		}
+	}
+
+	a := &ACL{t: t, users: make(map[string]*User)}
+	a.users["default"] = a.defaultUser()
+	a.log.max = 128
+	return a
+}
+
+func (a *ACL) defaultUser() *User {
+	u := newUser("default")
+	for _, rule := range []string{"on", "nopass", "~*", "&*", "+@all"} {
+		u.apply(rule, a.t)
+	}
+	return u
+}
+
+// RuleError is a rule ACL SETUSER could not apply.
+type RuleError struct {
+	Rule string
+	Err  error
+}
+
+func (e *RuleError) Error() string {
+	return fmt.Sprintf("Error in ACL SETUSER modifier '%s': %v", e.Rule, e.Err)
+}
+
+// SetUser applies rules to the named user, creating it if needed. Either
+// all rules are applied or, on error, none.
+func (a *ACL) SetUser(name string, rules ...string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	u, err := a.buildUser(a.users[name], name, rules)
+	if err != nil {
+		return err
+	}
+	a.users[name] = u
+	return nil
+}
+
+// buildUser returns a copy of u, or a new user, with rules applied.
+func (a *ACL) buildUser(u *User, name string, rules []string) (*User, error) {
+	if u == nil {
+		u = newUser(name)
+	} else {
+		u = u.clone()
+	}
+	for _, rule := range rules {
+		if err := u.apply(rule, a.t); err != nil {
+			return nil, &RuleError{Rule: rule, Err: err}
+		}
+	}
+	return u, nil
+}
+
+// DelUser deletes the named users and returns how many existed. The default
+// user cannot be deleted.
+func (a *ACL) DelUser(names ...string) (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, name := range names {
+		if name == "default" {
+			return 0, fmt.Errorf("The 'default' user cannot be removed")
+		}
+	}
+	n := 0
+	for _, name := range names {
+		if _, ok := a.users[name]; ok {
+			delete(a.users, name)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (a *ACL) GetUser(name string) (*User, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	u, ok := a.users[name]
+	return u, ok
+}
+
+// Users returns the user names in sorted order.
+func (a *ACL) Users() []string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	names := make([]string, 0, len(a.users))
+	for name := range a.users {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// List returns every user in ACL LIST format.
+func (a *ACL) List() []string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	names := make([]string, 0, len(a.users))
+	for name := range a.users {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	lines := make([]string, len(names))
+	for i, name := range names {
+		lines[i] = a.users[name].describe()
+	}
+	return lines
+}
+
+// Authenticate reports whether password is valid for the named user, which
+// must exist and be on.
+func (a *ACL) Authenticate(name, password string) bool {
+	u, ok := a.GetUser(name)
+	if !ok || !u.enabled {
+		return false
+	}
+	return u.checkPassword(password)
+}
+
+// NoPass reports whether the named user exists, is on and needs no password.
+func (a *ACL) NoPass(name string) bool {
+	u, ok := a.GetUser(name)
+	return ok && u.enabled && u.nopass
+}
+
+// CategoryCommands returns the commands in a category.
+func (a *ACL) CategoryCommands(category string) ([]string, bool) {
+	cmds, ok := a.t.categories[strings.ToLower(category)]
+	return cmds, ok
+}
+
+// Request is what a command is about to do, for permission checks.
+type Request struct {
+	Command    string // lower case
+	Subcommand string // lower case, for container commands
+	Keys       []Key
+	Channels   []string
+	Patterns   bool // Channels are subscription patterns
+}
+
+// Key is a key a command accesses. Read is set when the command reads the
+// value and Write when it changes it; metadata access needs neither. Any
+// marks keys only named at run time, such as those looked up through a SORT
+// BY or GET pattern: they need access to every key, and Name is only used to
+// report a denial.
+type Key struct {
+	Name        string
+	Read, Write bool
+	Any         bool
+}
+
+// DeniedError is a request the user has no permission for. Reason is
+// "command", "key" or "channel" and Object the command, key or channel that
+// was denied.
+type DeniedError struct {
+	Reason string
+	Object string
+	User   string
+}
+
+func (e *DeniedError) Error() string {
+	switch e.Reason {
+	case "key":
+		return "NOPERM No permissions to access a key"
+	case "channel":
+		return "NOPERM No permissions to access a channel"
+	}
+	return fmt.Sprintf("NOPERM User %s has no permissions to run the '%s' command", e.User, e.Object)
+}
+
+// Describe explains the denial the way ACL DRYRUN reports it.
+func (e *DeniedError) Describe() string {
+	if e.Reason == "command" {
+		return fmt.Sprintf("User %s has no permissions to run the '%s' command", e.User, e.Object)
+	}
+	return fmt.Sprintf("User %s has no permissions to access the '%s' %s", e.User, e.Object, e.Reason)
+}
+
+// Check returns a *DeniedError if the named user may not make req.
+func (a *ACL) Check(name string, req Request) error {
+	u, ok := a.GetUser(name)
+
+	object := req.Command
+	if req.Subcommand != "" {
+		object += "|" + req.Subcommand
+	}
+	if !ok || !u.canRun(req.Command, req.Subcommand) {
+		return &DeniedError{Reason: "command", Object: object, User: name}
+	}
+
+	for _, k := range req.Keys {
+		if !u.canAccessKey(k) {
+			return &DeniedError{Reason: "key", Object: k.Name, User: name}
+		}
+	}
+	for _, ch := range req.Channels {
+		if !u.canAccessChannel(ch, req.Patterns) {
+			return &DeniedError{Reason: "channel", Object: ch, User: name}
+		}
+	}
+	return nil
+}
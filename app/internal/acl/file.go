@@ -0,0 +1,80 @@
+package acl
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The aclfile holds one "user <name> <rule> ..." line per user, as printed by
+// ACL LIST. Passwords are stored as their SHA-256 digests.
+
+// Load replaces all users with the ones in filename. The file is checked as
+// a whole first, so a bad file leaves the current users in place. A default
+// user is created as usual when the file does not define one.
+func (a *ACL) Load(filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	users := make(map[string]*User)
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "user" {
+			return fmt.Errorf("%s:%d: line should start with user keyword", filename, n)
+		}
+		name := fields[1]
+		if _, ok := users[name]; ok {
+			return fmt.Errorf("%s:%d: duplicate user '%s' found", filename, n, name)
+		}
+		u, err := a.buildUser(nil, name, fields[2:])
+		if err != nil {
+			return fmt.Errorf("%s:%d: %v", filename, n, err)
+		}
+		users[name] = u
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+
+	if _, ok := users["default"]; !ok {
+		users["default"] = a.defaultUser()
+	}
+
+	a.mu.Lock()
+	a.users = users
+	a.mu.Unlock()
+	return nil
+}
+
+// Save writes all users to filename, replacing it atomically.
+func (a *ACL) Save(filename string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	for _, line := range a.List() {
+		w.WriteString(line + "\n")
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
@@ -0,0 +1,105 @@
+package acl
+
+import (
+	"sync"
+	"time"
+)
+
+// LogEntry is an ACL LOG entry. Repeated denials of the same kind are
+// folded into one entry with a count.
+type LogEntry struct {
+	ID         int64
+	Count      int
+	Reason     string // command, key, channel or auth
+	Context    string
+	Object     string
+	Username   string
+	ClientInfo string
+	Created    time.Time
+	Updated    time.Time
+}
+
+// logGroupWindow is how long a new denial can still be folded into an
+// existing entry.
+const logGroupWindow = time.Minute
+
+type aclLog struct {
+	mu      sync.Mutex
+	entries []*LogEntry // newest first
+	max     int
+	nextID  int64
+}
+
+// SetLogMaxLen sets how many entries ACL LOG keeps.
+func (a *ACL) SetLogMaxLen(n int) {
+	a.log.mu.Lock()
+	defer a.log.mu.Unlock()
+
+	a.log.max = n
+	a.log.trim()
+}
+
+// Log records a denied command or a failed authentication.
+func (a *ACL) Log(reason, object, username, clientInfo string) {
+	l := &a.log
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	for i, e := range l.entries {
+		if e.Reason == reason && e.Object == object && e.Username == username &&
+			now.Sub(e.Updated) < logGroupWindow {
+			e.Count++
+			e.Updated = now
+			e.ClientInfo = clientInfo
+			copy(l.entries[1:i+1], l.entries[:i])
+			l.entries[0] = e
+			return
+		}
+	}
+
+	e := &LogEntry{
+		ID:         l.nextID,
+		Count:      1,
+		Reason:     reason,
+		Context:    "toplevel",
+		Object:     object,
+		Username:   username,
+		ClientInfo: clientInfo,
+		Created:    now,
+		Updated:    now,
+	}
+	l.nextID++
+	l.entries = append([]*LogEntry{e}, l.entries...)
+	l.trim()
+}
+
+// LogEntries returns up to n entries, newest first. n < 0 returns all.
+func (a *ACL) LogEntries(n int) []LogEntry {
+	l := &a.log
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if n < 0 || n > len(l.entries) {
+		n = len(l.entries)
+	}
+	entries := make([]LogEntry, n)
+	for i := range entries {
+		entries[i] = *l.entries[i]
+	}
+	return entries
+}
+
+func (a *ACL) ResetLog() {
+	a.log.mu.Lock()
+	defer a.log.mu.Unlock()
+
+	a.log.entries = nil
+}
+
+// trim drops the oldest entries over the limit. Callers must hold l.mu.
+func (l *aclLog) trim() {
+	if len(l.entries) > l.max {
+		l.entries = l.entries[:l.max]
+	}
+}
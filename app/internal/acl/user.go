@@ -0,0 +1,374 @@
+package acl
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"maps"
+	"redis-go/internal/helper"
+	"slices"
+	"strings"
+)
+
+var (
+	errSyntax         = errors.New("Syntax error")
+	errUnknownCommand = errors.New("Unknown command or category name in ACL")
+	errBadHash        = errors.New("The password hash must be exactly 64 characters and contain only lowercase hexadecimal characters")
+	errNoSuchPassword = errors.New("The password you are trying to remove from the user does not exist")
+)
+
+// User is an ACL user. A User is never modified once it is stored in the
+// ACL; SETUSER builds a changed copy and swaps it in.
+type User struct {
+	name      string
+	enabled   bool
+	nopass    bool
+	passwords []string // hex SHA-256 digests
+
+	allowed  map[string]bool // command -> allowed
+	subs     map[string]bool // "command|subcommand" -> allowed, overriding allowed
+	cmdRules []string        // command rules in the order given, for display
+
+	keys        []keyPattern
+	allChannels bool
+	channels    []string
+}
+
+type keyPattern struct {
+	pattern     string
+	read, write bool
+}
+
+// newUser returns a user that is off and may do nothing.
+func newUser(name string) *User {
+	return &User{
+		name:    name,
+		allowed: make(map[string]bool),
+		subs:    make(map[string]bool),
+	}
+}
+
+func (u *User) clone() *User {
+	c := *u
+	c.passwords = slices.Clone(u.passwords)
+	c.allowed = maps.Clone(u.allowed)
+	c.subs = maps.Clone(u.subs)
+	c.cmdRules = slices.Clone(u.cmdRules)
+	c.keys = slices.Clone(u.keys)
+	c.channels = slices.Clone(u.channels)
+	return &c
+}
+
+func hashPassword(password string) string {
+	sum := sha256.Sum256([]byte(password))
+	return hex.EncodeToString(sum[:])
+}
+
+func validHash(h string) bool {
+	if len(h) != 64 {
+		return false
+	}
+	for i := 0; i < len(h); i++ {
+		if !(h[i] >= '0' && h[i] <= '9' || h[i] >= 'a' && h[i] <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// apply changes u according to one ACL SETUSER rule.
+func (u *User) apply(rule string, t *table) error {
+	if rule == "" {
+		return errSyntax
+	}
+
+	switch rule[0] {
+	case '>', '#':
+		h := rule[1:]
+		if rule[0] == '>' {
+			h = hashPassword(h)
+		} else if !validHash(h) {
+			return errBadHash
+		}
+		if !slices.Contains(u.passwords, h) {
+			u.passwords = append(u.passwords, h)
+		}
+		u.nopass = false
+		return nil
+
+	case '<', '!':
+		h := rule[1:]
+		if rule[0] == '<' {
+			h = hashPassword(h)
+		} else if !validHash(h) {
+			return errBadHash
+		}
+		i := slices.Index(u.passwords, h)
+		if i < 0 {
+			return errNoSuchPassword
+		}
+		u.passwords = slices.Delete(u.passwords, i, i+1)
+		return nil
+
+	case '~':
+		u.keys = append(u.keys, keyPattern{pattern: rule[1:], read: true, write: true})
+		return nil
+
+	case '%':
+		perms, pattern, ok := strings.Cut(rule[1:], "~")
+		if !ok || perms == "" {
+			return errSyntax
+		}
+		p := keyPattern{pattern: pattern}
+		for _, c := range strings.ToUpper(perms) {
+			switch c {
+			case 'R':
+				p.read = true
+			case 'W':
+				p.write = true
+			default:
+				return errSyntax
+			}
+		}
+		u.keys = append(u.keys, p)
+		return nil
+
+	case '&':
+		if rule == "&*" {
+			u.allChannels, u.channels = true, nil
+		} else if !u.allChannels {
+			u.channels = append(u.channels, rule[1:])
+		}
+		return nil
+
+	case '+', '-':
+		return u.applyCommandRule(strings.ToLower(rule), t)
+	}
+
+	switch strings.ToLower(rule) {
+	case "on":
+		u.enabled = true
+	case "off":
+		u.enabled = false
+	case "nopass":
+		u.nopass, u.passwords = true, nil
+	case "resetpass":
+		u.nopass, u.passwords = false, nil
+	case "allkeys":
+		u.keys = append(u.keys, keyPattern{pattern: "*", read: true, write: true})
+	case "resetkeys":
+		u.keys = nil
+	case "allchannels":
+		u.allChannels, u.channels = true, nil
+	case "resetchannels":
+		u.allChannels, u.channels = false, nil
+	case "allcommands":
+		return u.applyCommandRule("+@all", t)
+	case "nocommands":
+		return u.applyCommandRule("-@all", t)
+	case "reset":
+		for _, r := range []string{"resetpass", "resetkeys", "resetchannels", "off", "-@all"} {
+			u.apply(r, t)
+		}
+	default:
+		return errSyntax
+	}
+	return nil
+}
+
+// applyCommandRule handles +command, -command, +@category, -@category and
+// +command|subcommand rules.
+func (u *User) applyCommandRule(rule string, t *table) error {
+	allow := rule[0] == '+'
+	name := rule[1:]
+
+	if name == "@all" {
+		clear(u.allowed)
+		clear(u.subs)
+		if allow {
+			for cmd := range t.commands {
+				u.allowed[cmd] = true
+			}
+		}
+		u.cmdRules = []string{rule}
+		return nil
+	}
+
+	switch cat, isCategory := strings.CutPrefix(name, "@"); {
+	case isCategory:
+		cmds, ok := t.categories[cat]
+		if !ok {
+			return errUnknownCommand
+		}
+		for _, cmd := range cmds {
+			u.setCommand(cmd, allow)
+		}
+
+	case strings.Contains(name, "|"):
+		cmd, sub, _ := strings.Cut(name, "|")
+		info, ok := t.commands[cmd]
+		if !ok || !slices.Contains(info.Subcommands, sub) {
+			return errUnknownCommand
+		}
+		u.subs[name] = allow
+
+	default:
+		if _, ok := t.commands[name]; !ok {
+			return errUnknownCommand
+		}
+		u.setCommand(name, allow)
+	}
+
+	u.cmdRules = append(u.cmdRules, rule)
+	return nil
+}
+
+// setCommand allows or denies cmd as a whole, dropping its subcommand rules.
+func (u *User) setCommand(cmd string, allow bool) {
+	if allow {
+		u.allowed[cmd] = true
+	} else {
+		delete(u.allowed, cmd)
+	}
+	for name := range u.subs {
+		if strings.HasPrefix(name, cmd+"|") {
+			delete(u.subs, name)
+		}
+	}
+}
+
+func (u *User) canRun(cmd, sub string) bool {
+	if sub != "" {
+		if allow, ok := u.subs[cmd+"|"+sub]; ok {
+			return allow
+		}
+	}
+	return u.allowed[cmd]
+}
+
+func (u *User) canAccessKey(k Key) bool {
+	for _, p := range u.keys {
+		if (k.Read && !p.read) || (k.Write && !p.write) {
+			continue
+		}
+		if k.Any {
+			if p.pattern == "*" {
+				return true
+			}
+			continue
+		}
+		if helper.Match(p.pattern, k.Name, false) {
+			return true
+		}
+	}
+	return false
+}
+
+// canAccessChannel checks a channel name, or with literal a subscription
+// pattern, which must appear as is among the user's channel patterns.
+func (u *User) canAccessChannel(channel string, literal bool) bool {
+	if u.allChannels {
+		return true
+	}
+	for _, p := range u.channels {
+		if p == channel || (!literal && helper.Match(p, channel, false)) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPassword reports whether password matches one of the user's
+// passwords. The digests are compared in constant time.
+func (u *User) checkPassword(password string) bool {
+	if u.nopass {
+		return true
+	}
+	sum := sha256.Sum256([]byte(password))
+	ok := false
+	for _, h := range u.passwords {
+		want, _ := hex.DecodeString(h)
+		if subtle.ConstantTimeCompare(want, sum[:]) == 1 {
+			ok = true
+		}
+	}
+	return ok
+}
+
+func (u *User) Name() string {
+	return u.name
+}
+
+// Flags returns the user's flags as shown by ACL GETUSER.
+func (u *User) Flags() []string {
+	flags := []string{"off"}
+	if u.enabled {
+		flags[0] = "on"
+	}
+	if u.nopass {
+		flags = append(flags, "nopass")
+	}
+	return flags
+}
+
+// Passwords returns the hex SHA-256 digests of the user's passwords.
+func (u *User) Passwords() []string {
+	return slices.Clone(u.passwords)
+}
+
+// Commands returns the user's command rules, e.g. "+@all -config|set".
+func (u *User) Commands() string {
+	if len(u.cmdRules) == 0 {
+		return "-@all"
+	}
+	return strings.Join(u.cmdRules, " ")
+}
+
+// Keys returns the user's key patterns, e.g. "~app:* %R~shared:*".
+func (u *User) Keys() string {
+	parts := make([]string, len(u.keys))
+	for i, p := range u.keys {
+		switch {
+		case p.read && p.write:
+			parts[i] = "~" + p.pattern
+		case p.read:
+			parts[i] = "%R~" + p.pattern
+		default:
+			parts[i] = "%W~" + p.pattern
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// Channels returns the user's channel patterns, e.g. "&*".
+func (u *User) Channels() string {
+	if u.allChannels {
+		return "&*"
+	}
+	parts := make([]string, len(u.channels))
+	for i, p := range u.channels {
+		parts[i] = "&" + p
+	}
+	return strings.Join(parts, " ")
+}
+
+// describe returns the user as an ACL LIST line, which is also the aclfile
+// format.
+func (u *User) describe() string {
+	parts := []string{"user", u.name}
+	parts = append(parts, u.Flags()...)
+	for _, h := range u.passwords {
+		parts = append(parts, "#"+h)
+	}
+	if keys := u.Keys(); keys != "" {
+		parts = append(parts, keys)
+	}
+	if channels := u.Channels(); channels != "" {
+		parts = append(parts, channels)
+	} else {
+		parts = append(parts, "resetchannels")
+	}
+	parts = append(parts, u.Commands())
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,174 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"redis-go/internal/acl"
+	"redis-go/internal/protocol"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ACL WHOAMI needs the connection's user and is handled by the server.
+
+const errNoACLFile = "ERR This Redis instance is not configured to use an ACL file. " +
+	"You may want to specify users via the ACL SETUSER command and then issue a CONFIG REWRITE " +
+	"(assuming you have a Redis configuration file set) in order to store users in the Redis configuration."
+
+func (r *Registry) registerACLCommands() {
+
+	r.cmds["ACL"] = func(args []string, _ time.Duration) string {
+		if len(args) < 1 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		switch strings.ToUpper(args[0]) {
+		case "SETUSER":
+			// ACL SETUSER username [rule ...]
+			if len(args) < 2 {
+				return "-ERR wrong number of arguments\r\n"
+			}
+			if err := r.acl.SetUser(args[1], args[2:]...); err != nil {
+				return protocol.Error("ERR " + err.Error())
+			}
+			return protocol.SimpleString("OK")
+
+		case "GETUSER":
+			// ACL GETUSER username
+			if len(args) != 2 {
+				return "-ERR wrong number of arguments\r\n"
+			}
+			u, ok := r.acl.GetUser(args[1])
+			if !ok {
+				return protocol.NullBulkString()
+			}
+			return protocol.Array(
+				protocol.BulkString("flags"), protocol.BulkArray(u.Flags()),
+				protocol.BulkString("passwords"), protocol.BulkArray(u.Passwords()),
+				protocol.BulkString("commands"), protocol.BulkString(u.Commands()),
+				protocol.BulkString("keys"), protocol.BulkString(u.Keys()),
+				protocol.BulkString("channels"), protocol.BulkString(u.Channels()),
+				protocol.BulkString("selectors"), protocol.Array(),
+			)
+
+		case "DELUSER":
+			// ACL DELUSER username [username ...]
+			if len(args) < 2 {
+				return "-ERR wrong number of arguments\r\n"
+			}
+			n, err := r.acl.DelUser(args[1:]...)
+			if err != nil {
+				return protocol.Error("ERR " + err.Error())
+			}
+			return protocol.Integer(n)
+
+		case "USERS":
+			return protocol.BulkArray(r.acl.Users())
+
+		case "LIST":
+			return protocol.BulkArray(r.acl.List())
+
+		case "CAT":
+			// ACL CAT [category]
+			if len(args) > 2 {
+				return "-ERR wrong number of arguments\r\n"
+			}
+			if len(args) == 1 {
+				return protocol.BulkArray(acl.Categories)
+			}
+			cmds, ok := r.acl.CategoryCommands(args[1])
+			if !ok {
+				return protocol.Error("ERR Unknown category '" + args[1] + "'")
+			}
+			cmds = slices.Clone(cmds)
+			slices.Sort(cmds)
+			return protocol.BulkArray(cmds)
+
+		case "LOG":
+			// ACL LOG [count | RESET]
+			if len(args) > 2 {
+				return "-ERR wrong number of arguments\r\n"
+			}
+			count := -1
+			if len(args) == 2 {
+				if strings.EqualFold(args[1], "RESET") {
+					r.acl.ResetLog()
+					return protocol.SimpleString("OK")
+				}
+				n, err := strconv.Atoi(args[1])
+				if err != nil || n < 0 {
+					return protocol.Error("ERR " + errNotInteger.Error())
+				}
+				count = n
+			}
+			var elems []string
+			for _, e := range r.acl.LogEntries(count) {
+				elems = append(elems, aclLogEntry(e))
+			}
+			return protocol.Array(elems...)
+
+		case "DRYRUN":
+			// ACL DRYRUN username command [arg ...]
+			if len(args) < 3 {
+				return "-ERR wrong number of arguments\r\n"
+			}
+			if _, ok := r.acl.GetUser(args[1]); !ok {
+				return protocol.Error("ERR User '" + args[1] + "' not found")
+			}
+			cmd := strings.ToUpper(args[2])
+			if _, ok := commandTable[cmd]; !ok {
+				return protocol.Error("ERR Command '" + args[2] + "' not found")
+			}
+
+			err := r.acl.Check(args[1], aclRequest(cmd, args[3:]))
+			var denied *acl.DeniedError
+			if errors.As(err, &denied) {
+				return protocol.BulkString(denied.Describe())
+			}
+			return protocol.SimpleString("OK")
+
+		case "SAVE":
+			filename, _ := r.config.Get("aclfile")
+			if filename == "" {
+				return protocol.Error(errNoACLFile)
+			}
+			if err := r.acl.Save(filename); err != nil {
+				log.Println("error saving ACLs:", err)
+				return protocol.Error("ERR There was an error trying to save the ACLs. Please check the server logs for more information")
+			}
+			return protocol.SimpleString("OK")
+
+		case "LOAD":
+			filename, _ := r.config.Get("aclfile")
+			if filename == "" {
+				return protocol.Error(errNoACLFile)
+			}
+			if err := r.acl.Load(filename); err != nil {
+				return protocol.Error("ERR Error loading ACLs: " + err.Error())
+			}
+			return protocol.SimpleString("OK")
+
+		default:
+			return protocol.Error("ERR unknown subcommand '" + args[0] + "'. Try ACL HELP.")
+		}
+	}
+}
+
+func aclLogEntry(e acl.LogEntry) string {
+	age := time.Since(e.Created).Seconds()
+	return protocol.Array(
+		protocol.BulkString("count"), protocol.Integer(e.Count),
+		protocol.BulkString("reason"), protocol.BulkString(e.Reason),
+		protocol.BulkString("context"), protocol.BulkString(e.Context),
+		protocol.BulkString("object"), protocol.BulkString(e.Object),
+		protocol.BulkString("username"), protocol.BulkString(e.Username),
+		protocol.BulkString("age-seconds"), protocol.BulkString(fmt.Sprintf("%.3f", age)),
+		protocol.BulkString("client-info"), protocol.BulkString(e.ClientInfo),
+		protocol.BulkString("entry-id"), protocol.Integer(int(e.ID)),
+		protocol.BulkString("timestamp-created"), protocol.Integer(int(e.Created.UnixMilli())),
+		protocol.BulkString("timestamp-last-updated"), protocol.Integer(int(e.Updated.UnixMilli())),
+	)
+}
@@ -0,0 +1,61 @@
+package commands
+
+import "testing"
+
+func TestACLKeyPatterns(t *testing.T) {
+	r := newTestRegistry()
+	expect(t, r, "+OK\r\n", "ACL", "SETUSER", "app", "on", "nopass", "+@all", "~app:*")
+	app := &Caller{User: "app", Addr: "127.0.0.1:1"}
+	noKey := "-NOPERM No permissions to access a key\r\n"
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"SET", "app:a", "1"}, "+OK\r\n"},
+		{[]string{"SET", "secret:a", "1"}, noKey},
+		{[]string{"MGET", "app:a", "secret:a"}, noKey},
+		{[]string{"SORT", "app:l", "STORE", "secret:l"}, noKey},
+		{[]string{"SORT", "app:l", "BY", "nosort", "GET", "secret:*"}, noKey},
+		{[]string{"SORT", "app:l", "BY", "secret:*"}, noKey},
+		{[]string{"SORT_RO", "app:l", "BY", "nosort", "GET", "secret:*"}, noKey},
+		{[]string{"SORT", "app:l", "BY", "nosort", "GET", "#"}, "*0\r\n"},
+		{[]string{"LRANGE", "secret:l", "0", "-1"}, noKey},
+		{[]string{"SMEMBERS", "secret:s"}, noKey},
+		{[]string{"HGETALL", "secret:h"}, noKey},
+	}
+	for _, tt := range tests {
+		if got := r.Execute(app, tt.args[0], tt.args[1:], 0); got != tt.want {
+			t.Errorf("%q = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+
+	expect(t, r, "+OK\r\n", "ACL", "SETUSER", "app", "~*")
+	if got := r.Execute(app, "SORT", []string{"app:l", "BY", "nosort", "GET", "secret:*"}, 0); got != "*0\r\n" {
+		t.Errorf("SORT with GET pattern and ~* = %q", got)
+	}
+}
+
+func TestACLCommandRules(t *testing.T) {
+	r := newTestRegistry()
+	expect(t, r, "+OK\r\n", "ACL", "SETUSER", "ro", "on", "nopass", "+@read", "allkeys")
+	ro := &Caller{User: "ro", Addr: "127.0.0.1:1"}
+
+	if got := r.Execute(ro, "GET", []string{"k"}, 0); got != "*0\r\n" {
+		t.Errorf("GET = %q", got)
+	}
+	want := "-NOPERM User ro has no permissions to run the 'set' command\r\n"
+	if got := r.Execute(ro, "SET", []string{"k", "v"}, 0); got != want {
+		t.Errorf("SET = %q, want %q", got, want)
+	}
+
+	// the denial is logged
+	if got := run(r, "ACL", "LOG"); got == "*0\r\n" {
+		t.Errorf("ACL LOG is empty after a denial")
+	}
+
+	off := &Caller{User: "nobody", Addr: "127.0.0.1:1"}
+	if got := r.Execute(off, "GET", []string{"k"}, 0); got[0] != '-' {
+		t.Errorf("GET as an unknown user = %q, want an error", got)
+	}
+}
@@ -0,0 +1,274 @@
+package commands
+
+import (
+	"redis-go/internal/db"
+	"redis-go/internal/protocol"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	errBitOffset     = "-ERR bit offset is not an integer or out of range\r\n"
+	errBitFieldType  = "-ERR Invalid bitfield type. Use something like i16 u8. Note that u64 is not supported but i64 is.\r\n"
+	errBitFieldRO    = "-ERR BITFIELD_RO only supports the GET subcommand\r\n"
+	errNotIntOrRange = "-ERR value is not an integer or out of range\r\n"
+)
+
+// parseBitRange parses the optional "start end [BYTE | BIT]" arguments of
+// BITCOUNT and BITPOS. It returns nil when no range was given.
+func parseBitRange(args []string, endOptional bool) (*db.BitRange, string) {
+	if len(args) == 0 {
+		return nil, ""
+	}
+	if len(args) > 3 || (len(args) == 1 && !endOptional) {
+		return nil, "-ERR syntax error\r\n"
+	}
+
+	rng := &db.BitRange{}
+	var err error
+	if rng.Start, err = strconv.Atoi(args[0]); err != nil {
+		return nil, errNotIntOrRange
+	}
+	if len(args) >= 2 {
+		if rng.End, err = strconv.Atoi(args[1]); err != nil {
+			return nil, errNotIntOrRange
+		}
+		rng.HasEnd = true
+	}
+	if len(args) == 3 {
+		switch strings.ToUpper(args[2]) {
+		case "BIT":
+			rng.Bit = true
+		case "BYTE":
+		default:
+			return nil, "-ERR syntax error\r\n"
+		}
+	}
+	return rng, ""
+}
+
+// parseBitFieldType parses a type such as i16 or u8.
+func parseBitFieldType(s string) (signed bool, width int, ok bool) {
+	if len(s) < 2 {
+		return false, 0, false
+	}
+	switch s[0] {
+	case 'i', 'I':
+		signed = true
+	case 'u', 'U':
+	default:
+		return false, 0, false
+	}
+
+	width, err := strconv.Atoi(s[1:])
+	if err != nil || width < 1 || (signed && width > 64) || (!signed && width > 63) {
+		return false, 0, false
+	}
+	return signed, width, true
+}
+
+// parseBitFieldOffset parses an offset, where "#N" means N times the width.
+func parseBitFieldOffset(s string, width int) (int, bool) {
+	mult := 1
+	if strings.HasPrefix(s, "#") {
+		mult = width
+		s = s[1:]
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 || n > db.MaxBitOffset/int64(mult) {
+		return 0, false
+	}
+	offset := int(n) * mult
+	if offset+width-1 > db.MaxBitOffset {
+		return 0, false
+	}
+	return offset, true
+}
+
+func (r *Registry) registerBitmapCommands() {
+
+	r.cmds["SETBIT"] = func(args []string, _ time.Duration) string {
+		if len(args) != 3 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		offset, ok := parseBitFieldOffset(args[1], 1)
+		if !ok || strings.HasPrefix(args[1], "#") {
+			return errBitOffset
+		}
+		if args[2] != "0" && args[2] != "1" {
+			return "-ERR bit is not an integer or out of range\r\n"
+		}
+
+		old, err := r.db.SetBit(args[0], offset, int(args[2][0]-'0'))
+		if err != nil {
+			return protocol.Error(err.Error())
+		}
+		return protocol.Integer(old)
+	}
+
+	r.cmds["GETBIT"] = func(args []string, _ time.Duration) string {
+		if len(args) != 2 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		offset, ok := parseBitFieldOffset(args[1], 1)
+		if !ok || strings.HasPrefix(args[1], "#") {
+			return errBitOffset
+		}
+
+		bit, err := r.db.GetBit(args[0], offset)
+		if err != nil {
+			return protocol.Error(err.Error())
+		}
+		return protocol.Integer(bit)
+	}
+
+	r.cmds["BITCOUNT"] = func(args []string, _ time.Duration) string {
+		// BITCOUNT key [start end [BYTE | BIT]]
+		if len(args) < 1 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		rng, errReply := parseBitRange(args[1:], false)
+		if errReply != "" {
+			return errReply
+		}
+
+		n, err := r.db.BitCount(args[0], rng)
+		if err != nil {
+			return protocol.Error(err.Error())
+		}
+		return protocol.Integer(n)
+	}
+
+	r.cmds["BITPOS"] = func(args []string, _ time.Duration) string {
+		// BITPOS key bit [start [end [BYTE | BIT]]]
+		if len(args) < 2 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+		if args[1] != "0" && args[1] != "1" {
+			return "-ERR The bit argument must be 1 or 0.\r\n"
+		}
+
+		rng, errReply := parseBitRange(args[2:], true)
+		if errReply != "" {
+			return errReply
+		}
+
+		pos, err := r.db.BitPos(args[0], int(args[1][0]-'0'), rng)
+		if err != nil {
+			return protocol.Error(err.Error())
+		}
+		return protocol.Integer(pos)
+	}
+
+	r.cmds["BITOP"] = func(args []string, _ time.Duration) string {
+		// BITOP AND | OR | XOR | NOT destkey key [key ...]
+		if len(args) < 3 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		op := strings.ToUpper(args[0])
+		switch op {
+		case "AND", "OR", "XOR":
+		case "NOT":
+			if len(args) != 3 {
+				return "-ERR BITOP NOT must be called with a single source key.\r\n"
+			}
+		default:
+			return "-ERR syntax error\r\n"
+		}
+
+		n, err := r.db.BitOp(op, args[1], args[2:]...)
+		if err != nil {
+			return protocol.Error(err.Error())
+		}
+		return protocol.Integer(n)
+	}
+
+	bitField := func(readOnly bool) CommandFunc {
+		return func(args []string, _ time.Duration) string {
+			// BITFIELD key [GET type offset] [SET type offset value]
+			//   [INCRBY type offset increment] [OVERFLOW WRAP | SAT | FAIL] ...
+			if len(args) < 1 {
+				return "-ERR wrong number of arguments\r\n"
+			}
+
+			var ops []db.BitFieldOp
+			overflow := "WRAP"
+			for rest := args[1:]; len(rest) > 0; {
+				kind := strings.ToUpper(rest[0])
+
+				if kind == "OVERFLOW" {
+					if readOnly {
+						return errBitFieldRO
+					}
+					if len(rest) < 2 {
+						return "-ERR syntax error\r\n"
+					}
+					overflow = strings.ToUpper(rest[1])
+					if overflow != "WRAP" && overflow != "SAT" && overflow != "FAIL" {
+						return "-ERR Invalid OVERFLOW type specified\r\n"
+					}
+					rest = rest[2:]
+					continue
+				}
+
+				nargs := 3
+				switch kind {
+				case "GET":
+				case "SET", "INCRBY":
+					if readOnly {
+						return errBitFieldRO
+					}
+					nargs = 4
+				default:
+					return "-ERR syntax error\r\n"
+				}
+				if len(rest) < nargs {
+					return "-ERR syntax error\r\n"
+				}
+
+				op := db.BitFieldOp{Kind: kind, Overflow: overflow}
+				var ok bool
+				if op.Signed, op.Width, ok = parseBitFieldType(rest[1]); !ok {
+					return errBitFieldType
+				}
+				if op.Offset, ok = parseBitFieldOffset(rest[2], op.Width); !ok {
+					return errBitOffset
+				}
+				if nargs == 4 {
+					v, err := strconv.ParseInt(rest[3], 10, 64)
+					if err != nil {
+						return errNotIntOrRange
+					}
+					op.Value = v
+				}
+
+				ops = append(ops, op)
+				rest = rest[nargs:]
+			}
+
+			results, found, err := r.db.BitField(args[0], ops)
+			if err != nil {
+				return protocol.Error(err.Error())
+			}
+
+			elems := make([]string, len(results))
+			for i, v := range results {
+				if found[i] {
+					elems[i] = ":" + strconv.FormatInt(v, 10) + "\r\n"
+				} else {
+					elems[i] = protocol.NullBulkString()
+				}
+			}
+			return protocol.Array(elems...)
+		}
+	}
+
+	r.cmds["BITFIELD"] = bitField(false)
+	r.cmds["BITFIELD_RO"] = bitField(true)
+}
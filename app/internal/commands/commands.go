@@ -1,30 +1,88 @@
 package commands
 
 import (
+	"errors"
 	"fmt"
+	"redis-go/internal/acl"
+	"redis-go/internal/config"
 	"redis-go/internal/db"
+	"redis-go/internal/protocol"
 	"strconv"
-	"strings"
 	"time"
 )
 
 type CommandFunc func(args []string, ttl time.Duration) string
 
 type Registry struct {
-	db   *db.DB
-	cmds map[string]CommandFunc
+	db      *db.DB
+	config  *config.Config
+	acl     *acl.ACL
+	cmds    map[string]CommandFunc
+	started time.Time
+}
+
+// Caller is the client a command runs for. A nil Caller runs commands
+// without permission checks.
+type Caller struct {
+	User string
+	Addr string
+}
+
+// String describes the client for the ACL log.
+func (c *Caller) String() string {
+	return fmt.Sprintf("addr=%s user=%s", c.Addr, c.User)
 }
 
 func (r *Registry) GetDB() *db.DB {
 	return r.db
 }
 
-func NewRegistry(db *db.DB) *Registry {
+func (r *Registry) GetConfig() *config.Config {
+	return r.config
+}
+
+func (r *Registry) GetACL() *acl.ACL {
+	return r.acl
+}
+
+func NewRegistry(db *db.DB, cfg *config.Config) *Registry {
 	r := &Registry{
-		db:   db,
-		cmds: make(map[string]CommandFunc),
+		db:      db,
+		config:  cfg,
+		acl:     acl.New(aclCommands()),
+		cmds:    make(map[string]CommandFunc),
+		started: time.Now(),
 	}
 
+	cfg.Watch("set-max-intset-entries", func(v string) {
+		n, _ := strconv.Atoi(v)
+		db.SetMaxIntsetEntries(n)
+	})
+	cfg.Watch("hll-sparse-max-bytes", func(v string) {
+		n, _ := strconv.Atoi(v)
+		db.SetMaxHLLSparseBytes(n)
+	})
+	cfg.Watch("client-output-buffer-limit", func(v string) {
+		limits, _ := config.ParseOutputBufferLimits(v)
+		l := limits["pubsub"]
+		db.SetPubSubOutputLimit(l.Hard, l.Soft, l.SoftSeconds)
+	})
+	cfg.Watch("notify-keyspace-events", func(v string) {
+		db.SetNotifyKeyspaceEvents(v)
+	})
+	cfg.Watch("requirepass", func(v string) {
+		// requirepass is the password of the default user
+		if v == "" {
+			r.acl.SetUser("default", "resetpass", "nopass")
+		} else {
+			r.acl.SetUser("default", "resetpass", ">"+v)
+		}
+	})
+	cfg.Watch("acllog-max-len", func(v string) {
+		n, _ := strconv.Atoi(v)
+		r.acl.SetLogMaxLen(n)
+	})
+
 	r.cmds["PING"] = func(args []string, _ time.Duration) string {
 		return "+PONG\r\n"
 	}
@@ -50,15 +108,11 @@ func NewRegistry(db *db.DB) *Registry {
 	}
 
 	r.cmds["DEL"] = func(args []string, _ time.Duration) string {
-		if len(args) != 1 {
+		if len(args) < 1 {
 			return "-ERR wrong number of arguments\r\n"
 		}
 
-		if ok := r.db.Delete(args[0]); ok {
-			return "+1\r\n"
-		}
-
-		return "-0\r\n"
+		return protocol.Integer(r.db.Delete(args...))
 	}
 
 	r.cmds["LPUSH"] = func(args []string, _ time.Duration) string {
@@ -98,88 +152,67 @@ func NewRegistry(db *db.DB) *Registry {
 			return "-ERR\r\n"
 		}
 
-		arr := r.db.LRange(args[0], start, end)
-		if arr == nil {
-			return "+0\r\n"
-		}
-
-		joinarr := strings.Join(arr, ",")
-
-		return joinarr
+		return protocol.BulkArray(r.db.LRange(args[0], start, end))
 	}
 
-	r.cmds["SADD"] = func(args []string, _ time.Duration) string {
-		if len(args) < 2 {
-			return "-ERR wrong number of arguments\r\n"
-		}
-
-		r.db.SAdd(args[0], args[1:]...)
+	r.cmds["FLUSHALL"] = func(args []string, _ time.Duration) string {
+		r.db.Flush()
 		return "+OK\r\n"
 	}
 
-	r.cmds["SMEMBERS"] = func(args []string, _ time.Duration) string {
-		if len(args) != 1 {
-			return "-ERR wrong number of arguments\r\n"
-		}
-
-		arr := r.db.SMembers(args[0])
-		if arr == nil {
-			return "+0\r\n"
+	r.registerStringCommands()
+	r.registerBitmapCommands()
+	r.registerHyperLogLogCommands()
+	r.registerSetCommands()
+	r.registerHashCommands()
+	r.registerHashExpireCommands()
+	r.registerGeoCommands()
+	r.registerGenericCommands()
+	r.registerScanCommands()
+	r.registerSortCommands()
+	r.registerDumpCommands()
+	r.registerPubSubCommands()
+	r.registerInfoCommands()
+	r.registerConfigCommands()
+	r.registerACLCommands()
+
+	for name := range r.cmds {
+		if _, ok := commandTable[name]; !ok {
+			panic("commands: " + name + " is missing from the command table")
 		}
-
-		joinarr := strings.Join(arr, ",")
-
-		return joinarr
-
 	}
 
-	r.cmds["HGET"] = func(args []string, _ time.Duration) string {
-		if len(args) != 2 {
-			return "-ERR wrong number of arguments\r\n"
-		}
+	return r
+}
 
-		if val, ok := r.db.HGet(args[0], args[1]); ok {
-			return fmt.Sprintf("$%d\r\n%s\r\n", len(val), val)
-		}
-		return "*0\r\n"
+// Execute runs cmd after checking that c may run it.
+func (r *Registry) Execute(c *Caller, cmd string, args []string, ttl time.Duration) string {
+	fn, ok := r.cmds[cmd]
+	if !ok {
+		return "-ERR unknown command\r\n"
 	}
-
-	r.cmds["HSET"] = func(args []string, _ time.Duration) string {
-		if len(args) < 3 {
-			return "-ERR wrong number of arguments\r\n"
-		}
-
-		r.db.HSet(args[0], args[1], args[2])
-		return "+OK\r\n"
+	if reply := r.Authorize(c, cmd, args); reply != "" {
+		return reply
 	}
 
-	r.cmds["HGETALL"] = func(args []string, _ time.Duration) string {
-		if len(args) != 1 {
-			return "-ERR wrong number of arguments\r\n"
-		}
-
-		arr := r.db.HGetAll(args[0])
-		if arr == nil {
-			return "+0\r\n"
-		}
-
-		joinarr := strings.Join(arr, ",")
+	return fn(args, ttl)
+}
 
-		return joinarr
+// Authorize checks c's permissions for cmd. It returns the error reply if
+// the command is denied, after recording the denial in the ACL log, and ""
+// otherwise. The server calls it for the commands it handles itself.
+func (r *Registry) Authorize(c *Caller, cmd string, args []string) string {
+	if c == nil {
+		return ""
 	}
 
-	r.cmds["FLUSHALL"] = func(args []string, _ time.Duration) string {
-		r.db.Flush()
-		return "+OK\r\n"
+	err := r.acl.Check(c.User, aclRequest(cmd, args))
+	if err == nil {
+		return ""
 	}
-
-	return r
-}
-
-func (r *Registry) Execute(cmd string, args []string, ttl time.Duration) string {
-	if fn, ok := r.cmds[cmd]; ok {
-		return fn(args, ttl)
+	var denied *acl.DeniedError
+	if errors.As(err, &denied) {
+		r.acl.Log(denied.Reason, denied.Object, c.User, c.String())
 	}
-
-	return "-ERR unknown command\r\n"
+	return protocol.Error(err.Error())
 }
@@ -0,0 +1,24 @@
+package commands
+
+import (
+	"redis-go/internal/config"
+	"redis-go/internal/db"
+	"testing"
+)
+
+func newTestRegistry() *Registry {
+	return NewRegistry(db.New(), config.New())
+}
+
+// run executes a command without an ACL caller and returns its raw reply.
+func run(r *Registry, cmd string, args ...string) string {
+	return r.Execute(nil, cmd, args, 0)
+}
+
+// expect runs a command and fails the test unless it replies with want.
+func expect(t *testing.T, r *Registry, want string, cmd string, args ...string) {
+	t.Helper()
+	if got := run(r, cmd, args...); got != want {
+		t.Errorf("%s %q = %q, want %q", cmd, args, got, want)
+	}
+}
@@ -0,0 +1,57 @@
+package commands
+
+import (
+	"redis-go/internal/helper"
+	"redis-go/internal/protocol"
+	"strings"
+	"time"
+)
+
+func (r *Registry) registerConfigCommands() {
+
+	r.cmds["CONFIG"] = func(args []string, _ time.Duration) string {
+		if len(args) < 1 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		switch strings.ToUpper(args[0]) {
+		case "GET":
+			// CONFIG GET parameter [parameter ...]
+			if len(args) < 2 {
+				return "-ERR wrong number of arguments\r\n"
+			}
+
+			// each argument is a glob pattern; a parameter matched by several
+			// patterns is reported once
+			seen := make(map[string]bool)
+			var elems []string
+			for _, pattern := range args[1:] {
+				for _, name := range r.config.Names() {
+					if seen[name] || !helper.Match(pattern, name, true) {
+						continue
+					}
+					seen[name] = true
+					val, _ := r.config.Get(name)
+					elems = append(elems, protocol.BulkString(name), protocol.BulkString(val))
+				}
+			}
+			return protocol.Array(elems...)
+
+		case "SET":
+			// CONFIG SET parameter value [parameter value ...]
+			if len(args) < 3 || len(args)%2 == 0 {
+				return "-ERR wrong number of arguments\r\n"
+			}
+
+			for i := 1; i < len(args); i += 2 {
+				if err := r.config.Set(args[i], args[i+1]); err != nil {
+					return protocol.Error("ERR " + err.Error())
+				}
+			}
+			return protocol.SimpleString("OK")
+
+		default:
+			return protocol.Error("ERR unknown subcommand '" + args[0] + "'. Try CONFIG HELP.")
+		}
+	}
+}
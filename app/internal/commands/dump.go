@@ -0,0 +1,251 @@
+package commands
+
+import (
+	"bufio"
+	"net"
+	"redis-go/internal/db"
+	"redis-go/internal/protocol"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func (r *Registry) registerDumpCommands() {
+
+	r.cmds["DUMP"] = func(args []string, _ time.Duration) string {
+		// DUMP key
+		if len(args) != 1 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		payload, _, _, ok := r.db.Dump(args[0])
+		if !ok {
+			return protocol.NullBulkString()
+		}
+		return protocol.BulkString(string(payload))
+	}
+
+	r.cmds["RESTORE"] = func(args []string, _ time.Duration) string {
+		// RESTORE key ttl serialized-value [REPLACE] [ABSTTL] [IDLETIME seconds] [FREQ frequency]
+		if len(args) < 3 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		ttl, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return protocol.Error("ERR " + errNotInteger.Error())
+		}
+		if ttl < 0 {
+			return "-ERR Invalid TTL value, must be >= 0\r\n"
+		}
+
+		// There is no LRU or LFU eviction, so IDLETIME and FREQ are
+		// validated and otherwise ignored.
+		var replace, absTTL, idle, freq bool
+		rest := args[3:]
+		for i := 0; i < len(rest); i++ {
+			switch opt := strings.ToUpper(rest[i]); {
+			case opt == "REPLACE":
+				replace = true
+			case opt == "ABSTTL":
+				absTTL = true
+			case opt == "IDLETIME" && i+1 < len(rest) && !freq:
+				n, err := strconv.ParseInt(rest[i+1], 10, 64)
+				if err != nil {
+					return protocol.Error("ERR " + errNotInteger.Error())
+				}
+				if n < 0 {
+					return "-ERR Invalid IDLETIME value, must be >= 0\r\n"
+				}
+				idle = true
+				i++
+			case opt == "FREQ" && i+1 < len(rest) && !idle:
+				n, err := strconv.ParseInt(rest[i+1], 10, 64)
+				if err != nil {
+					return protocol.Error("ERR " + errNotInteger.Error())
+				}
+				if n < 0 || n > 255 {
+					return "-ERR Invalid FREQ value, must be >= 0 and <= 255\r\n"
+				}
+				freq = true
+				i++
+			default:
+				return "-ERR syntax error\r\n"
+			}
+		}
+
+		var expiresAt time.Time
+		switch {
+		case ttl == 0:
+		case absTTL:
+			expiresAt = time.UnixMilli(ttl)
+		default:
+			expiresAt = time.Now().Add(time.Duration(ttl) * time.Millisecond)
+		}
+
+		if err := r.db.Restore(args[0], []byte(args[2]), expiresAt, replace); err != nil {
+			return protocol.Error(err.Error())
+		}
+		return "+OK\r\n"
+	}
+
+	r.cmds["MIGRATE"] = func(args []string, _ time.Duration) string {
+		// MIGRATE host port key|"" destination-db timeout [COPY] [REPLACE]
+		//   [AUTH password | AUTH2 username password] [KEYS key [key ...]]
+		if len(args) < 5 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		var copyKeys, replace bool
+		var auth []string
+		keys := []string{args[2]}
+		rest := args[5:]
+	options:
+		for i := 0; i < len(rest); i++ {
+			switch strings.ToUpper(rest[i]) {
+			case "COPY":
+				copyKeys = true
+			case "REPLACE":
+				replace = true
+			case "AUTH":
+				if i+1 >= len(rest) {
+					return "-ERR syntax error\r\n"
+				}
+				auth = rest[i+1 : i+2]
+				i++
+			case "AUTH2":
+				if i+2 >= len(rest) {
+					return "-ERR syntax error\r\n"
+				}
+				auth = rest[i+1 : i+3]
+				i += 2
+			case "KEYS":
+				if args[2] != "" {
+					return "-ERR When using MIGRATE KEYS option, the key argument must be set to the empty string\r\n"
+				}
+				keys = rest[i+1:]
+				break options
+			default:
+				return "-ERR syntax error\r\n"
+			}
+		}
+
+		dbIndex, err := strconv.Atoi(args[3])
+		if err != nil {
+			return protocol.Error("ERR " + errNotInteger.Error())
+		}
+		if dbIndex != 0 {
+			// there is a single keyspace
+			return "-ERR DB index is out of range\r\n"
+		}
+		timeout, err := strconv.Atoi(args[4])
+		if err != nil {
+			return protocol.Error("ERR " + errNotInteger.Error())
+		}
+		if timeout <= 0 {
+			timeout = 1000
+		}
+
+		return r.migrate(net.JoinHostPort(args[0], args[1]), time.Duration(timeout)*time.Millisecond, keys, auth, copyKeys, replace)
+	}
+}
+
+// migrate sends keys to the instance at addr with RESTORE and deletes the
+// ones it accepted, unless copyKeys is set. auth holds the AUTH arguments
+// for the target, if any. Keys are not locked while they are in flight; one
+// written to during the transfer is kept on the source, which then holds
+// the newer value, and the reply is an error naming it.
+func (r *Registry) migrate(addr string, timeout time.Duration, keys, auth []string, copyKeys, replace bool) string {
+	type dumped struct {
+		key     string
+		payload []byte
+		ttl     int64
+		version db.KeyVersion
+	}
+
+	var batch []dumped
+	for _, key := range keys {
+		payload, expiresAt, version, ok := r.db.Dump(key)
+		if !ok {
+			continue
+		}
+		var ttl int64
+		if !expiresAt.IsZero() {
+			ttl = max(time.Until(expiresAt).Milliseconds(), 1)
+		}
+		batch = append(batch, dumped{key, payload, ttl, version})
+	}
+	if len(batch) == 0 {
+		return "+NOKEY\r\n"
+	}
+
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return "-IOERR error or timeout connecting to the client\r\n"
+	}
+	defer conn.Close()
+
+	rd := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+
+	// Some servers greet new connections; PING and skip replies until the
+	// PONG so the RESTORE replies line up.
+	conn.SetDeadline(time.Now().Add(timeout))
+	if len(auth) > 0 {
+		w.WriteString(protocol.BulkArray(append([]string{"AUTH"}, auth...)))
+	}
+	w.WriteString(protocol.BulkArray([]string{"PING"}))
+	if err := w.Flush(); err != nil {
+		return "-IOERR error or timeout writing to target instance\r\n"
+	}
+	for {
+		reply, err := protocol.ReadReply(rd)
+		if err != nil {
+			return "-IOERR error or timeout reading to target instance\r\n"
+		}
+		if reply.IsError() {
+			return protocol.Error("ERR Target instance replied with error: " + reply.Str)
+		}
+		if reply.Kind == '+' && reply.Str == "PONG" {
+			break
+		}
+	}
+
+	conn.SetDeadline(time.Now().Add(timeout))
+	for _, d := range batch {
+		cmd := []string{"RESTORE", d.key, strconv.FormatInt(d.ttl, 10), string(d.payload)}
+		if replace {
+			cmd = append(cmd, "REPLACE")
+		}
+		w.WriteString(protocol.BulkArray(cmd))
+	}
+	if err := w.Flush(); err != nil {
+		return "-IOERR error or timeout writing to target instance\r\n"
+	}
+
+	var lastErr string
+	moved := make(map[string]db.KeyVersion)
+	for _, d := range batch {
+		conn.SetDeadline(time.Now().Add(timeout))
+		reply, err := protocol.ReadReply(rd)
+		if err != nil {
+			lastErr = "IOERR error or timeout reading to target instance"
+			break
+		}
+		if reply.IsError() {
+			lastErr = "ERR Target instance replied with error: " + reply.Str
+			continue
+		}
+		moved[d.key] = d.version
+	}
+
+	if !copyKeys && len(moved) > 0 {
+		if kept := r.db.DeleteUnchanged(moved); len(kept) > 0 && lastErr == "" {
+			lastErr = "ERR keys written to during MIGRATE were not removed from the source: " + strings.Join(kept, " ")
+		}
+	}
+	if lastErr != "" {
+		return protocol.Error(lastErr)
+	}
+	return "+OK\r\n"
+}
@@ -0,0 +1,73 @@
+package commands
+
+import (
+	"bufio"
+	"net"
+	"redis-go/internal/protocol"
+	"strconv"
+	"testing"
+)
+
+// fakeTarget accepts one MIGRATE connection, answers PING and acknowledges
+// every RESTORE, calling beforeReply first.
+func fakeTarget(t *testing.T, beforeReply func()) (host, port string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		rd := bufio.NewReader(conn)
+		for {
+			cmd, err := protocol.ReadArray(rd)
+			if err != nil {
+				return
+			}
+			switch cmd[0] {
+			case "PING":
+				conn.Write([]byte("+PONG\r\n"))
+			case "RESTORE":
+				beforeReply()
+				conn.Write([]byte("+OK\r\n"))
+			}
+		}
+	}()
+
+	addr := ln.Addr().(*net.TCPAddr)
+	return addr.IP.String(), strconv.Itoa(addr.Port)
+}
+
+func TestMigrateMovesKeys(t *testing.T) {
+	r := newTestRegistry()
+	run(r, "SET", "a", "1")
+	run(r, "SET", "b", "2")
+
+	host, port := fakeTarget(t, func() {})
+	expect(t, r, "+OK\r\n", "MIGRATE", host, port, "", "0", "1000", "KEYS", "a", "b")
+	expect(t, r, ":0\r\n", "EXISTS", "a", "b")
+}
+
+func TestMigrateReportsKeysWrittenInFlight(t *testing.T) {
+	r := newTestRegistry()
+	run(r, "SET", "a", "1")
+	run(r, "SET", "b", "2")
+
+	written := false
+	host, port := fakeTarget(t, func() {
+		if !written {
+			run(r, "APPEND", "b", "x")
+			written = true
+		}
+	})
+	expect(t, r, "-ERR keys written to during MIGRATE were not removed from the source: b\r\n",
+		"MIGRATE", host, port, "", "0", "1000", "KEYS", "a", "b")
+	expect(t, r, ":0\r\n", "EXISTS", "a")
+	expect(t, r, "$2\r\n2x\r\n", "GET", "b")
+}
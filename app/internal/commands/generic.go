@@ -0,0 +1,132 @@
+package commands
+
+import (
+	"redis-go/internal/protocol"
+	"strings"
+	"time"
+)
+
+func (r *Registry) registerGenericCommands() {
+
+	r.cmds["OBJECT"] = func(args []string, _ time.Duration) string {
+		if len(args) < 1 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		switch strings.ToUpper(args[0]) {
+		case "ENCODING":
+			if len(args) != 2 {
+				return "-ERR wrong number of arguments\r\n"
+			}
+			enc, ok := r.db.ObjectEncoding(args[1])
+			if !ok {
+				return protocol.NullBulkString()
+			}
+			return protocol.BulkString(enc)
+		default:
+			return protocol.Error("ERR unknown subcommand '" + args[0] + "'. Try OBJECT HELP.")
+		}
+	}
+
+	r.cmds["UNLINK"] = func(args []string, _ time.Duration) string {
+		// UNLINK key [key ...]
+		if len(args) < 1 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		return protocol.Integer(r.db.Delete(args...))
+	}
+
+	r.cmds["EXISTS"] = func(args []string, _ time.Duration) string {
+		// EXISTS key [key ...]
+		if len(args) < 1 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		return protocol.Integer(r.db.Exists(args...))
+	}
+
+	r.cmds["TOUCH"] = func(args []string, _ time.Duration) string {
+		// TOUCH key [key ...]
+		if len(args) < 1 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		return protocol.Integer(r.db.Touch(args...))
+	}
+
+	r.cmds["TYPE"] = func(args []string, _ time.Duration) string {
+		// TYPE key
+		if len(args) != 1 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		return protocol.SimpleString(r.db.Type(args[0]))
+	}
+
+	r.cmds["RENAME"] = func(args []string, _ time.Duration) string {
+		// RENAME key newkey
+		if len(args) != 2 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		if err := r.db.Rename(args[0], args[1]); err != nil {
+			return protocol.Error(err.Error())
+		}
+		return "+OK\r\n"
+	}
+
+	r.cmds["RENAMENX"] = func(args []string, _ time.Duration) string {
+		// RENAMENX key newkey
+		if len(args) != 2 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		ok, err := r.db.RenameNX(args[0], args[1])
+		if err != nil {
+			return protocol.Error(err.Error())
+		}
+		return protocol.Integer(boolToInt(ok))
+	}
+
+	r.cmds["COPY"] = func(args []string, _ time.Duration) string {
+		// COPY source destination [REPLACE]
+		if len(args) < 2 || len(args) > 3 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		replace := false
+		if len(args) == 3 {
+			if strings.ToUpper(args[2]) != "REPLACE" {
+				return "-ERR syntax error\r\n"
+			}
+			replace = true
+		}
+
+		ok, err := r.db.Copy(args[0], args[1], replace)
+		if err != nil {
+			return protocol.Error(err.Error())
+		}
+		return protocol.Integer(boolToInt(ok))
+	}
+
+	r.cmds["DBSIZE"] = func(args []string, _ time.Duration) string {
+		if len(args) != 0 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		return protocol.Integer(r.db.DBSize())
+	}
+
+	r.cmds["RANDOMKEY"] = func(args []string, _ time.Duration) string {
+		if len(args) != 0 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		key, ok := r.db.RandomKey()
+		if !ok {
+			return protocol.NullBulkString()
+		}
+		return protocol.BulkString(key)
+	}
+}
@@ -0,0 +1,335 @@
+package commands
+
+import (
+	"fmt"
+	"redis-go/internal/db"
+	"redis-go/internal/protocol"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// geoUnit returns the number of meters in unit.
+func geoUnit(unit string) (float64, bool) {
+	switch strings.ToLower(unit) {
+	case "m":
+		return 1, true
+	case "km":
+		return 1000, true
+	case "ft":
+		return 0.3048, true
+	case "mi":
+		return 1609.34, true
+	}
+	return 0, false
+}
+
+const errGeoUnit = "-ERR unsupported unit provided. please use M, KM, FT, MI\r\n"
+
+func parseLonLat(lonArg, latArg string) (lon, lat float64, errReply string) {
+	lon, err1 := strconv.ParseFloat(lonArg, 64)
+	lat, err2 := strconv.ParseFloat(latArg, 64)
+	if err1 != nil || err2 != nil {
+		return 0, 0, "-ERR value is not a valid float\r\n"
+	}
+	if lon < db.GeoLongMin || lon > db.GeoLongMax || lat < db.GeoLatMin || lat > db.GeoLatMax {
+		return 0, 0, fmt.Sprintf("-ERR invalid longitude,latitude pair %f,%f\r\n", lon, lat)
+	}
+	return lon, lat, ""
+}
+
+func formatCoord(v float64) string {
+	return protocol.BulkString(strconv.FormatFloat(v, 'f', -1, 64))
+}
+
+// geoSearchOptions holds the reply modifiers of GEOSEARCH.
+type geoSearchOptions struct {
+	withCoord, withDist, withHash, storeDist bool
+	unit                                     float64
+}
+
+// parseGeoSearch parses the GEOSEARCH arguments following the key. store
+// enables the GEOSEARCHSTORE-only STOREDIST flag and rejects the WITH* flags.
+func parseGeoSearch(args []string, store bool) (db.GeoQuery, geoSearchOptions, string) {
+	var q db.GeoQuery
+	opts := geoSearchOptions{unit: 1}
+	fromSet, bySet := 0, 0
+
+	for i := 0; i < len(args); i++ {
+		left := len(args) - i - 1
+		switch strings.ToUpper(args[i]) {
+		case "FROMMEMBER":
+			if left < 1 {
+				return q, opts, "-ERR syntax error\r\n"
+			}
+			q.FromMember, q.UseMember = args[i+1], true
+			fromSet++
+			i++
+		case "FROMLONLAT":
+			if left < 2 {
+				return q, opts, "-ERR syntax error\r\n"
+			}
+			var errReply string
+			if q.Lon, q.Lat, errReply = parseLonLat(args[i+1], args[i+2]); errReply != "" {
+				return q, opts, errReply
+			}
+			fromSet++
+			i += 2
+		case "BYRADIUS":
+			if left < 2 {
+				return q, opts, "-ERR syntax error\r\n"
+			}
+			radius, err := strconv.ParseFloat(args[i+1], 64)
+			if err != nil || radius < 0 {
+				return q, opts, "-ERR need numeric radius\r\n"
+			}
+			unit, ok := geoUnit(args[i+2])
+			if !ok {
+				return q, opts, errGeoUnit
+			}
+			q.Radius, opts.unit = radius*unit, unit
+			bySet++
+			i += 2
+		case "BYBOX":
+			if left < 3 {
+				return q, opts, "-ERR syntax error\r\n"
+			}
+			width, err1 := strconv.ParseFloat(args[i+1], 64)
+			height, err2 := strconv.ParseFloat(args[i+2], 64)
+			if err1 != nil || err2 != nil || width < 0 || height < 0 {
+				return q, opts, "-ERR need numeric width and height\r\n"
+			}
+			unit, ok := geoUnit(args[i+3])
+			if !ok {
+				return q, opts, errGeoUnit
+			}
+			q.ByBox, q.Width, q.Height, opts.unit = true, width*unit, height*unit, unit
+			bySet++
+			i += 3
+		case "ASC":
+			q.Sort = 1
+		case "DESC":
+			q.Sort = -1
+		case "COUNT":
+			if left < 1 {
+				return q, opts, "-ERR syntax error\r\n"
+			}
+			n, err := strconv.Atoi(args[i+1])
+			if err != nil || n <= 0 {
+				return q, opts, "-ERR COUNT must be > 0\r\n"
+			}
+			q.Count = n
+			i++
+		case "ANY":
+			q.Any = true
+		case "WITHCOORD":
+			opts.withCoord = true
+		case "WITHDIST":
+			opts.withDist = true
+		case "WITHHASH":
+			opts.withHash = true
+		case "STOREDIST":
+			if !store {
+				return q, opts, "-ERR syntax error\r\n"
+			}
+			opts.storeDist = true
+		default:
+			return q, opts, "-ERR syntax error\r\n"
+		}
+	}
+
+	if fromSet != 1 {
+		return q, opts, "-ERR exactly one of FROMMEMBER or FROMLONLAT can be specified for geosearch\r\n"
+	}
+	if bySet != 1 {
+		return q, opts, "-ERR exactly one of BYRADIUS and BYBOX can be specified for geosearch\r\n"
+	}
+	if q.Any && q.Count == 0 {
+		return q, opts, "-ERR the ANY argument requires COUNT argument\r\n"
+	}
+	if store && (opts.withCoord || opts.withDist || opts.withHash) {
+		return q, opts, "-ERR syntax error\r\n"
+	}
+
+	// like Redis, a COUNT without ANY returns the closest matches
+	if q.Count > 0 && q.Sort == 0 && !q.Any {
+		q.Sort = 1
+	}
+	return q, opts, ""
+}
+
+func (r *Registry) registerGeoCommands() {
+
+	r.cmds["GEOADD"] = func(args []string, _ time.Duration) string {
+		// GEOADD key [NX | XX] [CH] longitude latitude member [longitude latitude member ...]
+		if len(args) < 4 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		var nx, xx, ch bool
+		rest := args[1:]
+	options:
+		for len(rest) > 0 {
+			switch strings.ToUpper(rest[0]) {
+			case "NX":
+				nx = true
+			case "XX":
+				xx = true
+			case "CH":
+				ch = true
+			default:
+				break options
+			}
+			rest = rest[1:]
+		}
+
+		if nx && xx {
+			return "-ERR XX and NX options at the same time are not compatible\r\n"
+		}
+		if len(rest) == 0 || len(rest)%3 != 0 {
+			return "-ERR syntax error. Try GEOADD key [x1] [y1] [name1] [x2] [y2] [name2] ... \r\n"
+		}
+
+		points := make([]db.GeoPoint, 0, len(rest)/3)
+		for i := 0; i < len(rest); i += 3 {
+			lon, lat, errReply := parseLonLat(rest[i], rest[i+1])
+			if errReply != "" {
+				return errReply
+			}
+			points = append(points, db.GeoPoint{Member: rest[i+2], Lon: lon, Lat: lat})
+		}
+
+		n, err := r.db.GeoAdd(args[0], nx, xx, ch, points)
+		if err != nil {
+			return protocol.Error(err.Error())
+		}
+		return protocol.Integer(n)
+	}
+
+	r.cmds["GEODIST"] = func(args []string, _ time.Duration) string {
+		// GEODIST key member1 member2 [M | KM | FT | MI]
+		if len(args) < 3 || len(args) > 4 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		unit := 1.0
+		if len(args) == 4 {
+			var ok bool
+			if unit, ok = geoUnit(args[3]); !ok {
+				return errGeoUnit
+			}
+		}
+
+		dist, ok, err := r.db.GeoDist(args[0], args[1], args[2])
+		if err != nil {
+			return protocol.Error(err.Error())
+		}
+		if !ok {
+			return protocol.NullBulkString()
+		}
+		return protocol.BulkString(strconv.FormatFloat(dist/unit, 'f', 4, 64))
+	}
+
+	r.cmds["GEOPOS"] = func(args []string, _ time.Duration) string {
+		// GEOPOS key [member ...]
+		if len(args) < 1 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		points, found, err := r.db.GeoPos(args[0], args[1:]...)
+		if err != nil {
+			return protocol.Error(err.Error())
+		}
+
+		elems := make([]string, len(points))
+		for i, p := range points {
+			if !found[i] {
+				elems[i] = protocol.NullArray()
+				continue
+			}
+			elems[i] = protocol.Array(formatCoord(p.Lon), formatCoord(p.Lat))
+		}
+		return protocol.Array(elems...)
+	}
+
+	r.cmds["GEOHASH"] = func(args []string, _ time.Duration) string {
+		// GEOHASH key [member ...]
+		if len(args) < 1 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		points, found, err := r.db.GeoPos(args[0], args[1:]...)
+		if err != nil {
+			return protocol.Error(err.Error())
+		}
+
+		elems := make([]string, len(points))
+		for i, p := range points {
+			if !found[i] {
+				elems[i] = protocol.NullBulkString()
+				continue
+			}
+			elems[i] = protocol.BulkString(db.GeohashString(p.Hash))
+		}
+		return protocol.Array(elems...)
+	}
+
+	r.cmds["GEOSEARCH"] = func(args []string, _ time.Duration) string {
+		// GEOSEARCH key FROMMEMBER member | FROMLONLAT longitude latitude
+		//   BYRADIUS radius unit | BYBOX width height unit
+		//   [ASC | DESC] [COUNT count [ANY]] [WITHCOORD] [WITHDIST] [WITHHASH]
+		if len(args) < 5 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		q, opts, errReply := parseGeoSearch(args[1:], false)
+		if errReply != "" {
+			return errReply
+		}
+
+		points, err := r.db.GeoSearch(args[0], q)
+		if err != nil {
+			return protocol.Error(err.Error())
+		}
+
+		elems := make([]string, len(points))
+		for i, p := range points {
+			if !opts.withCoord && !opts.withDist && !opts.withHash {
+				elems[i] = protocol.BulkString(p.Member)
+				continue
+			}
+
+			// extra fields come in a fixed order: distance, hash, coordinates
+			entry := []string{protocol.BulkString(p.Member)}
+			if opts.withDist {
+				entry = append(entry, protocol.BulkString(strconv.FormatFloat(p.Dist/opts.unit, 'f', 4, 64)))
+			}
+			if opts.withHash {
+				entry = append(entry, ":"+strconv.FormatUint(p.Hash, 10)+"\r\n")
+			}
+			if opts.withCoord {
+				entry = append(entry, protocol.Array(formatCoord(p.Lon), formatCoord(p.Lat)))
+			}
+			elems[i] = protocol.Array(entry...)
+		}
+		return protocol.Array(elems...)
+	}
+
+	r.cmds["GEOSEARCHSTORE"] = func(args []string, _ time.Duration) string {
+		// GEOSEARCHSTORE destination source <GEOSEARCH options> [STOREDIST]
+		if len(args) < 6 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		q, opts, errReply := parseGeoSearch(args[2:], true)
+		if errReply != "" {
+			return errReply
+		}
+
+		n, err := r.db.GeoSearchStore(args[0], args[1], q, opts.storeDist, opts.unit)
+		if err != nil {
+			return protocol.Error(err.Error())
+		}
+		return protocol.Integer(n)
+	}
+}
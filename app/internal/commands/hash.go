@@ -0,0 +1,237 @@
+package commands
+
+import (
+	"fmt"
+	"math"
+	"redis-go/internal/protocol"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func (r *Registry) registerHashCommands() {
+
+	r.cmds["HGET"] = func(args []string, _ time.Duration) string {
+		if len(args) != 2 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		if val, ok := r.db.HGet(args[0], args[1]); ok {
+			return fmt.Sprintf("$%d\r\n%s\r\n", len(val), val)
+		}
+		return "*0\r\n"
+	}
+
+	r.cmds["HSET"] = func(args []string, _ time.Duration) string {
+		// HSET key field value [field value ...]
+		if len(args) < 3 || len(args)%2 == 0 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		added, err := r.db.HSet(args[0], args[1:]...)
+		if err != nil {
+			return protocol.Error(err.Error())
+		}
+		return protocol.Integer(added)
+	}
+
+	r.cmds["HSETNX"] = func(args []string, _ time.Duration) string {
+		if len(args) != 3 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		ok, err := r.db.HSetNX(args[0], args[1], args[2])
+		if err != nil {
+			return protocol.Error(err.Error())
+		}
+		return protocol.Integer(boolToInt(ok))
+	}
+
+	r.cmds["HGETALL"] = func(args []string, _ time.Duration) string {
+		if len(args) != 1 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		return protocol.BulkArray(r.db.HGetAll(args[0]))
+	}
+
+	r.cmds["HMGET"] = func(args []string, _ time.Duration) string {
+		if len(args) < 2 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		vals, found, err := r.db.HMGet(args[0], args[1:]...)
+		if err != nil {
+			return protocol.Error(err.Error())
+		}
+		return bulkOrNullArray(vals, found)
+	}
+
+	r.cmds["HDEL"] = func(args []string, _ time.Duration) string {
+		if len(args) < 2 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		removed, err := r.db.HDel(args[0], args[1:]...)
+		if err != nil {
+			return protocol.Error(err.Error())
+		}
+		return protocol.Integer(removed)
+	}
+
+	r.cmds["HEXISTS"] = func(args []string, _ time.Duration) string {
+		if len(args) != 2 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		ok, err := r.db.HExists(args[0], args[1])
+		if err != nil {
+			return protocol.Error(err.Error())
+		}
+		return protocol.Integer(boolToInt(ok))
+	}
+
+	r.cmds["HLEN"] = func(args []string, _ time.Duration) string {
+		if len(args) != 1 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		n, err := r.db.HLen(args[0])
+		if err != nil {
+			return protocol.Error(err.Error())
+		}
+		return protocol.Integer(n)
+	}
+
+	r.cmds["HSTRLEN"] = func(args []string, _ time.Duration) string {
+		if len(args) != 2 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		n, err := r.db.HStrLen(args[0], args[1])
+		if err != nil {
+			return protocol.Error(err.Error())
+		}
+		return protocol.Integer(n)
+	}
+
+	r.cmds["HKEYS"] = func(args []string, _ time.Duration) string {
+		if len(args) != 1 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		keys, err := r.db.HKeys(args[0])
+		if err != nil {
+			return protocol.Error(err.Error())
+		}
+		return protocol.BulkArray(keys)
+	}
+
+	r.cmds["HVALS"] = func(args []string, _ time.Duration) string {
+		if len(args) != 1 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		vals, err := r.db.HVals(args[0])
+		if err != nil {
+			return protocol.Error(err.Error())
+		}
+		return protocol.BulkArray(vals)
+	}
+
+	r.cmds["HINCRBY"] = func(args []string, _ time.Duration) string {
+		if len(args) != 3 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		delta, err := strconv.ParseInt(args[2], 10, 64)
+		if err != nil {
+			return "-ERR value is not an integer or out of range\r\n"
+		}
+
+		n, err := r.db.HIncrBy(args[0], args[1], delta)
+		if err != nil {
+			return protocol.Error(err.Error())
+		}
+		return ":" + strconv.FormatInt(n, 10) + "\r\n"
+	}
+
+	r.cmds["HINCRBYFLOAT"] = func(args []string, _ time.Duration) string {
+		if len(args) != 3 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		delta, err := strconv.ParseFloat(args[2], 64)
+		if err != nil || math.IsNaN(delta) || math.IsInf(delta, 0) {
+			return "-ERR value is not a valid float\r\n"
+		}
+
+		val, err := r.db.HIncrByFloat(args[0], args[1], delta)
+		if err != nil {
+			return protocol.Error(err.Error())
+		}
+		return protocol.BulkString(val)
+	}
+
+	r.cmds["HRANDFIELD"] = func(args []string, _ time.Duration) string {
+		// HRANDFIELD key [count [WITHVALUES]]
+		if len(args) < 1 || len(args) > 3 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		if len(args) == 1 {
+			fields, _, err := r.db.HRandField(args[0], 1)
+			if err != nil {
+				return protocol.Error(err.Error())
+			}
+			if len(fields) == 0 {
+				return protocol.NullBulkString()
+			}
+			return protocol.BulkString(fields[0])
+		}
+
+		count, err := strconv.Atoi(args[1])
+		if err != nil {
+			return "-ERR value is not an integer or out of range\r\n"
+		}
+		if count < -maxRandCount {
+			return "-ERR value is out of range\r\n"
+		}
+
+		withValues := false
+		if len(args) == 3 {
+			if strings.ToUpper(args[2]) != "WITHVALUES" {
+				return "-ERR syntax error\r\n"
+			}
+			withValues = true
+		}
+
+		fields, vals, err := r.db.HRandField(args[0], count)
+		if err != nil {
+			return protocol.Error(err.Error())
+		}
+		if !withValues {
+			return protocol.BulkArray(fields)
+		}
+
+		elems := make([]string, 0, len(fields)*2)
+		for i := range fields {
+			elems = append(elems, protocol.BulkString(fields[i]), protocol.BulkString(vals[i]))
+		}
+		return protocol.Array(elems...)
+	}
+}
+
+// bulkOrNullArray encodes vals as an array, using null bulk strings for the
+// entries whose found flag is false.
+func bulkOrNullArray(vals []string, found []bool) string {
+	elems := make([]string, len(vals))
+	for i, v := range vals {
+		if found[i] {
+			elems[i] = protocol.BulkString(v)
+		} else {
+			elems[i] = protocol.NullBulkString()
+		}
+	}
+	return protocol.Array(elems...)
+}
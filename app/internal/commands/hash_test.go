@@ -0,0 +1,35 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHRandFieldCount(t *testing.T) {
+	r := newTestRegistry()
+	expect(t, r, ":2\r\n", "HSET", "h", "a", "1", "b", "2")
+
+	expect(t, r, "-ERR value is out of range\r\n", "HRANDFIELD", "h", "-9223372036854775808")
+	expect(t, r, "-ERR value is out of range\r\n", "HRANDFIELD", "h", "-1000000000", "WITHVALUES")
+
+	if got := run(r, "HRANDFIELD", "h", "-3", "WITHVALUES"); !strings.HasPrefix(got, "*6\r\n") {
+		t.Errorf("HRANDFIELD h -3 WITHVALUES = %q, want 3 pairs", got)
+	}
+}
+
+func TestHRandFieldExpiredFields(t *testing.T) {
+	r := newTestRegistry()
+	expect(t, r, ":1\r\n", "HSET", "h", "f", "v")
+	expect(t, r, "*1\r\n:1\r\n", "HPEXPIRE", "h", "1", "FIELDS", "1", "f")
+	time.Sleep(5 * time.Millisecond)
+
+	expect(t, r, "*0\r\n", "HRANDFIELD", "h", "-2")
+}
+
+func TestHGetAll(t *testing.T) {
+	r := newTestRegistry()
+	expect(t, r, "*0\r\n", "HGETALL", "h")
+	expect(t, r, ":1\r\n", "HSET", "h", "a,b", "1")
+	expect(t, r, "*2\r\n$3\r\na,b\r\n$1\r\n1\r\n", "HGETALL", "h")
+}
@@ -0,0 +1,281 @@
+package commands
+
+import (
+	"errors"
+	"math"
+	"redis-go/internal/db"
+	"redis-go/internal/protocol"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	errNotInteger    = errors.New("value is not an integer or out of range")
+	errInvalidExpire = errors.New("invalid expire time")
+)
+
+// expireTime converts an expiry argument to an absolute time. unit is the
+// argument's resolution; abs marks a unix timestamp rather than a relative
+// duration.
+func expireTime(arg string, unit time.Duration, abs bool) (time.Time, error) {
+	n, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		return time.Time{}, errNotInteger
+	}
+
+	mult := int64(unit / time.Millisecond)
+	if n > math.MaxInt64/mult || n < math.MinInt64/mult {
+		return time.Time{}, errInvalidExpire
+	}
+	ms := n * mult
+
+	if abs {
+		return time.UnixMilli(ms), nil
+	}
+
+	now := time.Now().UnixMilli()
+	if ms > 0 && now > math.MaxInt64-ms {
+		return time.Time{}, errInvalidExpire
+	}
+	return time.UnixMilli(now + ms), nil
+}
+
+// parseFields parses the trailing "FIELDS numfields field [field ...]" block
+// of the hash field expiration commands. perField is the number of arguments
+// each field takes (2 for HSETEX, 1 otherwise).
+func parseFields(args []string, perField int) ([]string, string) {
+	if len(args) < 2 || strings.ToUpper(args[0]) != "FIELDS" {
+		return nil, "-ERR Mandatory argument FIELDS is missing or not at the right position\r\n"
+	}
+
+	n, err := strconv.Atoi(args[1])
+	if err != nil || n <= 0 {
+		return nil, "-ERR Parameter `numFields` should be greater than 0\r\n"
+	}
+	if len(args)-2 != n*perField {
+		return nil, "-ERR The `numfields` parameter must match the number of arguments\r\n"
+	}
+	return args[2:], ""
+}
+
+func (r *Registry) registerHashExpireCommands() {
+
+	hexpire := func(name string, unit time.Duration, abs bool) CommandFunc {
+		return func(args []string, _ time.Duration) string {
+			// HEXPIRE key seconds [NX | XX | GT | LT] FIELDS numfields field [field ...]
+			if len(args) < 4 {
+				return "-ERR wrong number of arguments\r\n"
+			}
+
+			at, err := expireTime(args[1], unit, abs)
+			if err != nil {
+				return expireError(err, name)
+			}
+
+			rest := args[2:]
+			cond := ""
+			switch c := strings.ToUpper(rest[0]); c {
+			case "NX", "XX", "GT", "LT":
+				cond = c
+				rest = rest[1:]
+			}
+
+			fields, errReply := parseFields(rest, 1)
+			if errReply != "" {
+				return errReply
+			}
+
+			result, err := r.db.HExpire(args[0], at, cond, fields...)
+			if err != nil {
+				return protocol.Error(err.Error())
+			}
+			return integerArray(result)
+		}
+	}
+
+	r.cmds["HEXPIRE"] = hexpire("hexpire", time.Second, false)
+	r.cmds["HPEXPIRE"] = hexpire("hpexpire", time.Millisecond, false)
+	r.cmds["HEXPIREAT"] = hexpire("hexpireat", time.Second, true)
+	r.cmds["HPEXPIREAT"] = hexpire("hpexpireat", time.Millisecond, true)
+
+	httl := func(reply func(at time.Time) int) CommandFunc {
+		return func(args []string, _ time.Duration) string {
+			// HTTL key FIELDS numfields field [field ...]
+			if len(args) < 3 {
+				return "-ERR wrong number of arguments\r\n"
+			}
+
+			fields, errReply := parseFields(args[1:], 1)
+			if errReply != "" {
+				return errReply
+			}
+
+			expires, exists, err := r.db.HExpiresAt(args[0], fields...)
+			if err != nil {
+				return protocol.Error(err.Error())
+			}
+
+			result := make([]int, len(fields))
+			for i := range fields {
+				switch {
+				case !exists[i]:
+					result[i] = -2
+				case expires[i].IsZero():
+					result[i] = -1
+				default:
+					result[i] = reply(expires[i])
+				}
+			}
+			return integerArray(result)
+		}
+	}
+
+	r.cmds["HTTL"] = httl(func(at time.Time) int {
+		return int((time.Until(at) + 500*time.Millisecond) / time.Second)
+	})
+	r.cmds["HPTTL"] = httl(func(at time.Time) int {
+		return int(time.Until(at) / time.Millisecond)
+	})
+	r.cmds["HEXPIRETIME"] = httl(func(at time.Time) int {
+		return int(at.Unix())
+	})
+	r.cmds["HPEXPIRETIME"] = httl(func(at time.Time) int {
+		return int(at.UnixMilli())
+	})
+
+	r.cmds["HPERSIST"] = func(args []string, _ time.Duration) string {
+		// HPERSIST key FIELDS numfields field [field ...]
+		if len(args) < 3 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		fields, errReply := parseFields(args[1:], 1)
+		if errReply != "" {
+			return errReply
+		}
+
+		result, err := r.db.HPersist(args[0], fields...)
+		if err != nil {
+			return protocol.Error(err.Error())
+		}
+		return integerArray(result)
+	}
+
+	r.cmds["HGETEX"] = func(args []string, _ time.Duration) string {
+		// HGETEX key [EX seconds | PX ms | EXAT ts | PXAT ms-ts | PERSIST] FIELDS numfields field [field ...]
+		if len(args) < 3 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		ttl := db.FieldTTL{Keep: true}
+		rest := args[1:]
+		switch opt := strings.ToUpper(rest[0]); opt {
+		case "PERSIST":
+			ttl = db.FieldTTL{}
+			rest = rest[1:]
+		case "EX", "PX", "EXAT", "PXAT":
+			at, errReply := parseExpireOption(opt, rest[1:], "hgetex")
+			if errReply != "" {
+				return errReply
+			}
+			ttl = db.FieldTTL{At: at, Set: true}
+			rest = rest[2:]
+		}
+
+		fields, errReply := parseFields(rest, 1)
+		if errReply != "" {
+			return errReply
+		}
+
+		vals, found, err := r.db.HGetEx(args[0], ttl, fields...)
+		if err != nil {
+			return protocol.Error(err.Error())
+		}
+		return bulkOrNullArray(vals, found)
+	}
+
+	r.cmds["HSETEX"] = func(args []string, _ time.Duration) string {
+		// HSETEX key [FNX | FXX] [EX seconds | PX ms | EXAT ts | PXAT ms-ts | KEEPTTL] FIELDS numfields field value [field value ...]
+		if len(args) < 4 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		rest := args[1:]
+		cond := ""
+		switch c := strings.ToUpper(rest[0]); c {
+		case "FNX", "FXX":
+			cond = c
+			rest = rest[1:]
+		}
+
+		var ttl db.FieldTTL
+		switch opt := strings.ToUpper(rest[0]); opt {
+		case "KEEPTTL":
+			ttl = db.FieldTTL{Keep: true}
+			rest = rest[1:]
+		case "EX", "PX", "EXAT", "PXAT":
+			at, errReply := parseExpireOption(opt, rest[1:], "hsetex")
+			if errReply != "" {
+				return errReply
+			}
+			ttl = db.FieldTTL{At: at, Set: true}
+			rest = rest[2:]
+		}
+
+		pairs, errReply := parseFields(rest, 2)
+		if errReply != "" {
+			return errReply
+		}
+
+		ok, err := r.db.HSetEx(args[0], cond, ttl, pairs...)
+		if err != nil {
+			return protocol.Error(err.Error())
+		}
+		return protocol.Integer(boolToInt(ok))
+	}
+}
+
+// parseExpireOption reads the value following an EX, PX, EXAT or PXAT option.
+func parseExpireOption(opt string, rest []string, name string) (time.Time, string) {
+	if len(rest) < 1 {
+		return time.Time{}, "-ERR syntax error\r\n"
+	}
+
+	var at time.Time
+	var err error
+	switch opt {
+	case "EX":
+		at, err = expireTime(rest[0], time.Second, false)
+	case "PX":
+		at, err = expireTime(rest[0], time.Millisecond, false)
+	case "EXAT":
+		at, err = expireTime(rest[0], time.Second, true)
+	case "PXAT":
+		at, err = expireTime(rest[0], time.Millisecond, true)
+	}
+	if err == nil {
+		if n, _ := strconv.ParseInt(rest[0], 10, 64); n <= 0 {
+			err = errInvalidExpire
+		}
+	}
+	if err != nil {
+		return time.Time{}, expireError(err, name)
+	}
+	return at, ""
+}
+
+func expireError(err error, name string) string {
+	if err == errNotInteger {
+		return protocol.Error("ERR " + err.Error())
+	}
+	return protocol.Error("ERR " + err.Error() + " in '" + name + "' command")
+}
+
+func integerArray(vals []int) string {
+	elems := make([]string, len(vals))
+	for i, v := range vals {
+		elems[i] = protocol.Integer(v)
+	}
+	return protocol.Array(elems...)
+}
@@ -0,0 +1,48 @@
+package commands
+
+import (
+	"redis-go/internal/protocol"
+	"strconv"
+	"time"
+)
+
+func (r *Registry) registerHyperLogLogCommands() {
+
+	r.cmds["PFADD"] = func(args []string, _ time.Duration) string {
+		// PFADD key [element ...]
+		if len(args) < 1 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		changed, err := r.db.PFAdd(args[0], args[1:]...)
+		if err != nil {
+			return protocol.Error(err.Error())
+		}
+		return protocol.Integer(boolToInt(changed))
+	}
+
+	r.cmds["PFCOUNT"] = func(args []string, _ time.Duration) string {
+		// PFCOUNT key [key ...]
+		if len(args) < 1 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		card, err := r.db.PFCount(args...)
+		if err != nil {
+			return protocol.Error(err.Error())
+		}
+		return ":" + strconv.FormatInt(card, 10) + "\r\n"
+	}
+
+	r.cmds["PFMERGE"] = func(args []string, _ time.Duration) string {
+		// PFMERGE destkey [sourcekey ...]
+		if len(args) < 1 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		if err := r.db.PFMerge(args[0], args[1:]...); err != nil {
+			return protocol.Error(err.Error())
+		}
+		return protocol.SimpleString("OK")
+	}
+}
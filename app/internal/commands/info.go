@@ -0,0 +1,75 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"redis-go/internal/protocol"
+	"strings"
+	"time"
+)
+
+// infoSections lists the INFO sections in output order.
+var infoSections = []string{"server", "stats", "keyspace"}
+
+// infoSection renders one INFO section, header included.
+func (r *Registry) infoSection(name string) string {
+	var b strings.Builder
+
+	switch name {
+	case "server":
+		uptime := time.Since(r.started)
+		b.WriteString("# Server\r\n")
+		fmt.Fprintf(&b, "process_id:%d\r\n", os.Getpid())
+		fmt.Fprintf(&b, "uptime_in_seconds:%d\r\n", int(uptime.Seconds()))
+		fmt.Fprintf(&b, "uptime_in_days:%d\r\n", int(uptime.Hours()/24))
+
+	case "stats":
+		dropped, disconnected := r.db.PubSubStats()
+		b.WriteString("# Stats\r\n")
+		fmt.Fprintf(&b, "pubsub_channels:%d\r\n", len(r.db.Channels("")))
+		fmt.Fprintf(&b, "pubsub_patterns:%d\r\n", r.db.NumPat())
+		fmt.Fprintf(&b, "pubsub_dropped_messages:%d\r\n", dropped)
+		fmt.Fprintf(&b, "client_output_buffer_limit_disconnections:%d\r\n", disconnected)
+
+	case "keyspace":
+		keys, expires := r.db.KeyspaceInfo()
+		b.WriteString("# Keyspace\r\n")
+		if keys > 0 {
+			fmt.Fprintf(&b, "db0:keys=%d,expires=%d,avg_ttl=0\r\n", keys, expires)
+		}
+	}
+
+	return b.String()
+}
+
+func (r *Registry) registerInfoCommands() {
+
+	r.cmds["INFO"] = func(args []string, _ time.Duration) string {
+		// INFO [section [section ...]]
+		sections := infoSections
+		if len(args) > 0 {
+			sections = nil
+			for _, arg := range args {
+				switch name := strings.ToLower(arg); name {
+				case "all", "default", "everything":
+					sections = append(sections, infoSections...)
+				default:
+					sections = append(sections, name)
+				}
+			}
+		}
+
+		parts := make([]string, 0, len(sections))
+		seen := make(map[string]bool)
+		for _, name := range sections {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			if s := r.infoSection(name); s != "" {
+				parts = append(parts, s)
+			}
+		}
+		return protocol.BulkString(strings.Join(parts, "\r\n"))
+	}
+}
@@ -0,0 +1,76 @@
+package commands
+
+import (
+	"redis-go/internal/protocol"
+	"strings"
+	"time"
+)
+
+// Subscriptions belong to connections and are handled by the server; the
+// commands here only need the DB.
+
+func (r *Registry) registerPubSubCommands() {
+
+	r.cmds["PUBLISH"] = func(args []string, _ time.Duration) string {
+		// PUBLISH channel message
+		if len(args) != 2 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		return protocol.Integer(r.db.Publish(args[0], args[1]))
+	}
+
+	r.cmds["SPUBLISH"] = func(args []string, _ time.Duration) string {
+		// SPUBLISH shardchannel message
+		if len(args) != 2 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		return protocol.Integer(r.db.SPublish(args[0], args[1]))
+	}
+
+	r.cmds["PUBSUB"] = func(args []string, _ time.Duration) string {
+		// PUBSUB CHANNELS [pattern] | NUMSUB [channel ...] | NUMPAT
+		//   | SHARDCHANNELS [pattern] | SHARDNUMSUB [shardchannel ...]
+		if len(args) < 1 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		switch sub := strings.ToUpper(args[0]); sub {
+		case "CHANNELS", "SHARDCHANNELS":
+			if len(args) > 2 {
+				return "-ERR wrong number of arguments\r\n"
+			}
+			pattern := ""
+			if len(args) == 2 {
+				pattern = args[1]
+			}
+			if sub == "SHARDCHANNELS" {
+				return protocol.BulkArray(r.db.ShardChannels(pattern))
+			}
+			return protocol.BulkArray(r.db.Channels(pattern))
+
+		case "NUMSUB", "SHARDNUMSUB":
+			var counts []int
+			if sub == "SHARDNUMSUB" {
+				counts = r.db.ShardNumSub(args[1:]...)
+			} else {
+				counts = r.db.NumSub(args[1:]...)
+			}
+			elems := make([]string, 0, 2*len(counts))
+			for i, n := range counts {
+				elems = append(elems, protocol.BulkString(args[1+i]), protocol.Integer(n))
+			}
+			return protocol.Array(elems...)
+
+		case "NUMPAT":
+			if len(args) != 1 {
+				return "-ERR wrong number of arguments\r\n"
+			}
+			return protocol.Integer(r.db.NumPat())
+
+		default:
+			return protocol.Error("ERR unknown subcommand '" + args[0] + "'. Try PUBSUB HELP.")
+		}
+	}
+}
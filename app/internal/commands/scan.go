@@ -0,0 +1,119 @@
+package commands
+
+import (
+	"redis-go/internal/db"
+	"redis-go/internal/protocol"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseScanOptions parses the MATCH, COUNT and, for SCAN, TYPE options.
+// noValues is set by HSCAN's NOVALUES flag.
+func parseScanOptions(args []string, allowType, allowNoValues bool) (opts db.ScanOptions, noValues bool, errReply string) {
+	for i := 0; i < len(args); i++ {
+		opt := strings.ToUpper(args[i])
+		switch {
+		case opt == "MATCH" && i+1 < len(args):
+			opts.Match = args[i+1]
+			i++
+		case opt == "COUNT" && i+1 < len(args):
+			n, err := strconv.Atoi(args[i+1])
+			if err != nil {
+				return opts, false, protocol.Error("ERR " + errNotInteger.Error())
+			}
+			if n < 1 {
+				return opts, false, "-ERR syntax error\r\n"
+			}
+			opts.Count = n
+			i++
+		case opt == "TYPE" && allowType && i+1 < len(args):
+			opts.Type = db.ValueType(strings.ToLower(args[i+1]))
+			i++
+		case opt == "NOVALUES" && allowNoValues:
+			noValues = true
+		default:
+			return opts, false, "-ERR syntax error\r\n"
+		}
+	}
+	return opts, noValues, ""
+}
+
+func parseCursor(arg string) (uint64, bool) {
+	cursor, err := strconv.ParseUint(arg, 10, 64)
+	return cursor, err == nil
+}
+
+func scanReply(cursor uint64, elems []string) string {
+	return protocol.Array(protocol.BulkString(strconv.FormatUint(cursor, 10)), protocol.BulkArray(elems))
+}
+
+func (r *Registry) registerScanCommands() {
+
+	r.cmds["SCAN"] = func(args []string, _ time.Duration) string {
+		// SCAN cursor [MATCH pattern] [COUNT count] [TYPE type]
+		if len(args) < 1 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		cursor, ok := parseCursor(args[0])
+		if !ok {
+			return "-ERR invalid cursor\r\n"
+		}
+		opts, _, errReply := parseScanOptions(args[1:], true, false)
+		if errReply != "" {
+			return errReply
+		}
+
+		next, keys := r.db.Scan(cursor, opts)
+		return scanReply(next, keys)
+	}
+
+	r.cmds["KEYS"] = func(args []string, _ time.Duration) string {
+		// KEYS pattern
+		if len(args) != 1 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		return protocol.BulkArray(r.db.Keys(args[0]))
+	}
+
+	// The collection variants share the same shape: key, cursor, options.
+	collection := func(scan func(string, uint64, db.ScanOptions) (uint64, []string, error), allowNoValues bool) CommandFunc {
+		return func(args []string, _ time.Duration) string {
+			if len(args) < 2 {
+				return "-ERR wrong number of arguments\r\n"
+			}
+
+			cursor, ok := parseCursor(args[1])
+			if !ok {
+				return "-ERR invalid cursor\r\n"
+			}
+			opts, noValues, errReply := parseScanOptions(args[2:], false, allowNoValues)
+			if errReply != "" {
+				return errReply
+			}
+
+			next, elems, err := scan(args[0], cursor, opts)
+			if err != nil {
+				return protocol.Error(err.Error())
+			}
+			if noValues {
+				// elems holds field/value pairs; keep the fields
+				fields := make([]string, 0, len(elems)/2)
+				for i := 0; i < len(elems); i += 2 {
+					fields = append(fields, elems[i])
+				}
+				elems = fields
+			}
+			return scanReply(next, elems)
+		}
+	}
+
+	// SSCAN key cursor [MATCH pattern] [COUNT count]
+	r.cmds["SSCAN"] = collection(r.db.SScan, false)
+	// HSCAN key cursor [MATCH pattern] [COUNT count] [NOVALUES]
+	r.cmds["HSCAN"] = collection(r.db.HScan, true)
+	// ZSCAN key cursor [MATCH pattern] [COUNT count]
+	r.cmds["ZSCAN"] = collection(r.db.ZScan, false)
+}
@@ -0,0 +1,237 @@
+package commands
+
+import (
+	"redis-go/internal/protocol"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRandCount bounds the repeating (negative) counts of SRANDMEMBER and
+// HRANDFIELD, whose replies are built in memory before they are sent.
+const maxRandCount = 1 << 20
+
+func (r *Registry) registerSetCommands() {
+
+	r.cmds["SADD"] = func(args []string, _ time.Duration) string {
+		if len(args) < 2 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		added, err := r.db.SAdd(args[0], args[1:]...)
+		if err != nil {
+			return protocol.Error(err.Error())
+		}
+		return protocol.Integer(added)
+	}
+
+	r.cmds["SREM"] = func(args []string, _ time.Duration) string {
+		if len(args) < 2 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		removed, err := r.db.SRem(args[0], args[1:]...)
+		if err != nil {
+			return protocol.Error(err.Error())
+		}
+		return protocol.Integer(removed)
+	}
+
+	r.cmds["SMEMBERS"] = func(args []string, _ time.Duration) string {
+		if len(args) != 1 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		return protocol.BulkArray(r.db.SMembers(args[0]))
+	}
+
+	r.cmds["SISMEMBER"] = func(args []string, _ time.Duration) string {
+		if len(args) != 2 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		ok, err := r.db.SIsMember(args[0], args[1])
+		if err != nil {
+			return protocol.Error(err.Error())
+		}
+		return protocol.Integer(boolToInt(ok))
+	}
+
+	r.cmds["SMISMEMBER"] = func(args []string, _ time.Duration) string {
+		if len(args) < 2 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		found, err := r.db.SMIsMember(args[0], args[1:]...)
+		if err != nil {
+			return protocol.Error(err.Error())
+		}
+
+		elems := make([]string, len(found))
+		for i, ok := range found {
+			elems[i] = protocol.Integer(boolToInt(ok))
+		}
+		return protocol.Array(elems...)
+	}
+
+	r.cmds["SCARD"] = func(args []string, _ time.Duration) string {
+		if len(args) != 1 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		n, err := r.db.SCard(args[0])
+		if err != nil {
+			return protocol.Error(err.Error())
+		}
+		return protocol.Integer(n)
+	}
+
+	r.cmds["SPOP"] = func(args []string, _ time.Duration) string {
+		if len(args) < 1 || len(args) > 2 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		if len(args) == 1 {
+			popped, err := r.db.SPop(args[0], 1)
+			if err != nil {
+				return protocol.Error(err.Error())
+			}
+			if len(popped) == 0 {
+				return protocol.NullBulkString()
+			}
+			return protocol.BulkString(popped[0])
+		}
+
+		count, err := strconv.Atoi(args[1])
+		if err != nil || count < 0 {
+			return "-ERR value is out of range, must be positive\r\n"
+		}
+
+		popped, err := r.db.SPop(args[0], count)
+		if err != nil {
+			return protocol.Error(err.Error())
+		}
+		return protocol.BulkArray(popped)
+	}
+
+	r.cmds["SRANDMEMBER"] = func(args []string, _ time.Duration) string {
+		if len(args) < 1 || len(args) > 2 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		if len(args) == 1 {
+			members, err := r.db.SRandMember(args[0], 1)
+			if err != nil {
+				return protocol.Error(err.Error())
+			}
+			if len(members) == 0 {
+				return protocol.NullBulkString()
+			}
+			return protocol.BulkString(members[0])
+		}
+
+		count, err := strconv.Atoi(args[1])
+		if err != nil {
+			return "-ERR value is not an integer or out of range\r\n"
+		}
+		if count < -maxRandCount {
+			return "-ERR value is out of range\r\n"
+		}
+
+		members, err := r.db.SRandMember(args[0], count)
+		if err != nil {
+			return protocol.Error(err.Error())
+		}
+		return protocol.BulkArray(members)
+	}
+
+	r.cmds["SMOVE"] = func(args []string, _ time.Duration) string {
+		if len(args) != 3 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		moved, err := r.db.SMove(args[0], args[1], args[2])
+		if err != nil {
+			return protocol.Error(err.Error())
+		}
+		return protocol.Integer(boolToInt(moved))
+	}
+
+	setRead := func(op func(keys ...string) ([]string, error)) CommandFunc {
+		return func(args []string, _ time.Duration) string {
+			if len(args) < 1 {
+				return "-ERR wrong number of arguments\r\n"
+			}
+
+			members, err := op(args...)
+			if err != nil {
+				return protocol.Error(err.Error())
+			}
+			return protocol.BulkArray(members)
+		}
+	}
+
+	setStore := func(op func(dst string, keys ...string) (int, error)) CommandFunc {
+		return func(args []string, _ time.Duration) string {
+			if len(args) < 2 {
+				return "-ERR wrong number of arguments\r\n"
+			}
+
+			n, err := op(args[0], args[1:]...)
+			if err != nil {
+				return protocol.Error(err.Error())
+			}
+			return protocol.Integer(n)
+		}
+	}
+
+	r.cmds["SINTER"] = setRead(r.db.SInter)
+	r.cmds["SUNION"] = setRead(r.db.SUnion)
+	r.cmds["SDIFF"] = setRead(r.db.SDiff)
+	r.cmds["SINTERSTORE"] = setStore(r.db.SInterStore)
+	r.cmds["SUNIONSTORE"] = setStore(r.db.SUnionStore)
+	r.cmds["SDIFFSTORE"] = setStore(r.db.SDiffStore)
+
+	r.cmds["SINTERCARD"] = func(args []string, _ time.Duration) string {
+		// SINTERCARD numkeys key [key ...] [LIMIT limit]
+		if len(args) < 2 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		numkeys, err := strconv.Atoi(args[0])
+		if err != nil || numkeys <= 0 {
+			return "-ERR numkeys should be greater than 0\r\n"
+		}
+		if len(args) < 1+numkeys {
+			return "-ERR Number of keys can't be greater than number of args\r\n"
+		}
+
+		keys := args[1 : 1+numkeys]
+		rest := args[1+numkeys:]
+		limit := 0
+
+		for len(rest) > 0 {
+			if strings.ToUpper(rest[0]) != "LIMIT" || len(rest) < 2 {
+				return "-ERR syntax error\r\n"
+			}
+			limit, err = strconv.Atoi(rest[1])
+			if err != nil || limit < 0 {
+				return "-ERR LIMIT can't be negative\r\n"
+			}
+			rest = rest[2:]
+		}
+
+		n, err := r.db.SInterCard(limit, keys...)
+		if err != nil {
+			return protocol.Error(err.Error())
+		}
+		return protocol.Integer(n)
+	}
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
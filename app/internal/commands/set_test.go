@@ -0,0 +1,21 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSRandMemberCount(t *testing.T) {
+	r := newTestRegistry()
+	expect(t, r, ":2\r\n", "SADD", "s", "a", "b")
+
+	expect(t, r, "-ERR value is out of range\r\n", "SRANDMEMBER", "s", "-9223372036854775808")
+	expect(t, r, "-ERR value is out of range\r\n", "SRANDMEMBER", "s", "-1000000000")
+
+	if got := run(r, "SRANDMEMBER", "s", "-5"); !strings.HasPrefix(got, "*5\r\n") {
+		t.Errorf("SRANDMEMBER s -5 = %q, want 5 members", got)
+	}
+	if got := run(r, "SRANDMEMBER", "s", "9223372036854775807"); !strings.HasPrefix(got, "*2\r\n") {
+		t.Errorf("SRANDMEMBER s max = %q, want both members", got)
+	}
+}
@@ -0,0 +1,95 @@
+package commands
+
+import (
+	"redis-go/internal/db"
+	"redis-go/internal/protocol"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseSortOptions parses the arguments of SORT following the key. readOnly
+// rejects STORE, for SORT_RO.
+func parseSortOptions(args []string, readOnly bool) (db.SortOptions, string) {
+	opts := db.SortOptions{Count: -1}
+
+	for i := 0; i < len(args); i++ {
+		left := len(args) - i - 1
+		switch strings.ToUpper(args[i]) {
+		case "ASC":
+			opts.Desc = false
+		case "DESC":
+			opts.Desc = true
+		case "ALPHA":
+			opts.Alpha = true
+		case "LIMIT":
+			if left < 2 {
+				return opts, "-ERR syntax error\r\n"
+			}
+			offset, err1 := strconv.Atoi(args[i+1])
+			count, err2 := strconv.Atoi(args[i+2])
+			if err1 != nil || err2 != nil {
+				return opts, protocol.Error("ERR " + errNotInteger.Error())
+			}
+			opts.Offset, opts.Count = offset, count
+			i += 2
+		case "BY":
+			if left < 1 {
+				return opts, "-ERR syntax error\r\n"
+			}
+			opts.By = args[i+1]
+			i++
+		case "GET":
+			if left < 1 {
+				return opts, "-ERR syntax error\r\n"
+			}
+			opts.Get = append(opts.Get, args[i+1])
+			i++
+		case "STORE":
+			if readOnly || left < 1 {
+				return opts, "-ERR syntax error\r\n"
+			}
+			opts.Store = args[i+1]
+			i++
+		default:
+			return opts, "-ERR syntax error\r\n"
+		}
+	}
+	return opts, ""
+}
+
+func (r *Registry) registerSortCommands() {
+
+	sortCommand := func(readOnly bool) CommandFunc {
+		return func(args []string, _ time.Duration) string {
+			if len(args) < 1 {
+				return "-ERR wrong number of arguments\r\n"
+			}
+
+			opts, errReply := parseSortOptions(args[1:], readOnly)
+			if errReply != "" {
+				return errReply
+			}
+
+			if opts.Store != "" {
+				n, err := r.db.SortStore(args[0], opts)
+				if err != nil {
+					return protocol.Error(err.Error())
+				}
+				return protocol.Integer(n)
+			}
+
+			vals, found, err := r.db.Sort(args[0], opts)
+			if err != nil {
+				return protocol.Error(err.Error())
+			}
+			return bulkOrNullArray(vals, found)
+		}
+	}
+
+	// SORT key [BY pattern] [LIMIT offset count] [GET pattern [GET pattern ...]]
+	//   [ASC | DESC] [ALPHA] [STORE destination]
+	r.cmds["SORT"] = sortCommand(false)
+	// SORT_RO is SORT without STORE
+	r.cmds["SORT_RO"] = sortCommand(true)
+}
@@ -0,0 +1,13 @@
+package commands
+
+import "testing"
+
+func TestSortLimit(t *testing.T) {
+	r := newTestRegistry()
+	expect(t, r, ":3\r\n", "SADD", "l", "3", "1", "2")
+
+	expect(t, r, "*2\r\n$1\r\n2\r\n$1\r\n3\r\n", "SORT", "l", "LIMIT", "1", "9223372036854775807")
+	expect(t, r, "*1\r\n$1\r\n2\r\n", "SORT", "l", "LIMIT", "1", "1")
+	expect(t, r, "*0\r\n", "SORT", "l", "LIMIT", "9223372036854775807", "9223372036854775807")
+	expect(t, r, "*3\r\n$1\r\n1\r\n$1\r\n2\r\n$1\r\n3\r\n", "SORT", "l", "LIMIT", "0", "-1")
+}
@@ -0,0 +1,255 @@
+package commands
+
+import (
+	"math"
+	"redis-go/internal/db"
+	"redis-go/internal/protocol"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func (r *Registry) registerStringCommands() {
+
+	incrBy := func(args []string, sign int64) string {
+		delta, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil || (sign < 0 && delta == math.MinInt64) {
+			return "-ERR value is not an integer or out of range\r\n"
+		}
+
+		n, err := r.db.IncrBy(args[0], sign*delta)
+		if err != nil {
+			return protocol.Error(err.Error())
+		}
+		return ":" + strconv.FormatInt(n, 10) + "\r\n"
+	}
+
+	r.cmds["INCR"] = func(args []string, _ time.Duration) string {
+		if len(args) != 1 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+		return incrBy([]string{args[0], "1"}, 1)
+	}
+
+	r.cmds["DECR"] = func(args []string, _ time.Duration) string {
+		if len(args) != 1 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+		return incrBy([]string{args[0], "1"}, -1)
+	}
+
+	r.cmds["INCRBY"] = func(args []string, _ time.Duration) string {
+		if len(args) != 2 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+		return incrBy(args, 1)
+	}
+
+	r.cmds["DECRBY"] = func(args []string, _ time.Duration) string {
+		if len(args) != 2 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+		return incrBy(args, -1)
+	}
+
+	r.cmds["INCRBYFLOAT"] = func(args []string, _ time.Duration) string {
+		if len(args) != 2 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		delta, err := strconv.ParseFloat(args[1], 64)
+		if err != nil || math.IsNaN(delta) || math.IsInf(delta, 0) {
+			return "-ERR value is not a valid float\r\n"
+		}
+
+		val, err := r.db.IncrByFloat(args[0], delta)
+		if err != nil {
+			return protocol.Error(err.Error())
+		}
+		return protocol.BulkString(val)
+	}
+
+	r.cmds["APPEND"] = func(args []string, _ time.Duration) string {
+		if len(args) != 2 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		n, err := r.db.Append(args[0], args[1])
+		if err != nil {
+			return protocol.Error(err.Error())
+		}
+		return protocol.Integer(n)
+	}
+
+	r.cmds["STRLEN"] = func(args []string, _ time.Duration) string {
+		if len(args) != 1 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		n, err := r.db.StrLen(args[0])
+		if err != nil {
+			return protocol.Error(err.Error())
+		}
+		return protocol.Integer(n)
+	}
+
+	r.cmds["GETRANGE"] = func(args []string, _ time.Duration) string {
+		if len(args) != 3 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		start, err := strconv.Atoi(args[1])
+		if err != nil {
+			return "-ERR value is not an integer or out of range\r\n"
+		}
+		end, err := strconv.Atoi(args[2])
+		if err != nil {
+			return "-ERR value is not an integer or out of range\r\n"
+		}
+
+		val, err := r.db.GetRange(args[0], start, end)
+		if err != nil {
+			return protocol.Error(err.Error())
+		}
+		return protocol.BulkString(val)
+	}
+
+	r.cmds["SETRANGE"] = func(args []string, _ time.Duration) string {
+		if len(args) != 3 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		offset, err := strconv.Atoi(args[1])
+		if err != nil {
+			return "-ERR value is not an integer or out of range\r\n"
+		}
+		if offset < 0 {
+			return "-ERR offset is out of range\r\n"
+		}
+		if offset > db.MaxStringSize-len(args[2]) {
+			return protocol.Error(db.ErrStringTooLarge.Error())
+		}
+
+		n, err := r.db.SetRange(args[0], offset, args[2])
+		if err != nil {
+			return protocol.Error(err.Error())
+		}
+		return protocol.Integer(n)
+	}
+
+	r.cmds["GETDEL"] = func(args []string, _ time.Duration) string {
+		if len(args) != 1 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		val, ok, err := r.db.GetDel(args[0])
+		return bulkOrNull(val, ok, err)
+	}
+
+	r.cmds["GETEX"] = func(args []string, _ time.Duration) string {
+		// GETEX key [EX seconds | PX ms | EXAT ts | PXAT ms-ts | PERSIST]
+		if len(args) < 1 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		var at time.Time
+		persist := false
+		switch {
+		case len(args) == 1:
+		case len(args) == 2 && strings.ToUpper(args[1]) == "PERSIST":
+			persist = true
+		case len(args) == 3:
+			switch opt := strings.ToUpper(args[1]); opt {
+			case "EX", "PX", "EXAT", "PXAT":
+				var errReply string
+				at, errReply = parseExpireOption(opt, args[2:], "getex")
+				if errReply != "" {
+					return errReply
+				}
+			default:
+				return "-ERR syntax error\r\n"
+			}
+		default:
+			return "-ERR syntax error\r\n"
+		}
+
+		val, ok, err := r.db.GetEx(args[0], at, persist)
+		return bulkOrNull(val, ok, err)
+	}
+
+	r.cmds["GETSET"] = func(args []string, _ time.Duration) string {
+		if len(args) != 2 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		val, ok, err := r.db.GetSet(args[0], args[1])
+		return bulkOrNull(val, ok, err)
+	}
+
+	r.cmds["MGET"] = func(args []string, _ time.Duration) string {
+		if len(args) < 1 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		vals, found := r.db.MGet(args...)
+		return bulkOrNullArray(vals, found)
+	}
+
+	r.cmds["MSET"] = func(args []string, _ time.Duration) string {
+		if len(args) < 2 || len(args)%2 != 0 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		r.db.MSet(args...)
+		return protocol.SimpleString("OK")
+	}
+
+	r.cmds["MSETNX"] = func(args []string, _ time.Duration) string {
+		if len(args) < 2 || len(args)%2 != 0 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		return protocol.Integer(boolToInt(r.db.MSetNX(args...)))
+	}
+
+	r.cmds["SETNX"] = func(args []string, _ time.Duration) string {
+		if len(args) != 2 {
+			return "-ERR wrong number of arguments\r\n"
+		}
+
+		return protocol.Integer(boolToInt(r.db.SetNX(args[0], args[1])))
+	}
+
+	setEx := func(name string, unit time.Duration) CommandFunc {
+		return func(args []string, _ time.Duration) string {
+			// SETEX key seconds value
+			if len(args) != 3 {
+				return "-ERR wrong number of arguments\r\n"
+			}
+
+			n, err := strconv.ParseInt(args[1], 10, 64)
+			if err != nil {
+				return "-ERR value is not an integer or out of range\r\n"
+			}
+			if n <= 0 || n > math.MaxInt64/int64(unit) {
+				return protocol.Error("ERR invalid expire time in '" + name + "' command")
+			}
+
+			r.db.Set(args[0], args[2], time.Duration(n)*unit)
+			return protocol.SimpleString("OK")
+		}
+	}
+
+	r.cmds["SETEX"] = setEx("setex", time.Second)
+	r.cmds["PSETEX"] = setEx("psetex", time.Millisecond)
+}
+
+func bulkOrNull(val string, ok bool, err error) string {
+	if err != nil {
+		return protocol.Error(err.Error())
+	}
+	if !ok {
+		return protocol.NullBulkString()
+	}
+	return protocol.BulkString(val)
+}
@@ -0,0 +1,41 @@
+package commands
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestSetRangeOffsetLimit(t *testing.T) {
+	r := newTestRegistry()
+	tooLarge := "-ERR string exceeds maximum allowed size (proto-max-bulk-len)\r\n"
+
+	expect(t, r, tooLarge, "SETRANGE", "k", "9223372036854775807", "ab")
+	expect(t, r, tooLarge, "SETRANGE", "k", strconv.Itoa(512<<20-1), "ab")
+	expect(t, r, "-ERR offset is out of range\r\n", "SETRANGE", "k", "-1", "ab")
+	expect(t, r, ":7\r\n", "SETRANGE", "k", "5", "ab")
+	expect(t, r, "$7\r\n\x00\x00\x00\x00\x00ab\r\n", "GET", "k")
+}
+
+func TestGetDuringIncr(t *testing.T) {
+	r := newTestRegistry()
+	expect(t, r, "+OK\r\n", "SET", "n", "0")
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for range 10000 {
+			run(r, "INCR", "n")
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for range 10000 {
+			run(r, "GET", "n")
+		}
+	}()
+	wg.Wait()
+
+	expect(t, r, "$5\r\n10000\r\n", "GET", "n")
+}
@@ -0,0 +1,329 @@
+package commands
+
+import (
+	"redis-go/internal/acl"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// commandInfo describes a command for ACLs: its categories and where its
+// keys and channels are among the arguments.
+type commandInfo struct {
+	categories  string // space separated
+	keys        []keySpec
+	findKeys    func(args []string) []acl.Key // for keys found by keyword
+	channels    channelSpec
+	subcommands []string
+}
+
+// keySpec locates keys in args[first], args[first+step], ... up to
+// args[last]. A negative last counts from the end, -1 being the last
+// argument. access holds "r" if the command reads the values and "w" if it
+// changes them.
+type keySpec struct {
+	first, last, step int
+	access            string
+}
+
+type channelSpec int
+
+const (
+	noChannels   channelSpec = iota
+	firstChannel             // PUBLISH channel message
+	allChannels              // SUBSCRIBE channel [channel ...]
+	allPatterns              // PSUBSCRIBE pattern [pattern ...]
+)
+
+// oneKey is the key spec of commands whose only key is the first argument.
+func oneKey(access string) []keySpec {
+	return []keySpec{{0, 0, 1, access}}
+}
+
+// allKeys is the key spec of commands taking only keys.
+func allKeys(access string) []keySpec {
+	return []keySpec{{0, -1, 1, access}}
+}
+
+// storeKeys is the key spec of commands writing to a destination key from
+// source keys, e.g. SINTERSTORE destination key [key ...].
+var storeKeys = []keySpec{{0, 0, 1, "w"}, {1, -1, 1, "r"}}
+
+var commandTable = map[string]commandInfo{
+	// connection and server
+	"PING":    {categories: "fast connection"},
+	"AUTH":    {categories: "fast connection"},
+	"QUIT":    {categories: "fast connection"},
+	"RESET":   {categories: "fast connection"},
+	"INFO":    {categories: "slow dangerous"},
+	"CONFIG":  {categories: "admin slow dangerous", subcommands: []string{"get", "set"}},
+	"CLIENT":  {categories: "admin slow dangerous connection", subcommands: []string{"id", "info", "list", "kill", "setname", "getname", "pause", "unpause", "reply", "no-evict", "tracking", "caching", "getredir", "trackinginfo"}},
+	"ACL":     {categories: "admin slow dangerous", subcommands: []string{"setuser", "getuser", "deluser", "users", "list", "whoami", "cat", "log", "dryrun", "save", "load"}},
+	"OBJECT":  {categories: "keyspace read slow", keys: []keySpec{{1, 1, 1, ""}}, subcommands: []string{"encoding"}},
+	"MIGRATE": {categories: "keyspace write slow dangerous", findKeys: migrateKeys},
+
+	// strings
+	"GET":         {categories: "read string fast", keys: oneKey("r")},
+	"SET":         {categories: "write string slow", keys: oneKey("w")},
+	"SETNX":       {categories: "write string fast", keys: oneKey("w")},
+	"SETEX":       {categories: "write string slow", keys: oneKey("w")},
+	"PSETEX":      {categories: "write string slow", keys: oneKey("w")},
+	"GETDEL":      {categories: "write string fast", keys: oneKey("rw")},
+	"GETEX":       {categories: "write string fast", keys: oneKey("rw")},
+	"GETSET":      {categories: "write string fast", keys: oneKey("rw")},
+	"MGET":        {categories: "read string fast", keys: allKeys("r")},
+	"MSET":        {categories: "write string slow", keys: []keySpec{{0, -1, 2, "w"}}},
+	"MSETNX":      {categories: "write string slow", keys: []keySpec{{0, -1, 2, "w"}}},
+	"INCR":        {categories: "write string fast", keys: oneKey("rw")},
+	"DECR":        {categories: "write string fast", keys: oneKey("rw")},
+	"INCRBY":      {categories: "write string fast", keys: oneKey("rw")},
+	"DECRBY":      {categories: "write string fast", keys: oneKey("rw")},
+	"INCRBYFLOAT": {categories: "write string fast", keys: oneKey("rw")},
+	"APPEND":      {categories: "write string fast", keys: oneKey("w")},
+	"STRLEN":      {categories: "read string fast", keys: oneKey("")},
+	"GETRANGE":    {categories: "read string slow", keys: oneKey("r")},
+	"SETRANGE":    {categories: "write string slow", keys: oneKey("w")},
+
+	// bitmaps
+	"SETBIT":      {categories: "write bitmap slow", keys: oneKey("rw")},
+	"GETBIT":      {categories: "read bitmap fast", keys: oneKey("r")},
+	"BITCOUNT":    {categories: "read bitmap slow", keys: oneKey("r")},
+	"BITPOS":      {categories: "read bitmap slow", keys: oneKey("r")},
+	"BITOP":       {categories: "write bitmap slow", keys: []keySpec{{1, 1, 1, "w"}, {2, -1, 1, "r"}}},
+	"BITFIELD":    {categories: "write bitmap slow", keys: oneKey("rw")},
+	"BITFIELD_RO": {categories: "read bitmap fast", keys: oneKey("r")},
+
+	// hyperloglogs
+	"PFADD":   {categories: "write hyperloglog fast", keys: oneKey("w")},
+	"PFCOUNT": {categories: "read hyperloglog slow", keys: allKeys("r")},
+	"PFMERGE": {categories: "write hyperloglog slow", keys: []keySpec{{0, 0, 1, "rw"}, {1, -1, 1, "r"}}},
+
+	// lists
+	"LPUSH":  {categories: "write list fast", keys: oneKey("w")},
+	"RPUSH":  {categories: "write list fast", keys: oneKey("w")},
+	"LRANGE": {categories: "read list slow", keys: oneKey("r")},
+
+	// sets
+	"SADD":        {categories: "write set fast", keys: oneKey("w")},
+	"SREM":        {categories: "write set fast", keys: oneKey("w")},
+	"SMEMBERS":    {categories: "read set slow", keys: oneKey("r")},
+	"SISMEMBER":   {categories: "read set fast", keys: oneKey("")},
+	"SMISMEMBER":  {categories: "read set fast", keys: oneKey("")},
+	"SCARD":       {categories: "read set fast", keys: oneKey("")},
+	"SPOP":        {categories: "write set fast", keys: oneKey("rw")},
+	"SRANDMEMBER": {categories: "read set slow", keys: oneKey("r")},
+	"SMOVE":       {categories: "write set fast", keys: []keySpec{{0, 0, 1, "rw"}, {1, 1, 1, "w"}}},
+	"SINTER":      {categories: "read set slow", keys: allKeys("r")},
+	"SUNION":      {categories: "read set slow", keys: allKeys("r")},
+	"SDIFF":       {categories: "read set slow", keys: allKeys("r")},
+	"SINTERSTORE": {categories: "write set slow", keys: storeKeys},
+	"SUNIONSTORE": {categories: "write set slow", keys: storeKeys},
+	"SDIFFSTORE":  {categories: "write set slow", keys: storeKeys},
+	"SINTERCARD":  {categories: "read set slow", findKeys: numKeys(0, "r")},
+	"SSCAN":       {categories: "read set slow", keys: oneKey("r")},
+
+	// hashes
+	"HSET":         {categories: "write hash fast", keys: oneKey("w")},
+	"HSETNX":       {categories: "write hash fast", keys: oneKey("w")},
+	"HDEL":         {categories: "write hash fast", keys: oneKey("w")},
+	"HINCRBY":      {categories: "write hash fast", keys: oneKey("rw")},
+	"HINCRBYFLOAT": {categories: "write hash fast", keys: oneKey("rw")},
+	"HGET":         {categories: "read hash fast", keys: oneKey("r")},
+	"HMGET":        {categories: "read hash fast", keys: oneKey("r")},
+	"HEXISTS":      {categories: "read hash fast", keys: oneKey("")},
+	"HLEN":         {categories: "read hash fast", keys: oneKey("")},
+	"HSTRLEN":      {categories: "read hash fast", keys: oneKey("")},
+	"HGETALL":      {categories: "read hash slow", keys: oneKey("r")},
+	"HKEYS":        {categories: "read hash slow", keys: oneKey("r")},
+	"HVALS":        {categories: "read hash slow", keys: oneKey("r")},
+	"HRANDFIELD":   {categories: "read hash slow", keys: oneKey("r")},
+	"HSCAN":        {categories: "read hash slow", keys: oneKey("r")},
+	"HEXPIRE":      {categories: "write hash fast", keys: oneKey("w")},
+	"HPEXPIRE":     {categories: "write hash fast", keys: oneKey("w")},
+	"HEXPIREAT":    {categories: "write hash fast", keys: oneKey("w")},
+	"HPEXPIREAT":   {categories: "write hash fast", keys: oneKey("w")},
+	"HPERSIST":     {categories: "write hash fast", keys: oneKey("w")},
+	"HTTL":         {categories: "read hash fast", keys: oneKey("")},
+	"HPTTL":        {categories: "read hash fast", keys: oneKey("")},
+	"HEXPIRETIME":  {categories: "read hash fast", keys: oneKey("")},
+	"HPEXPIRETIME": {categories: "read hash fast", keys: oneKey("")},
+	"HGETEX":       {categories: "write hash fast", keys: oneKey("rw")},
+	"HSETEX":       {categories: "write hash fast", keys: oneKey("w")},
+
+	// sorted sets and geo
+	"ZSCAN":          {categories: "read sortedset slow", keys: oneKey("r")},
+	"GEOADD":         {categories: "write geo slow", keys: oneKey("w")},
+	"GEODIST":        {categories: "read geo slow", keys: oneKey("r")},
+	"GEOPOS":         {categories: "read geo slow", keys: oneKey("r")},
+	"GEOHASH":        {categories: "read geo slow", keys: oneKey("r")},
+	"GEOSEARCH":      {categories: "read geo slow", keys: oneKey("r")},
+	"GEOSEARCHSTORE": {categories: "write geo slow", keys: []keySpec{{0, 0, 1, "w"}, {1, 1, 1, "r"}}},
+
+	// keyspace
+	"DEL":       {categories: "keyspace write slow", keys: allKeys("w")},
+	"UNLINK":    {categories: "keyspace write fast", keys: allKeys("w")},
+	"EXISTS":    {categories: "keyspace read fast", keys: allKeys("")},
+	"TOUCH":     {categories: "keyspace read fast", keys: allKeys("")},
+	"TYPE":      {categories: "keyspace read fast", keys: oneKey("")},
+	"RENAME":    {categories: "keyspace write slow", keys: []keySpec{{0, 0, 1, "rw"}, {1, 1, 1, "w"}}},
+	"RENAMENX":  {categories: "keyspace write fast", keys: []keySpec{{0, 0, 1, "rw"}, {1, 1, 1, "w"}}},
+	"COPY":      {categories: "keyspace write slow", keys: []keySpec{{0, 0, 1, "r"}, {1, 1, 1, "w"}}},
+	"DBSIZE":    {categories: "keyspace read fast"},
+	"RANDOMKEY": {categories: "keyspace read slow"},
+	"KEYS":      {categories: "keyspace read slow dangerous"},
+	"SCAN":      {categories: "keyspace read slow"},
+	"FLUSHALL":  {categories: "keyspace write slow dangerous"},
+	"SORT":      {categories: "write set sortedset list slow dangerous", findKeys: sortKeys},
+	"SORT_RO":   {categories: "read set sortedset list slow dangerous", findKeys: sortKeys},
+	"DUMP":      {categories: "keyspace read slow", keys: oneKey("r")},
+	"RESTORE":   {categories: "keyspace write slow dangerous", keys: oneKey("w")},
+
+	// pub/sub
+	"PUBLISH":      {categories: "pubsub fast", channels: firstChannel},
+	"SPUBLISH":     {categories: "pubsub fast", channels: firstChannel},
+	"SUBSCRIBE":    {categories: "pubsub slow", channels: allChannels},
+	"SSUBSCRIBE":   {categories: "pubsub slow", channels: allChannels},
+	"PSUBSCRIBE":   {categories: "pubsub slow", channels: allPatterns},
+	"UNSUBSCRIBE":  {categories: "pubsub slow"},
+	"SUNSUBSCRIBE": {categories: "pubsub slow"},
+	"PUNSUBSCRIBE": {categories: "pubsub slow"},
+	"PUBSUB":       {categories: "pubsub slow", subcommands: []string{"channels", "numsub", "numpat", "shardchannels", "shardnumsub"}},
+}
+
+// InCategory reports whether cmd belongs to the ACL category.
+func InCategory(cmd, category string) bool {
+	return slices.Contains(strings.Fields(commandTable[cmd].categories), category)
+}
+
+// CommandKeys returns the keys cmd names in its arguments.
+func CommandKeys(cmd string, args []string) []string {
+	req := aclRequest(cmd, args)
+	keys := make([]string, 0, len(req.Keys))
+	for _, k := range req.Keys {
+		if !k.Any {
+			keys = append(keys, k.Name)
+		}
+	}
+	return keys
+}
+
+// aclCommands returns the command table in the form the ACL needs.
+func aclCommands() []acl.Command {
+	cmds := make([]acl.Command, 0, len(commandTable))
+	for name, info := range commandTable {
+		cmds = append(cmds, acl.Command{
+			Name:        strings.ToLower(name),
+			Categories:  strings.Fields(info.categories),
+			Subcommands: info.subcommands,
+		})
+	}
+	return cmds
+}
+
+// aclRequest describes cmd for an ACL check.
+func aclRequest(cmd string, args []string) acl.Request {
+	info := commandTable[cmd]
+	req := acl.Request{Command: strings.ToLower(cmd)}
+	if len(info.subcommands) > 0 && len(args) > 0 {
+		req.Subcommand = strings.ToLower(args[0])
+	}
+
+	for _, spec := range info.keys {
+		last := spec.last
+		if last < 0 {
+			last += len(args)
+		}
+		for i := spec.first; i <= last && i < len(args); i += spec.step {
+			req.Keys = append(req.Keys, newKey(args[i], spec.access))
+		}
+	}
+	if info.findKeys != nil {
+		req.Keys = append(req.Keys, info.findKeys(args)...)
+	}
+
+	switch info.channels {
+	case firstChannel:
+		if len(args) > 0 {
+			req.Channels = args[:1]
+		}
+	case allChannels:
+		req.Channels = args
+	case allPatterns:
+		req.Channels, req.Patterns = args, true
+	}
+	return req
+}
+
+func newKey(name, access string) acl.Key {
+	return acl.Key{Name: name, Read: strings.Contains(access, "r"), Write: strings.Contains(access, "w")}
+}
+
+// numKeys finds keys given as "numkeys key [key ...]" with numkeys at
+// args[at].
+func numKeys(at int, access string) func(args []string) []acl.Key {
+	return func(args []string) []acl.Key {
+		if at >= len(args) {
+			return nil
+		}
+		n, err := strconv.Atoi(args[at])
+		if err != nil || n < 0 {
+			return nil
+		}
+		var keys []acl.Key
+		for i := at + 1; i <= at+n && i < len(args); i++ {
+			keys = append(keys, newKey(args[i], access))
+		}
+		return keys
+	}
+}
+
+// sortKeys finds the keys of SORT key [BY pattern] [LIMIT offset count]
+// [GET pattern ...] [ASC | DESC] [ALPHA] [STORE destination]. BY and GET
+// patterns that look up other keys can reach any key.
+func sortKeys(args []string) []acl.Key {
+	if len(args) == 0 {
+		return nil
+	}
+	keys := []acl.Key{newKey(args[0], "r")}
+	for i := 1; i+1 < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "BY", "GET":
+			i++
+			if strings.Contains(args[i], "*") {
+				keys = append(keys, acl.Key{Name: args[i], Read: true, Any: true})
+			}
+		case "LIMIT":
+			i += 2
+		case "STORE":
+			i++
+			keys = append(keys, newKey(args[i], "w"))
+		}
+	}
+	return keys
+}
+
+// migrateKeys finds the keys of MIGRATE host port key|"" db timeout
+// [COPY] [REPLACE] [AUTH password | AUTH2 username password] [KEYS key ...].
+func migrateKeys(args []string) []acl.Key {
+	if len(args) < 5 {
+		return nil
+	}
+	if args[2] != "" {
+		return []acl.Key{newKey(args[2], "rw")}
+	}
+	for i := 5; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "AUTH":
+			i++
+		case "AUTH2":
+			i += 2
+		case "KEYS":
+			var keys []acl.Key
+			for _, k := range args[i+1:] {
+				keys = append(keys, newKey(k, "rw"))
+			}
+			return keys
+		}
+	}
+	return nil
+}
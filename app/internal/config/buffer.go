@@ -0,0 +1,96 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// OutputBufferLimit is one class of client-output-buffer-limit. A client is
+// disconnected once its pending output reaches Hard bytes, or stays at or
+// above Soft bytes for SoftSeconds. Zero disables a limit.
+type OutputBufferLimit struct {
+	Hard        int64
+	Soft        int64
+	SoftSeconds int
+}
+
+// outputBufferClasses lists the client classes in the order they are
+// rendered.
+var outputBufferClasses = []string{"normal", "replica", "pubsub"}
+
+// ParseOutputBufferLimits parses "<class> <hard> <soft> <seconds>" groups.
+// Sizes accept the k, kb, m, mb, g and gb suffixes.
+func ParseOutputBufferLimits(value string) (map[string]OutputBufferLimit, error) {
+	fields := strings.Fields(value)
+	if len(fields)%4 != 0 {
+		return nil, fmt.Errorf("Wrong number of arguments in buffer limit configuration.")
+	}
+
+	limits := make(map[string]OutputBufferLimit)
+	for i := 0; i < len(fields); i += 4 {
+		class := strings.ToLower(fields[i])
+		if class == "slave" {
+			class = "replica"
+		}
+		if class != "normal" && class != "replica" && class != "pubsub" {
+			return nil, fmt.Errorf("Invalid client class specified in buffer limit configuration.")
+		}
+
+		hard, err1 := parseMemory(fields[i+1])
+		soft, err2 := parseMemory(fields[i+2])
+		seconds, err3 := strconv.Atoi(fields[i+3])
+		if err1 != nil || err2 != nil || err3 != nil || hard < 0 || soft < 0 || seconds < 0 {
+			return nil, fmt.Errorf("Error in hard, soft or soft_seconds setting in buffer limit configuration.")
+		}
+		limits[class] = OutputBufferLimit{Hard: hard, Soft: soft, SoftSeconds: seconds}
+	}
+	return limits, nil
+}
+
+// mergeOutputBufferLimits updates only the classes named in value.
+func mergeOutputBufferLimits(old, value string) (string, error) {
+	limits, err := ParseOutputBufferLimits(old)
+	if err != nil {
+		return "", err
+	}
+	updates, err := ParseOutputBufferLimits(value)
+	if err != nil {
+		return "", err
+	}
+	for class, limit := range updates {
+		limits[class] = limit
+	}
+
+	parts := make([]string, 0, len(outputBufferClasses))
+	for _, class := range outputBufferClasses {
+		l := limits[class]
+		parts = append(parts, fmt.Sprintf("%s %d %d %d", class, l.Hard, l.Soft, l.SoftSeconds))
+	}
+	return strings.Join(parts, " "), nil
+}
+
+// parseMemory parses a byte count such as 100, 1k (1000) or 1kb (1024).
+func parseMemory(s string) (int64, error) {
+	units := []struct {
+		suffix string
+		mul    int64
+	}{
+		{"gb", 1 << 30}, {"mb", 1 << 20}, {"kb", 1 << 10},
+		{"g", 1000 * 1000 * 1000}, {"m", 1000 * 1000}, {"k", 1000}, {"b", 1},
+	}
+
+	lower := strings.ToLower(s)
+	mul := int64(1)
+	for _, u := range units {
+		if strings.HasSuffix(lower, u.suffix) {
+			lower, mul = strings.TrimSuffix(lower, u.suffix), u.mul
+			break
+		}
+	}
+	n, err := strconv.ParseInt(lower, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return n * mul, nil
+}
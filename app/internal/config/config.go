@@ -0,0 +1,215 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Config holds the server's runtime parameters. Values are kept in their
+// string form, the same way CONFIG GET and CONFIG SET see them.
+type Config struct {
+	mu     sync.RWMutex
+	params map[string]*param
+}
+
+type param struct {
+	value    string
+	validate func(string) error
+	merge    func(old, value string) (string, error)
+	watchers []func(string)
+}
+
+type definition struct {
+	name     string
+	value    string
+	validate func(string) error
+
+	// merge, when set, combines a new value with the current one instead of
+	// replacing it, and validates the result.
+	merge func(old, value string) (string, error)
+}
+
+// definitions lists every supported parameter with its default value.
+var definitions = []definition{
+	{name: "set-max-intset-entries", value: "512", validate: isInt(0, 1<<30)},
+	{name: "hll-sparse-max-bytes", value: "3000", validate: isInt(0, 1<<30)},
+	{name: "client-output-buffer-limit", value: "normal 0 0 0 replica 268435456 67108864 60 pubsub 33554432 8388608 60", merge: mergeOutputBufferLimits},
+	{name: "notify-keyspace-events", value: "", validate: isKeyspaceEvents},
+	{name: "requirepass", value: ""},
+	{name: "protected-mode", value: "yes", validate: isBool},
+	{name: "aclfile", value: ""},
+	{name: "acllog-max-len", value: "128", validate: isInt(0, 1<<30)},
+	{name: "port", value: "6379", validate: isInt(0, 65535)},
+	{name: "maxclients", value: "10000", validate: isInt(1, 1<<30)},
+	{name: "timeout", value: "0", validate: isInt(0, 1<<30)},
+	{name: "tcp-keepalive", value: "300", validate: isInt(0, 1<<30)},
+	{name: "tcp-backlog", value: "511", validate: isInt(0, 1<<30)},
+	{name: "unixsocket", value: ""},
+	{name: "unixsocketperm", value: "0", validate: isFileMode},
+	{name: "tls-port", value: "0", validate: isInt(0, 65535)},
+	{name: "tls-cert-file", value: ""},
+	{name: "tls-key-file", value: ""},
+	{name: "tls-ca-cert-file", value: ""},
+	{name: "tls-auth-clients", value: "yes", validate: isOneOf("yes", "no", "optional")},
+}
+
+func New() *Config {
+	c := &Config{params: make(map[string]*param)}
+	for _, def := range definitions {
+		c.params[def.name] = &param{value: def.value, validate: def.validate, merge: def.merge}
+	}
+	return c
+}
+
+// Names returns all parameter names in sorted order.
+func (c *Config) Names() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	names := make([]string, 0, len(c.params))
+	for name := range c.params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (c *Config) Get(name string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	p, ok := c.params[strings.ToLower(name)]
+	if !ok {
+		return "", false
+	}
+	return p.value, true
+}
+
+// Int returns the value of an integer parameter, or 0 if it is unknown.
+func (c *Config) Int(name string) int {
+	v, _ := c.Get(name)
+	n, _ := strconv.Atoi(v)
+	return n
+}
+
+// Bool reports whether a yes/no parameter is set to yes.
+func (c *Config) Bool(name string) bool {
+	v, _ := c.Get(name)
+	return strings.EqualFold(v, "yes")
+}
+
+// Set validates and stores value, then notifies the parameter's watchers.
+func (c *Config) Set(name, value string) error {
+	name = strings.ToLower(name)
+
+	c.mu.Lock()
+	p, ok := c.params[name]
+	if !ok {
+		c.mu.Unlock()
+		return fmt.Errorf("Unknown option or number of arguments for CONFIG SET - '%s'", name)
+	}
+	if p.validate != nil {
+		if err := p.validate(value); err != nil {
+			c.mu.Unlock()
+			return fmt.Errorf("CONFIG SET failed (possibly related to argument '%s') - %v", name, err)
+		}
+	}
+	if p.merge != nil {
+		merged, err := p.merge(p.value, value)
+		if err != nil {
+			c.mu.Unlock()
+			return fmt.Errorf("CONFIG SET failed (possibly related to argument '%s') - %v", name, err)
+		}
+		value = merged
+	}
+	p.value = value
+	watchers := p.watchers
+	c.mu.Unlock()
+
+	for _, fn := range watchers {
+		fn(value)
+	}
+	return nil
+}
+
+// SetArgs applies command line parameters given as "--name value" pairs.
+func (c *Config) SetArgs(args []string) error {
+	for i := 0; i < len(args); i += 2 {
+		name, ok := strings.CutPrefix(args[i], "--")
+		if !ok || i+1 == len(args) {
+			return fmt.Errorf("bad argument %q, expected --name value", args[i])
+		}
+		if err := c.Set(name, args[i+1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Watch calls fn with the current value of name and again after every
+// successful Set.
+func (c *Config) Watch(name string, fn func(value string)) {
+	c.mu.Lock()
+	p, ok := c.params[name]
+	if !ok {
+		c.mu.Unlock()
+		panic("config: unknown parameter " + name)
+	}
+	p.watchers = append(p.watchers, fn)
+	value := p.value
+	c.mu.Unlock()
+
+	fn(value)
+}
+
+func isInt(lo, hi int) func(string) error {
+	return func(v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("argument couldn't be parsed into an integer")
+		}
+		if n < lo || n > hi {
+			return fmt.Errorf("argument must be between %d and %d inclusive", lo, hi)
+		}
+		return nil
+	}
+}
+
+func isBool(v string) error {
+	if !strings.EqualFold(v, "yes") && !strings.EqualFold(v, "no") {
+		return fmt.Errorf("argument must be 'yes' or 'no'")
+	}
+	return nil
+}
+
+func isOneOf(values ...string) func(string) error {
+	return func(v string) error {
+		for _, value := range values {
+			if strings.EqualFold(v, value) {
+				return nil
+			}
+		}
+		return fmt.Errorf("argument must be one of '%s'", strings.Join(values, "', '"))
+	}
+}
+
+// isFileMode accepts octal permission bits such as 700.
+func isFileMode(v string) error {
+	if n, err := strconv.ParseUint(v, 8, 32); err != nil || n > 0777 {
+		return fmt.Errorf("argument must be octal permission bits between 0 and 777")
+	}
+	return nil
+}
+
+// isKeyspaceEvents accepts a string of notify-keyspace-events class flags.
+func isKeyspaceEvents(v string) error {
+	for _, c := range v {
+		if !strings.ContainsRune("AKEg$lshzxetmn", c) {
+			return fmt.Errorf("Invalid event class character. Use 'Ag$lshzxeKEtmn'.")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,447 @@
+package db
+
+import (
+	"math"
+	"math/bits"
+)
+
+// MaxBitOffset is the largest bit offset SETBIT and BITFIELD accept, keeping
+// strings within 512MB.
+const MaxBitOffset = 1<<32 - 1
+
+// Bitmap operations on string values. Bit 0 is the most significant bit of
+// the first byte, as in Redis.
+
+// bitmapBytes is a string value as it is read: a string, or the []byte an
+// item holds after bit operations changed it in place.
+type bitmapBytes interface {
+	~string | ~[]byte
+}
+
+func getBit[B bitmapBytes](b B, offset int) int {
+	if offset/8 >= len(b) {
+		return 0
+	}
+	return int(b[offset/8]>>(7-offset%8)) & 1
+}
+
+func setBit(b []byte, offset, bit int) {
+	mask := byte(1) << (7 - offset%8)
+	if bit == 1 {
+		b[offset/8] |= mask
+	} else {
+		b[offset/8] &^= mask
+	}
+}
+
+// SetBit sets the bit at offset and returns its previous value.
+func (d *DB) SetBit(key string, offset, bit int) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	itm, err := d.stringForWrite(key)
+	if err != nil {
+		return 0, err
+	}
+
+	b := itm.bytes(offset/8 + 1)
+	old := getBit(b, offset)
+	setBit(b, offset, bit)
+
+	d.modified(key)
+	d.notify(notifyString, "setbit", key)
+	return old, nil
+}
+
+func (d *DB) GetBit(key string, offset int) (int, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	itm, err := d.stringAt(key)
+	if err != nil || itm == nil {
+		return 0, err
+	}
+	if itm.bitmap != nil {
+		return getBit(itm.bitmap, offset), nil
+	}
+	return getBit(itm.StringValue, offset), nil
+}
+
+// BitRange selects part of a string for BITCOUNT and BITPOS. Start and End
+// are inclusive and may be negative; Bit switches them from byte to bit
+// offsets. HasEnd is false when the caller gave only a start.
+type BitRange struct {
+	Start, End int
+	HasEnd     bool
+	Bit        bool
+}
+
+// resolve turns r into absolute inclusive bit offsets over a string of n
+// bytes. ok is false when the range is empty.
+func (r *BitRange) resolve(n int) (startBit, endBit int, ok bool) {
+	total := n
+	if r.Bit {
+		total = n * 8
+	}
+
+	start, end := r.Start, r.End
+	if !r.HasEnd {
+		end = total - 1
+	}
+	if start < 0 {
+		start = max(total+start, 0)
+	}
+	if end < 0 {
+		end = max(total+end, 0)
+	}
+	if end >= total {
+		end = total - 1
+	}
+	if start > end || total == 0 {
+		return 0, 0, false
+	}
+
+	if r.Bit {
+		return start, end, true
+	}
+	return start * 8, end*8 + 7, true
+}
+
+// countBits counts the set bits between the inclusive bit offsets.
+func countBits[B bitmapBytes](b B, startBit, endBit int) int {
+	n := 0
+	first, last := startBit/8, endBit/8
+	for i := first; i <= last; i++ {
+		v := b[i]
+		if i == first {
+			v &= 0xff >> (startBit % 8)
+		}
+		if i == last {
+			v &= 0xff << (7 - endBit%8)
+		}
+		n += bits.OnesCount8(v)
+	}
+	return n
+}
+
+// BitCount counts set bits, optionally restricted to r.
+func (d *DB) BitCount(key string, r *BitRange) (int, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	itm, err := d.stringAt(key)
+	if err != nil || itm == nil {
+		return 0, err
+	}
+
+	rng := BitRange{Start: 0, End: -1, HasEnd: true}
+	if r != nil {
+		rng = *r
+	}
+
+	startBit, endBit, ok := rng.resolve(itm.strLen())
+	if !ok {
+		return 0, nil
+	}
+	if itm.bitmap != nil {
+		return countBits(itm.bitmap, startBit, endBit), nil
+	}
+	return countBits(itm.StringValue, startBit, endBit), nil
+}
+
+// BitPos returns the position of the first bit equal to bit, or -1.
+func (d *DB) BitPos(key string, bit int, r *BitRange) (int, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	itm, err := d.stringAt(key)
+	if err != nil {
+		return 0, err
+	}
+	if itm == nil {
+		// a missing key is an endless run of zeros
+		if bit == 0 {
+			return 0, nil
+		}
+		return -1, nil
+	}
+
+	rng := BitRange{Start: 0, End: -1, HasEnd: false}
+	if r != nil {
+		rng = *r
+	}
+
+	startBit, endBit, ok := rng.resolve(itm.strLen())
+	if !ok {
+		return -1, nil
+	}
+
+	var pos int
+	if itm.bitmap != nil {
+		pos = findBit(itm.bitmap, bit, startBit, endBit)
+	} else {
+		pos = findBit(itm.StringValue, bit, startBit, endBit)
+	}
+	if pos >= 0 {
+		return pos, nil
+	}
+
+	// When looking for a clear bit without an explicit end, the string is
+	// considered padded with zeros on the right.
+	if bit == 0 && !rng.HasEnd {
+		return endBit + 1, nil
+	}
+	return -1, nil
+}
+
+// findBit returns the position of the first bit equal to bit between the
+// inclusive bit offsets, or -1.
+func findBit[B bitmapBytes](b B, bit, startBit, endBit int) int {
+	skip := byte(0)
+	if bit == 0 {
+		skip = 0xff
+	}
+	for i := startBit; i <= endBit; {
+		if i%8 == 0 && i+7 <= endBit && b[i/8] == skip {
+			i += 8
+			continue
+		}
+		if getBit(b, i) == bit {
+			return i
+		}
+		i++
+	}
+	return -1
+}
+
+// BitOp stores the bitwise AND, OR, XOR or NOT of keys at dst and returns the
+// length of the result.
+func (d *DB) BitOp(op, dst string, keys ...string) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	srcs := make([]*item, len(keys))
+	maxLen := 0
+	for i, k := range keys {
+		itm, err := d.stringAt(k)
+		if err != nil {
+			return 0, err
+		}
+		srcs[i] = itm
+		maxLen = max(maxLen, itm.strLen())
+	}
+
+	result := make([]byte, maxLen)
+	for i := range result {
+		var v byte
+		for j, src := range srcs {
+			s := src.byteAt(i)
+			switch {
+			case j == 0:
+				v = s
+			case op == "AND":
+				v &= s
+			case op == "OR":
+				v |= s
+			case op == "XOR":
+				v ^= s
+			}
+		}
+		if op == "NOT" {
+			v = ^v
+		}
+		result[i] = v
+	}
+
+	existed := d.lookup(dst) != nil
+	if len(result) == 0 {
+		d.deleteItem(dst)
+		d.modified(dst)
+		if existed {
+			d.notify(notifyGeneric, "del", dst)
+		}
+		return 0, nil
+	}
+
+	d.setItem(dst, &item{Type: StringType, bitmap: result})
+	d.modified(dst)
+	if !existed {
+		d.notify(notifyNew, "new", dst)
+	}
+	d.notify(notifyString, "set", dst)
+	return len(result), nil
+}
+
+// BitFieldOp is a single BITFIELD subcommand.
+type BitFieldOp struct {
+	Kind     string // GET, SET or INCRBY
+	Signed   bool
+	Width    int
+	Offset   int
+	Value    int64  // SET value or INCRBY increment
+	Overflow string // WRAP, SAT or FAIL
+}
+
+// BitField runs ops in order against the string at key. ok[i] is false when
+// an operation failed under OVERFLOW FAIL. Lists of GET operations only read
+// the value; others change it in place, first growing it to cover every
+// SET and INCRBY as Redis does.
+func (d *DB) BitField(key string, ops []BitFieldOp) (results []int64, ok []bool, err error) {
+	size := 0 // bytes needed by the writes
+	for _, op := range ops {
+		if op.Kind != "GET" {
+			size = max(size, (op.Offset+op.Width+7)/8)
+		}
+	}
+	if size == 0 {
+		return d.bitFieldRead(key, ops)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	itm, err := d.stringAt(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	grown := itm == nil || itm.strLen() < size
+	itm, _ = d.stringForWrite(key)
+	b := itm.bytes(size)
+
+	written := false
+	results = make([]int64, len(ops))
+	ok = make([]bool, len(ops))
+	for i, op := range ops {
+		if op.Kind == "GET" {
+			results[i], ok[i] = readBitField(b, op), true
+			continue
+		}
+
+		old := readBitField(b, op)
+		var val int64
+		var overflow bool
+		if op.Kind == "SET" {
+			val, overflow = bitFieldOverflow(op, op.Value, 0)
+		} else {
+			val, overflow = bitFieldOverflow(op, old, op.Value)
+		}
+		if overflow && op.Overflow == "FAIL" {
+			continue
+		}
+
+		writeBitField(b, op, val)
+		written = true
+		ok[i] = true
+		if op.Kind == "SET" {
+			results[i] = old
+		} else {
+			results[i] = val
+		}
+	}
+
+	if written || grown {
+		d.modified(key)
+	}
+	if written {
+		d.notify(notifyString, "setbit", key)
+	}
+	return results, ok, nil
+}
+
+// bitFieldRead runs a list of GET operations under the read lock.
+func (d *DB) bitFieldRead(key string, ops []BitFieldOp) (results []int64, ok []bool, err error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	itm, err := d.stringAt(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	results = make([]int64, len(ops))
+	ok = make([]bool, len(ops))
+	for i, op := range ops {
+		switch {
+		case itm == nil:
+		case itm.bitmap != nil:
+			results[i] = readBitField(itm.bitmap, op)
+		default:
+			results[i] = readBitField(itm.StringValue, op)
+		}
+		ok[i] = true
+	}
+	return results, ok, nil
+}
+
+func readBitField[B bitmapBytes](b B, op BitFieldOp) int64 {
+	var v uint64
+	for i := 0; i < op.Width; i++ {
+		v = v<<1 | uint64(getBit(b, op.Offset+i))
+	}
+	if op.Signed && op.Width < 64 && v&(1<<(op.Width-1)) != 0 {
+		v |= math.MaxUint64 << op.Width
+	}
+	return int64(v)
+}
+
+func writeBitField(b []byte, op BitFieldOp, val int64) {
+	v := uint64(val)
+	for i := 0; i < op.Width; i++ {
+		setBit(b, op.Offset+i, int(v>>(op.Width-1-i))&1)
+	}
+}
+
+// bitFieldOverflow computes value+incr for the field described by op and
+// applies its overflow policy. It reports whether an overflow happened.
+func bitFieldOverflow(op BitFieldOp, value, incr int64) (int64, bool) {
+	if op.Signed {
+		maxVal := int64(math.MaxInt64)
+		if op.Width < 64 {
+			maxVal = 1<<(op.Width-1) - 1
+		}
+		minVal := -maxVal - 1
+		maxIncr, minIncr := maxVal-value, minVal-value
+
+		up := value > maxVal || (op.Width != 64 && incr > maxIncr) || (value >= 0 && incr > 0 && incr > maxIncr)
+		down := value < minVal || (op.Width != 64 && incr < minIncr) || (value < 0 && incr < 0 && incr < minIncr)
+
+		switch {
+		case (up || down) && op.Overflow == "SAT":
+			if up {
+				return maxVal, true
+			}
+			return minVal, true
+		case up || down:
+			// WRAP: keep the low bits and sign extend
+			v := uint64(value) + uint64(incr)
+			if op.Width < 64 {
+				mask := uint64(1)<<op.Width - 1
+				v &= mask
+				if v&(1<<(op.Width-1)) != 0 {
+					v |= ^mask
+				}
+			}
+			return int64(v), true
+		}
+		return value + incr, false
+	}
+
+	maxVal := uint64(1)<<op.Width - 1
+	uval := uint64(value)
+	maxIncr := int64(maxVal - uval)
+	minIncr := -int64(uval)
+
+	up := uval > maxVal || (incr > 0 && incr > maxIncr)
+	down := !up && incr < 0 && incr < minIncr
+
+	switch {
+	case (up || down) && op.Overflow == "SAT":
+		if up {
+			return int64(maxVal), true
+		}
+		return 0, true
+	case up || down:
+		return int64((uval + uint64(incr)) & maxVal), true
+	}
+	return int64(uval + uint64(incr)), false
+}
@@ -0,0 +1,142 @@
+package db
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestSetBitInPlace(t *testing.T) {
+	d := New()
+	d.Set("k", "a", 0) // 0x61
+	if _, err := d.SetBit("k", 1000, 1); err != nil {
+		t.Fatal(err)
+	}
+	b := d.store["k"].bitmap
+	if len(b) != 126 {
+		t.Fatalf("bitmap is %d bytes, want 126", len(b))
+	}
+
+	if old, _ := d.SetBit("k", 6, 1); old != 0 {
+		t.Errorf("SETBIT returned %d, want 0", old)
+	}
+	if &d.store["k"].bitmap[0] != &b[0] {
+		t.Error("SETBIT within the bitmap reallocated it")
+	}
+
+	if v, _ := d.GetBit("k", 6); v != 1 {
+		t.Errorf("GETBIT 6 = %d, want 1", v)
+	}
+	if v, _ := d.GetBit("k", 1000); v != 1 {
+		t.Errorf("GETBIT 1000 = %d, want 1", v)
+	}
+	if n, _ := d.BitCount("k", nil); n != 5 {
+		t.Errorf("BITCOUNT = %d, want 5", n)
+	}
+	if pos, _ := d.BitPos("k", 1, &BitRange{Start: 1}); pos != 1000 {
+		t.Errorf("BITPOS 1 from byte 1 = %d, want 1000", pos)
+	}
+	if v, _ := d.Get("k"); v[0] != 'c' || len(v) != 126 {
+		t.Errorf("GET = %q..., %d bytes; want \"c\"..., 126 bytes", v[:1], len(v))
+	}
+}
+
+func TestBitmapAsString(t *testing.T) {
+	d := New()
+	d.SetBit("k", 1, 1)
+	d.SetBit("k", 7, 1) // "A"
+
+	if n, _ := d.Append("k", "B"); n != 2 {
+		t.Errorf("APPEND returned %d, want 2", n)
+	}
+	if n, _ := d.SetRange("k", 2, "C"); n != 3 {
+		t.Errorf("SETRANGE returned %d, want 3", n)
+	}
+	if v, _ := d.Get("k"); v != "ABC" {
+		t.Errorf("GET = %q, want \"ABC\"", v)
+	}
+
+	if ok, _ := d.Copy("k", "copy", false); !ok {
+		t.Fatal("COPY did not copy")
+	}
+	d.SetBit("k", 6, 1)
+	if v, _ := d.Get("copy"); v != "ABC" {
+		t.Errorf("copy changed with the source: %q", v)
+	}
+
+	file := filepath.Join(t.TempDir(), "dump.json")
+	if err := d.Save(file); err != nil {
+		t.Fatal(err)
+	}
+	loaded := New()
+	if err := loaded.Load(file); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := loaded.Get("k"); v != "CBC" {
+		t.Errorf("loaded GET = %q, want \"CBC\"", v)
+	}
+
+	if _, err := d.IncrBy("k", 1); err != ErrNotInteger {
+		t.Errorf("INCRBY error = %v, want %v", err, ErrNotInteger)
+	}
+	d.SetRange("k", 0, "123")
+	if _, err := d.IncrBy("k", 1); err != nil {
+		t.Errorf("INCRBY: %v", err)
+	}
+	if v, _ := d.Get("k"); v != "124" {
+		t.Errorf("GET = %q, want \"124\"", v)
+	}
+}
+
+func TestBitFieldInPlace(t *testing.T) {
+	d := New()
+	set := BitFieldOp{Kind: "SET", Width: 8, Offset: 8, Value: 'b', Overflow: "WRAP"}
+	if _, ok, _ := d.BitField("k", []BitFieldOp{set}); !ok[0] {
+		t.Fatal("BITFIELD SET failed")
+	}
+	b := d.store["k"].bitmap
+
+	incr := BitFieldOp{Kind: "INCRBY", Width: 8, Offset: 8, Value: 1, Overflow: "WRAP"}
+	if res, _, _ := d.BitField("k", []BitFieldOp{incr}); res[0] != 'c' {
+		t.Errorf("BITFIELD INCRBY = %d, want %d", res[0], 'c')
+	}
+	if &d.store["k"].bitmap[0] != &b[0] {
+		t.Error("BITFIELD within the bitmap reallocated it")
+	}
+	if v, _ := d.Get("k"); v != "\x00c" {
+		t.Errorf("GET = %q, want \"\\x00c\"", v)
+	}
+
+	// reads leave plain strings and missing keys alone
+	d.Set("s", "a", 0)
+	get := BitFieldOp{Kind: "GET", Width: 8, Offset: 0}
+	if res, _, _ := d.BitField("s", []BitFieldOp{get}); res[0] != 'a' {
+		t.Errorf("BITFIELD GET = %d, want %d", res[0], 'a')
+	}
+	if d.store["s"].bitmap != nil {
+		t.Error("BITFIELD GET converted the value to a bitmap")
+	}
+	if res, _, _ := d.BitField("missing", []BitFieldOp{get}); res[0] != 0 || d.Exists("missing") != 0 {
+		t.Errorf("BITFIELD GET on a missing key = %d and created it", res[0])
+	}
+}
+
+func TestBitOpSources(t *testing.T) {
+	d := New()
+	d.Set("s", "\x0f\x0f", 0)
+	d.SetBit("b", 0, 1) // "\x80"
+
+	if n, _ := d.BitOp("OR", "dst", "s", "b", "missing"); n != 2 {
+		t.Errorf("BITOP OR returned %d, want 2", n)
+	}
+	if v, _ := d.Get("dst"); v != "\x8f\x0f" {
+		t.Errorf("BITOP OR = %q, want \"\\x8f\\x0f\"", v)
+	}
+	if d.store["s"].bitmap != nil {
+		t.Error("BITOP converted a source to a bitmap")
+	}
+
+	d.BitOp("AND", "dst", "s", "b")
+	if v, _ := d.Get("dst"); v != "\x00\x00" {
+		t.Errorf("BITOP AND = %q, want \"\\x00\\x00\"", v)
+	}
+}
@@ -2,10 +2,13 @@ package db
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -16,54 +19,190 @@ const (
 	ListType   ValueType = "list"
 	SetType    ValueType = "set"
 	HashType   ValueType = "hash"
+	ZSetType   ValueType = "zset"
 )
 
+var ErrWrongType = errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
+
 type item struct {
-	Type        ValueType           `json:"type"`
-	StringValue string              `json:"string_value,omitempty"`
-	ListValue   []string            `json:"list_value,omitempty"`
-	SetValue    map[string]struct{} `json:"set_value,omitempty"`
-	HashValue   map[string]string   `json:"hash_value,omitempty"`
-	ExpiresAt   time.Time           `json:"expires_at"`
+	Type        ValueType          `json:"type"`
+	StringValue string             `json:"string_value,omitempty"`
+	ListValue   []string           `json:"list_value,omitempty"`
+	SetValue    *setValue          `json:"set_value,omitempty"`
+	HashValue   map[string]string  `json:"hash_value,omitempty"`
+	ZSetValue   map[string]float64 `json:"zset_value,omitempty"`
+	ExpiresAt   time.Time          `json:"expires_at"`
+
+	// FieldExpires holds per-field expirations for hashes.
+	FieldExpires map[string]time.Time `json:"field_expires,omitempty"`
+
+	bitmap  []byte               // a string value changed in place by bit operations, see bytes
+	version uint64               // d.version of the item's latest change
+	index   *scanIndex           // hash fields or sorted set members in SCAN order, once scanned
+	scores  *skiplist[zsetEntry] // sorted set members by score, once searched
+}
+
+// MarshalJSON saves a string held as a bitmap as the plain string.
+func (i *item) MarshalJSON() ([]byte, error) {
+	type plain item
+	p := plain(*i)
+	p.StringValue = i.str()
+	return json.Marshal(&p)
 }
 
 type DB struct {
-	mu          sync.RWMutex
-	store       map[string]*item
-	subscribers map[string][]chan string // channelName -> list of subscriber channels
-	dirty       bool
+	mu      sync.RWMutex
+	store   map[string]*item
+	dirty   bool
+	version uint64 // counts changes, see modified
+
+	scanMu sync.Mutex // guards building the scan indexes, see scanIndexOf
+	keys   *scanIndex // the keys of store in SCAN order, once scanned
+
+	psMu        sync.RWMutex                                // guards the pub/sub state below
+	subscribers map[string]map[*Subscriber]struct{}         // channelName -> subscribers
+	patterns    map[string]map[*Subscriber]struct{}         // pattern -> subscribers
+	shards      map[int]map[string]map[*Subscriber]struct{} // slot -> shard channel -> subscribers
+
+	pubsubHardLimit    atomic.Int64 // client-output-buffer-limit pubsub
+	pubsubSoftLimit    atomic.Int64
+	pubsubSoftSeconds  atomic.Int64
+	pubsubDropped      atomic.Int64 // messages lost to disconnected subscribers
+	pubsubDisconnected atomic.Int64 // subscribers disconnected for overflowing
+
+	maxIntsetEntries  atomic.Int64 // set-max-intset-entries
+	hllSparseMaxBytes atomic.Int64 // hll-sparse-max-bytes
+	notifyFlags       atomic.Int64 // notify-keyspace-events
+
+	trackMu  sync.Mutex                       // guards the client tracking state below
+	tracked  map[string]map[*Tracker]struct{} // key -> default mode trackers that read it
+	trackers map[*Tracker]struct{}            // trackers that are on
+}
+
+func (i *item) expired(now time.Time) bool {
+	return !i.ExpiresAt.IsZero() && !i.ExpiresAt.After(now)
+}
+
+// lookup returns the live item stored at key, or nil if the key is missing
+// or already expired. Callers must hold d.mu.
+func (d *DB) lookup(key string) *item {
+	itm, ok := d.store[key]
+	if !ok || itm.expired(time.Now()) {
+		return nil
+	}
+	return itm
+}
+
+// setItem stores itm at key. Callers must hold d.mu for writing.
+func (d *DB) setItem(key string, itm *item) {
+	if _, ok := d.store[key]; !ok {
+		d.keys.add(key)
+	}
+	d.store[key] = itm
+}
+
+// deleteItem removes key. Callers must hold d.mu for writing.
+func (d *DB) deleteItem(key string) {
+	if _, ok := d.store[key]; ok {
+		delete(d.store, key)
+		d.keys.remove(key)
+	}
 }
 
 func New() *DB {
-	return &DB{
+	d := &DB{
 		store:       make(map[string]*item),
-		subscribers: make(map[string][]chan string),
+		subscribers: make(map[string]map[*Subscriber]struct{}),
+		patterns:    make(map[string]map[*Subscriber]struct{}),
+		shards:      make(map[int]map[string]map[*Subscriber]struct{}),
+	}
+	d.maxIntsetEntries.Store(512)
+	d.hllSparseMaxBytes.Store(3000)
+	d.SetPubSubOutputLimit(32<<20, 8<<20, 60)
+	return d
+}
+
+// MaxIntsetEntries is the largest set kept in the intset encoding.
+func (d *DB) MaxIntsetEntries() int {
+	return int(d.maxIntsetEntries.Load())
+}
+
+func (d *DB) SetMaxIntsetEntries(n int) {
+	d.maxIntsetEntries.Store(int64(n))
+}
+
+// ObjectEncoding reports the internal encoding of the value stored at key.
+func (d *DB) ObjectEncoding(key string) (string, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	itm := d.lookup(key)
+	if itm == nil {
+		return "", false
+	}
+
+	switch itm.Type {
+	case StringType:
+		if itm.bitmap == nil {
+			if _, err := strconv.ParseInt(itm.StringValue, 10, 64); err == nil {
+				return "int", true
+			}
+		}
+		if itm.strLen() <= 44 {
+			return "embstr", true
+		}
+		return "raw", true
+	case ListType:
+		return "quicklist", true
+	case SetType:
+		return itm.SetValue.encoding(), true
+	default:
+		return encodingHashtable, true
 	}
 }
 
 func (d *DB) Get(key string) (string, bool) {
+	// Writers such as INCR and SETBIT change items in place, so the value is
+	// copied before the lock is released.
 	d.mu.RLock()
 	itm, ok := d.store[key]
+	var val string
+	var isString, expired bool
+	if ok {
+		isString, expired = itm.Type == StringType, itm.expired(time.Now())
+		if isString && !expired {
+			val = itm.str()
+		}
+	}
 	d.mu.RUnlock()
 
-	if !ok || itm.Type != StringType {
+	if !ok {
+		d.notify(notifyKeyMiss, "keymiss", key)
+		return "", false
+	}
+	if !isString {
 		return "", false
 	}
 
-	if itm.ExpiresAt.IsZero() || itm.ExpiresAt.After(time.Now()) {
-		return itm.StringValue, true
+	if !expired {
+		return val, true
 	}
 
-	// expired, remove it
+	// expired, remove it unless it was replaced in the meantime
 	d.mu.Lock()
-	delete(d.store, key)
+	if d.store[key] == itm && itm.expired(time.Now()) {
+		d.deleteItem(key)
+		d.invalidate(key)
+	}
 	d.mu.Unlock()
+	d.notify(notifyExpired, "expired", key)
+	d.notify(notifyKeyMiss, "keymiss", key)
 	return "", false
 }
 
 func (d *DB) Set(key, val string, ttl time.Duration) {
-	d.mu.RLock()
-	defer d.mu.RUnlock()
+	d.mu.Lock()
+	defer d.mu.Unlock()
 
 	var expiresAt time.Time
 
@@ -71,36 +210,50 @@ func (d *DB) Set(key, val string, ttl time.Duration) {
 		expiresAt = time.Now().Add(ttl)
 	}
 
-	d.store[key] = &item{
+	if d.lookup(key) == nil {
+		d.notify(notifyNew, "new", key)
+	}
+	d.setItem(key, &item{
 		Type:        StringType,
 		StringValue: val,
 		ExpiresAt:   expiresAt,
-	}
+	})
 
-	d.dirty = true
+	d.modified(key)
+	d.notify(notifyString, "set", key)
+	if ttl > 0 {
+		d.notify(notifyGeneric, "expire", key)
+	}
 }
 
-func (d *DB) Delete(key string) bool {
-	d.mu.RLock()
-	defer d.mu.RUnlock()
-
-	_, ok := d.store[key]
+// Delete removes keys and returns how many of them existed. Expired keys
+// are removed too but not counted.
+func (d *DB) Delete(keys ...string) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
 
-	if ok {
-		delete(d.store, key)
-		d.dirty = true
+	n := 0
+	for _, key := range keys {
+		if d.lookup(key) != nil {
+			n++
+			d.notify(notifyGeneric, "del", key)
+		}
+		if _, ok := d.store[key]; ok {
+			d.deleteItem(key)
+			d.modified(key)
+		}
 	}
-
-	return ok
-
+	return n
 }
 
 func (d *DB) Flush() {
 	d.mu.Lock()
 	d.store = make(map[string]*item)
-	d.mu.Unlock()
-
+	d.keys = nil
 	d.dirty = true
+	d.invalidateAll()
+	d.notify(notifyGeneric, "flushall", "")
+	d.mu.Unlock()
 }
 
 // List Datastructure
@@ -120,10 +273,14 @@ func (d *DB) LPush(key string, values ...string) int {
 
 	itm.ListValue = append(values, itm.ListValue...)
 
-	d.store[key] = itm
+	d.setItem(key, itm)
 
-	d.dirty = true
+	d.modified(key)
 
+	if !exists {
+		d.notify(notifyNew, "new", key)
+	}
+	d.notify(notifyList, "lpush", key)
 	return len(itm.ListValue)
 
 }
@@ -142,10 +299,14 @@ func (d *DB) RPush(key string, values ...string) int {
 	}
 
 	itm.ListValue = append(itm.ListValue, values...)
-	d.store[key] = itm
+	d.setItem(key, itm)
 
-	d.dirty = true
+	d.modified(key)
 
+	if !exists {
+		d.notify(notifyNew, "new", key)
+	}
+	d.notify(notifyList, "rpush", key)
 	return len(itm.ListValue)
 
 }
@@ -183,140 +344,6 @@ func (d *DB) LRange(key string, start, end int) []string {
 
 }
 
-func (d *DB) SAdd(key string, members ...string) {
-	d.mu.Lock()
-	defer d.mu.Unlock()
-
-	itm, exists := d.store[key]
-	if !exists {
-		itm = &item{Type: SetType, SetValue: make(map[string]struct{})}
-	}
-	if itm.Type != SetType {
-		return
-	}
-
-	for _, m := range members {
-		itm.SetValue[m] = struct{}{}
-	}
-
-	d.store[key] = itm
-	d.dirty = true
-}
-
-func (d *DB) SMembers(key string) []string {
-	d.mu.RLock()
-	defer d.mu.RUnlock()
-
-	itm, ok := d.store[key]
-	if !ok || itm.Type != SetType {
-		return nil
-	}
-
-	members := make([]string, 0, len(itm.SetValue))
-	for k := range itm.SetValue {
-		members = append(members, k)
-	}
-	return members
-}
-
-func (d *DB) HSet(key, field, value string) {
-	d.mu.Lock()
-	defer d.mu.Unlock()
-
-	itm, exists := d.store[key]
-	if !exists {
-		itm = &item{Type: HashType, HashValue: make(map[string]string)}
-	}
-	if itm.Type != HashType {
-		return
-	}
-
-	itm.HashValue[field] = value
-	d.store[key] = itm
-	d.dirty = true
-}
-
-func (d *DB) HGet(key, field string) (string, bool) {
-	d.mu.RLock()
-	defer d.mu.RUnlock()
-
-	itm, ok := d.store[key]
-	if !ok || itm.Type != HashType {
-		return "", false
-	}
-
-	val, exists := itm.HashValue[field]
-	return val, exists
-}
-
-func (d *DB) HGetAll(key string) []string {
-	d.mu.RLock()
-	defer d.mu.RUnlock()
-
-	itm, ok := d.store[key]
-	if !ok || itm.Type != HashType {
-		return nil
-	}
-
-	result := make(map[string]string, len(itm.HashValue))
-	for k, v := range itm.HashValue {
-		result[k] = v
-	}
-
-	resultstring := make([]string, 0, len(result)*2)
-
-	for k, v := range result {
-		resultstring = append(resultstring, k)
-		resultstring = append(resultstring, v)
-	}
-
-	return resultstring
-}
-
-func (d *DB) Subscribe(channel string) <-chan string {
-
-	d.mu.Lock()
-	defer d.mu.Unlock()
-
-	ch := make(chan string, 10) // buffered to avoid blocking publisher
-	d.subscribers[channel] = append(d.subscribers[channel], ch)
-
-	return ch
-}
-
-func (d *DB) Unsubscribe(channel string, ch <-chan string) {
-
-	d.mu.Lock()
-	defer d.mu.Unlock()
-
-	subs := d.subscribers[channel]
-	for i, c := range subs {
-		if c == ch {
-			d.subscribers[channel] = append(subs[:i], subs[i+1:]...)
-			close(c)
-			break
-		}
-	}
-
-}
-
-func (d *DB) Publish(channel string, message string) int {
-
-	d.mu.RLock()
-	defer d.mu.RUnlock()
-
-	subs := d.subscribers[channel]
-	for _, c := range subs {
-		select {
-		case c <- message:
-		default:
-		}
-	}
-
-	return len(subs)
-
-}
-
 // cleaning up expired keys and snapshots persistence logic
 
 func (d *DB) StartJanitor(interval time.Duration, stopCh <-chan struct{}) {
@@ -346,9 +373,15 @@ func (d *DB) cleanup() {
 	deleted := 0
 	for k, itm := range d.store {
 		if !itm.ExpiresAt.IsZero() && itm.ExpiresAt.Before(now) {
-			delete(d.store, k)
+			d.deleteItem(k)
 			deleted++
+			d.invalidate(k)
+			d.notify(notifyExpired, "expired", k)
+			continue
+		}
 
+		if itm.Type == HashType && len(itm.FieldExpires) > 0 {
+			d.purgeFields(k, itm, now)
 		}
 	}
 
@@ -401,10 +434,25 @@ func (d *DB) Load(filename string) error {
 	for k, v := range data {
 		if !v.ExpiresAt.IsZero() && v.ExpiresAt.Before(now) {
 			delete(data, k)
+			continue
+		}
+		if v.Type == SetType && v.SetValue != nil {
+			v.SetValue.compact(d.MaxIntsetEntries())
+		}
+		if v.Type == HashType && len(v.FieldExpires) > 0 {
+			for f, t := range v.FieldExpires {
+				if !t.After(now) {
+					delete(v.HashValue, f)
+					delete(v.FieldExpires, f)
+				}
+			}
+			if len(v.HashValue) == 0 {
+				delete(data, k)
+			}
 		}
 	}
 
-	d.store = data
+	d.store, d.keys = data, nil
 	return nil
 
 }
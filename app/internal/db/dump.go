@@ -0,0 +1,307 @@
+package db
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc64"
+	"math"
+	"slices"
+	"time"
+)
+
+// DUMP payloads are a type byte followed by the encoded value, a two byte
+// format version and a CRC64 of everything before it, all little endian.
+// Strings are length prefixed with a uvarint. Key TTLs are not part of the
+// payload; RESTORE takes them as an argument.
+
+const dumpVersion = 1
+
+const (
+	dumpString byte = iota
+	dumpList
+	dumpSet
+	dumpHash
+	dumpZSet
+)
+
+var (
+	ErrBusyKey     = errors.New("BUSYKEY Target key name already exists.")
+	ErrDumpPayload = errors.New("ERR DUMP payload version or checksum are wrong")
+	ErrBadDump     = errors.New("ERR Bad data format")
+)
+
+var crcTable = crc64.MakeTable(crc64.ECMA)
+
+type dumpWriter struct {
+	buf []byte
+}
+
+func (w *dumpWriter) uvarint(n uint64) {
+	w.buf = binary.AppendUvarint(w.buf, n)
+}
+
+func (w *dumpWriter) string(s string) {
+	w.uvarint(uint64(len(s)))
+	w.buf = append(w.buf, s...)
+}
+
+type dumpReader struct {
+	buf []byte
+	err error
+}
+
+func (r *dumpReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	n, size := binary.Uvarint(r.buf)
+	if size <= 0 {
+		r.err = ErrBadDump
+		return 0
+	}
+	r.buf = r.buf[size:]
+	return n
+}
+
+// count reads an element count, rejecting counts the payload cannot hold.
+func (r *dumpReader) count() int {
+	n := r.uvarint()
+	if n > uint64(len(r.buf)) {
+		r.err = ErrBadDump
+		return 0
+	}
+	return int(n)
+}
+
+func (r *dumpReader) string() string {
+	n := r.uvarint()
+	if r.err != nil {
+		return ""
+	}
+	if n > uint64(len(r.buf)) {
+		r.err = ErrBadDump
+		return ""
+	}
+	s := string(r.buf[:n])
+	r.buf = r.buf[n:]
+	return s
+}
+
+func (r *dumpReader) uint64() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	if len(r.buf) < 8 {
+		r.err = ErrBadDump
+		return 0
+	}
+	v := binary.LittleEndian.Uint64(r.buf)
+	r.buf = r.buf[8:]
+	return v
+}
+
+// length returns the number of elements of a collection.
+func (i *item) length() int {
+	switch i.Type {
+	case ListType:
+		return len(i.ListValue)
+	case SetType:
+		return i.SetValue.len()
+	case HashType:
+		return len(i.HashValue)
+	case ZSetType:
+		return len(i.ZSetValue)
+	}
+	return 0
+}
+
+// encodeItem serializes the value of itm, leaving out expired hash fields.
+func encodeItem(itm *item, now time.Time) []byte {
+	var w dumpWriter
+
+	switch itm.Type {
+	case StringType:
+		w.buf = append(w.buf, dumpString)
+		w.string(itm.str())
+	case ListType:
+		w.buf = append(w.buf, dumpList)
+		w.uvarint(uint64(len(itm.ListValue)))
+		for _, v := range itm.ListValue {
+			w.string(v)
+		}
+	case SetType:
+		w.buf = append(w.buf, dumpSet)
+		w.uvarint(uint64(itm.SetValue.len()))
+		itm.SetValue.each(func(m string) bool {
+			w.string(m)
+			return true
+		})
+	case HashType:
+		// fields carry their expiry in unix milliseconds, 0 for none
+		live := itm.liveHash(now)
+		w.buf = append(w.buf, dumpHash)
+		w.uvarint(uint64(len(live)))
+		for f, v := range live {
+			w.string(f)
+			w.string(v)
+			var at uint64
+			if t, ok := itm.FieldExpires[f]; ok {
+				at = uint64(t.UnixMilli())
+			}
+			w.uvarint(at)
+		}
+	case ZSetType:
+		w.buf = append(w.buf, dumpZSet)
+		w.uvarint(uint64(len(itm.ZSetValue)))
+		for m, score := range itm.ZSetValue {
+			w.string(m)
+			w.buf = binary.LittleEndian.AppendUint64(w.buf, math.Float64bits(score))
+		}
+	}
+
+	w.buf = binary.LittleEndian.AppendUint16(w.buf, dumpVersion)
+	w.buf = binary.LittleEndian.AppendUint64(w.buf, crc64.Checksum(w.buf, crcTable))
+	return w.buf
+}
+
+// decodeItem parses a DUMP payload into a new item without a TTL.
+func decodeItem(payload []byte, maxIntset int) (*item, error) {
+	if len(payload) < 11 {
+		return nil, ErrDumpPayload
+	}
+	body, footer := payload[:len(payload)-10], payload[len(payload)-10:]
+	if binary.LittleEndian.Uint16(footer) != dumpVersion ||
+		binary.LittleEndian.Uint64(footer[2:]) != crc64.Checksum(payload[:len(payload)-8], crcTable) {
+		return nil, ErrDumpPayload
+	}
+
+	r := &dumpReader{buf: body[1:]}
+	var itm *item
+
+	switch body[0] {
+	case dumpString:
+		itm = &item{Type: StringType, StringValue: r.string()}
+	case dumpList:
+		n := r.count()
+		itm = &item{Type: ListType, ListValue: make([]string, 0, n)}
+		for range n {
+			itm.ListValue = append(itm.ListValue, r.string())
+		}
+	case dumpSet:
+		n := r.count()
+		itm = &item{Type: SetType, SetValue: newSetValue()}
+		for range n {
+			itm.SetValue.add(r.string(), maxIntset)
+		}
+	case dumpHash:
+		n := r.count()
+		itm = &item{Type: HashType, HashValue: make(map[string]string, n)}
+		for range n {
+			f, v := r.string(), r.string()
+			itm.HashValue[f] = v
+			if at := r.uvarint(); at != 0 {
+				if itm.FieldExpires == nil {
+					itm.FieldExpires = make(map[string]time.Time)
+				}
+				itm.FieldExpires[f] = time.UnixMilli(int64(at))
+			}
+		}
+	case dumpZSet:
+		n := r.count()
+		itm = &item{Type: ZSetType, ZSetValue: make(map[string]float64, n)}
+		for range n {
+			m := r.string()
+			itm.ZSetValue[m] = math.Float64frombits(r.uint64())
+		}
+	default:
+		return nil, ErrBadDump
+	}
+
+	if r.err != nil || len(r.buf) != 0 {
+		return nil, ErrBadDump
+	}
+	if itm.Type != StringType && itm.length() == 0 {
+		// empty collections never exist as keys
+		return nil, ErrBadDump
+	}
+	return itm, nil
+}
+
+// KeyVersion identifies the value of a key at the time it was dumped.
+type KeyVersion struct {
+	itm     *item
+	version uint64
+}
+
+// Dump serializes the value stored at key. expiresAt is the key's expiry,
+// zero when it has none, and version identifies the value dumped.
+func (d *DB) Dump(key string) (payload []byte, expiresAt time.Time, version KeyVersion, ok bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	now := time.Now()
+	itm := d.lookup(key)
+	if itm == nil || (itm.Type == HashType && !itm.hasLiveFields(now)) {
+		return nil, time.Time{}, KeyVersion{}, false
+	}
+	return encodeItem(itm, now), itm.ExpiresAt, KeyVersion{itm, itm.version}, true
+}
+
+// DeleteUnchanged deletes the keys whose value is still the version Dump
+// returned. It returns the other keys, which were written to or deleted
+// since, sorted.
+func (d *DB) DeleteUnchanged(versions map[string]KeyVersion) (changed []string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for key, v := range versions {
+		itm, ok := d.store[key]
+		if !ok || itm != v.itm || itm.version != v.version {
+			changed = append(changed, key)
+			continue
+		}
+		d.deleteItem(key)
+		d.notify(notifyGeneric, "del", key)
+		d.modified(key)
+	}
+	slices.Sort(changed)
+	return changed
+}
+
+// Restore creates key from a DUMP payload. Unless replace is set an existing
+// key is an error. A non-zero expiresAt in the past deletes the key instead,
+// as if it had been restored and expired at once.
+func (d *DB) Restore(key string, payload []byte, expiresAt time.Time, replace bool) error {
+	itm, err := decodeItem(payload, d.MaxIntsetEntries())
+	if err != nil {
+		return err
+	}
+	itm.ExpiresAt = expiresAt
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	existed := d.lookup(key) != nil
+	if !replace && existed {
+		return ErrBusyKey
+	}
+
+	if itm.expired(time.Now()) {
+		if _, ok := d.store[key]; ok {
+			d.deleteItem(key)
+			d.modified(key)
+			if existed {
+				d.notify(notifyGeneric, "del", key)
+			}
+		}
+		return nil
+	}
+
+	d.setItem(key, itm)
+	d.modified(key)
+	if !existed {
+		d.notify(notifyNew, "new", key)
+	}
+	d.notify(notifyGeneric, "restore", key)
+	return nil
+}
@@ -0,0 +1,35 @@
+package db
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDeleteUnchanged(t *testing.T) {
+	d := New()
+	d.Set("same", "1", 0)
+	d.Set("appended", "1", 0)
+	d.Set("replaced", "1", 0)
+
+	versions := make(map[string]KeyVersion)
+	for _, key := range []string{"same", "appended", "replaced"} {
+		_, _, v, ok := d.Dump(key)
+		if !ok {
+			t.Fatalf("Dump(%q) found nothing", key)
+		}
+		versions[key] = v
+	}
+
+	// writes while the keys are in flight
+	if _, err := d.Append("appended", "2"); err != nil {
+		t.Fatal(err)
+	}
+	d.Set("replaced", "2", 0)
+
+	if kept := d.DeleteUnchanged(versions); !slices.Equal(kept, []string{"appended", "replaced"}) {
+		t.Errorf("kept %q, want the 2 written to", kept)
+	}
+	if n := d.Exists("same", "appended", "replaced"); n != 2 {
+		t.Errorf("%d keys left, want the 2 written to", n)
+	}
+}
@@ -0,0 +1,470 @@
+package db
+
+import (
+	"cmp"
+	"errors"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Geo indexes are sorted sets whose scores are 52-bit interleaved geohashes,
+// encoded the same way Redis does so scores are interchangeable.
+
+const (
+	GeoLatMin  = -85.05112878
+	GeoLatMax  = 85.05112878
+	GeoLongMin = -180.0
+	GeoLongMax = 180.0
+
+	geoStep          = 26 // bits per coordinate
+	earthRadiusMeter = 6372797.560856
+	geoAlphabet      = "0123456789bcdefghjkmnpqrstuvwxyz"
+)
+
+var ErrGeoMember = errors.New("ERR could not decode requested zset member")
+
+// GeoPoint is a member of a geo index. Dist is filled in by searches, in
+// meters.
+type GeoPoint struct {
+	Member string
+	Lon    float64
+	Lat    float64
+	Hash   uint64
+	Dist   float64
+}
+
+// GeoQuery describes a GEOSEARCH. The center is either a member of the index
+// or a longitude/latitude pair; the shape is a radius or a box, in meters.
+type GeoQuery struct {
+	FromMember string
+	UseMember  bool
+	Lon, Lat   float64
+
+	ByBox         bool
+	Radius        float64
+	Width, Height float64
+
+	Sort  int // 0 unsorted, 1 ascending, -1 descending
+	Count int // 0 means no limit
+	Any   bool
+}
+
+// spreadBits moves the low 32 bits of v to the even bit positions.
+func spreadBits(v uint64) uint64 {
+	v &= 0xffffffff
+	v = (v | v<<16) & 0x0000ffff0000ffff
+	v = (v | v<<8) & 0x00ff00ff00ff00ff
+	v = (v | v<<4) & 0x0f0f0f0f0f0f0f0f
+	v = (v | v<<2) & 0x3333333333333333
+	v = (v | v<<1) & 0x5555555555555555
+	return v
+}
+
+// squashBits is the inverse of spreadBits.
+func squashBits(v uint64) uint64 {
+	v &= 0x5555555555555555
+	v = (v | v>>1) & 0x3333333333333333
+	v = (v | v>>2) & 0x0f0f0f0f0f0f0f0f
+	v = (v | v>>4) & 0x00ff00ff00ff00ff
+	v = (v | v>>8) & 0x0000ffff0000ffff
+	v = (v | v>>16) & 0x00000000ffffffff
+	return v
+}
+
+// geohashEncode interleaves latitude (even bits) and longitude (odd bits)
+// quantized to geoStep bits each within the given ranges.
+func geohashEncode(lon, lat, latMin, latMax float64) uint64 {
+	latOffset := (lat - latMin) / (latMax - latMin)
+	lonOffset := (lon - GeoLongMin) / (GeoLongMax - GeoLongMin)
+	latBits := uint64(latOffset * (1 << geoStep))
+	lonBits := uint64(lonOffset * (1 << geoStep))
+	return spreadBits(latBits) | spreadBits(lonBits)<<1
+}
+
+// GeohashEncode returns the 52-bit score for a coordinate pair.
+func GeohashEncode(lon, lat float64) uint64 {
+	return geohashEncode(lon, lat, GeoLatMin, GeoLatMax)
+}
+
+// GeohashDecode returns the center of the cell a score describes.
+func GeohashDecode(hash uint64) (lon, lat float64) {
+	latBits := squashBits(hash)
+	lonBits := squashBits(hash >> 1)
+
+	const cells = 1 << geoStep
+	latMin := GeoLatMin + float64(latBits)/cells*(GeoLatMax-GeoLatMin)
+	latMax := GeoLatMin + float64(latBits+1)/cells*(GeoLatMax-GeoLatMin)
+	lonMin := GeoLongMin + float64(lonBits)/cells*(GeoLongMax-GeoLongMin)
+	lonMax := GeoLongMin + float64(lonBits+1)/cells*(GeoLongMax-GeoLongMin)
+
+	lon = min(max((lonMin+lonMax)/2, GeoLongMin), GeoLongMax)
+	lat = min(max((latMin+latMax)/2, GeoLatMin), GeoLatMax)
+	return lon, lat
+}
+
+// GeohashString renders a score as a standard 11 character geohash. Scores
+// use a latitude range of +/-85 degrees, so the position is re-encoded with
+// the standard +/-90 range first.
+func GeohashString(hash uint64) string {
+	lon, lat := GeohashDecode(hash)
+	std := geohashEncode(lon, lat, -90, 90)
+
+	buf := make([]byte, 11)
+	for i := range buf {
+		idx := 0
+		// only 52 bits are available; the last character is always '0'
+		if i < 10 {
+			idx = int(std>>(52-(i+1)*5)) & 0x1f
+		}
+		buf[i] = geoAlphabet[idx]
+	}
+	return string(buf)
+}
+
+func degRad(d float64) float64 {
+	return d * math.Pi / 180
+}
+
+func geoLatDistance(lat1, lat2 float64) float64 {
+	return earthRadiusMeter * math.Abs(degRad(lat2)-degRad(lat1))
+}
+
+// GeoDistance is the haversine distance in meters between two points.
+func GeoDistance(lon1, lat1, lon2, lat2 float64) float64 {
+	v := math.Sin((degRad(lon2) - degRad(lon1)) / 2)
+	if v == 0 {
+		return geoLatDistance(lat1, lat2)
+	}
+	lat1r, lat2r := degRad(lat1), degRad(lat2)
+	u := math.Sin((lat2r - lat1r) / 2)
+	a := u*u + math.Cos(lat1r)*math.Cos(lat2r)*v*v
+	return 2 * earthRadiusMeter * math.Asin(math.Sqrt(a))
+}
+
+// zsetAt returns the sorted set stored at key, or nil if there is none.
+// Callers must hold d.mu.
+func (d *DB) zsetAt(key string) (*item, error) {
+	itm := d.lookup(key)
+	if itm == nil {
+		return nil, nil
+	}
+	if itm.Type != ZSetType {
+		return nil, ErrWrongType
+	}
+	return itm, nil
+}
+
+// zsetEntry orders sorted set members by score, then by member.
+type zsetEntry struct {
+	score  float64
+	member string
+}
+
+func compareZSetEntries(a, b zsetEntry) int {
+	return cmp.Or(cmp.Compare(a.score, b.score), strings.Compare(a.member, b.member))
+}
+
+// zsetScores returns the members of the sorted set itm ordered by score,
+// building the index first if needed. Callers must hold d.mu for reading;
+// like scanIndexOf, the index is built once under d.scanMu and writers keep
+// a built one up to date.
+func (d *DB) zsetScores(itm *item) *skiplist[zsetEntry] {
+	d.scanMu.Lock()
+	defer d.scanMu.Unlock()
+
+	if itm.scores == nil {
+		itm.scores = newSkiplist(compareZSetEntries)
+		for m, score := range itm.ZSetValue {
+			itm.scores.insert(zsetEntry{score, m})
+		}
+	}
+	return itm.scores
+}
+
+// zsetSet sets the score of a sorted set member. Callers must hold d.mu for
+// writing.
+func (i *item) zsetSet(member string, score float64) {
+	old, ok := i.ZSetValue[member]
+	if !ok {
+		i.index.add(member)
+	}
+	i.ZSetValue[member] = score
+
+	if i.scores != nil {
+		if ok {
+			i.scores.delete(zsetEntry{old, member})
+		}
+		i.scores.insert(zsetEntry{score, member})
+	}
+}
+
+// GeoAdd adds or updates points. With nx only new members are added, with
+// xx only existing ones are updated. It returns the number of members added,
+// or added plus updated when ch is set.
+func (d *DB) GeoAdd(key string, nx, xx, ch bool, points []GeoPoint) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	itm, err := d.zsetAt(key)
+	if err != nil {
+		return 0, err
+	}
+	created := itm == nil
+	if created {
+		if xx {
+			return 0, nil
+		}
+		itm = &item{Type: ZSetType, ZSetValue: make(map[string]float64)}
+		d.setItem(key, itm)
+	}
+
+	added, changed := 0, 0
+	for _, p := range points {
+		score := float64(GeohashEncode(p.Lon, p.Lat))
+		old, exists := itm.ZSetValue[p.Member]
+		switch {
+		case exists && nx, !exists && xx:
+			continue
+		case !exists:
+			added++
+		case old != score:
+			changed++
+		default:
+			continue
+		}
+		itm.zsetSet(p.Member, score)
+	}
+
+	if len(itm.ZSetValue) == 0 {
+		d.deleteItem(key)
+	}
+	if added+changed > 0 {
+		d.modified(key)
+		if created {
+			d.notify(notifyNew, "new", key)
+		}
+		d.notify(notifyZSet, "zadd", key)
+	}
+	if ch {
+		return added + changed, nil
+	}
+	return added, nil
+}
+
+// GeoPos returns the decoded position of each member; ok[i] is false for
+// members that are not in the index.
+func (d *DB) GeoPos(key string, members ...string) (points []GeoPoint, ok []bool, err error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	itm, err := d.zsetAt(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	points = make([]GeoPoint, len(members))
+	ok = make([]bool, len(members))
+	if itm == nil {
+		return points, ok, nil
+	}
+	for i, m := range members {
+		score, found := itm.ZSetValue[m]
+		if !found {
+			continue
+		}
+		hash := uint64(score)
+		lon, lat := GeohashDecode(hash)
+		points[i] = GeoPoint{Member: m, Lon: lon, Lat: lat, Hash: hash}
+		ok[i] = true
+	}
+	return points, ok, nil
+}
+
+// GeoDist returns the distance in meters between two members.
+func (d *DB) GeoDist(key, m1, m2 string) (float64, bool, error) {
+	points, ok, err := d.GeoPos(key, m1, m2)
+	if err != nil || !ok[0] || !ok[1] {
+		return 0, false, err
+	}
+	return GeoDistance(points[0].Lon, points[0].Lat, points[1].Lon, points[1].Lat), true, nil
+}
+
+// GeoSearch returns the members of the index at key inside the query shape.
+func (d *DB) GeoSearch(key string, q GeoQuery) ([]GeoPoint, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.geoSearch(key, q)
+}
+
+// GeoSearchStore stores the result of a search at dst. Scores are the
+// members' geohashes, or their distances divided by unit when storeDist is
+// set. It returns the number of stored members.
+func (d *DB) GeoSearchStore(dst, src string, q GeoQuery, storeDist bool, unit float64) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	points, err := d.geoSearch(src, q)
+	if err != nil {
+		return 0, err
+	}
+
+	existed := d.lookup(dst) != nil
+	if len(points) == 0 {
+		d.deleteItem(dst)
+		d.modified(dst)
+		if existed {
+			d.notify(notifyGeneric, "del", dst)
+		}
+		return 0, nil
+	}
+
+	zset := make(map[string]float64, len(points))
+	for _, p := range points {
+		if storeDist {
+			zset[p.Member] = p.Dist / unit
+		} else {
+			zset[p.Member] = float64(p.Hash)
+		}
+	}
+	d.setItem(dst, &item{Type: ZSetType, ZSetValue: zset})
+	d.modified(dst)
+	if !existed {
+		d.notify(notifyNew, "new", dst)
+	}
+	d.notify(notifyZSet, "geosearchstore", dst)
+	return len(points), nil
+}
+
+// geoSearch runs q against the index at key. Callers must hold d.mu.
+func (d *DB) geoSearch(key string, q GeoQuery) ([]GeoPoint, error) {
+	itm, err := d.zsetAt(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if q.UseMember {
+		if itm == nil {
+			return nil, ErrGeoMember
+		}
+		score, ok := itm.ZSetValue[q.FromMember]
+		if !ok {
+			return nil, ErrGeoMember
+		}
+		q.Lon, q.Lat = GeohashDecode(uint64(score))
+	}
+	if itm == nil {
+		return nil, nil
+	}
+
+	var points []GeoPoint
+	scores := d.zsetScores(itm)
+search:
+	for _, cell := range geoSearchCells(q) {
+		for e := range scores.from(zsetEntry{score: float64(cell[0])}) {
+			if e.score >= float64(cell[1]) {
+				break
+			}
+			hash := uint64(e.score)
+			lon, lat := GeohashDecode(hash)
+
+			var dist float64
+			if q.ByBox {
+				if geoLatDistance(lat, q.Lat) > q.Height/2 ||
+					GeoDistance(lon, lat, q.Lon, lat) > q.Width/2 {
+					continue
+				}
+				dist = GeoDistance(q.Lon, q.Lat, lon, lat)
+			} else {
+				dist = GeoDistance(q.Lon, q.Lat, lon, lat)
+				if dist > q.Radius {
+					continue
+				}
+			}
+
+			points = append(points, GeoPoint{Member: e.member, Lon: lon, Lat: lat, Hash: hash, Dist: dist})
+			if q.Any && q.Count > 0 && len(points) == q.Count {
+				break search
+			}
+		}
+	}
+
+	switch q.Sort {
+	case 1:
+		sort.Slice(points, func(i, j int) bool { return points[i].Dist < points[j].Dist })
+	case -1:
+		sort.Slice(points, func(i, j int) bool { return points[i].Dist > points[j].Dist })
+	}
+
+	if q.Count > 0 && len(points) > q.Count {
+		points = points[:q.Count]
+	}
+	return points, nil
+}
+
+// geoSearchCells returns the score ranges, each [min, max), of the geohash
+// cells that can hold members inside the query shape: the cell of the
+// center and its eight neighbours, at the finest step where these cover the
+// shape's bounding box.
+func geoSearchCells(q GeoQuery) [][2]uint64 {
+	latRange, lonRange := q.Radius, q.Radius
+	if q.ByBox {
+		latRange, lonRange = q.Height/2, q.Width/2
+	}
+
+	latDelta := latRange / earthRadiusMeter * 180 / math.Pi
+	latMin := max(q.Lat-latDelta, GeoLatMin)
+	latMax := min(q.Lat+latDelta, GeoLatMax)
+
+	// Points within lonRange of a point at most farLat degrees from the
+	// equator differ by at most lonDelta degrees in longitude.
+	lonDelta := 360.0
+	farLat := math.Abs(q.Lat) + latDelta
+	if farLat < 90 {
+		if s := math.Sin(lonRange/(2*earthRadiusMeter)) / math.Cos(degRad(farLat)); s < 1 {
+			lonDelta = 2 * math.Asin(s) * 180 / math.Pi
+		}
+	}
+	lonMin, lonMax := q.Lon-lonDelta, q.Lon+lonDelta
+
+	for step := geoStep; step > 0 && lonDelta < 180; step-- {
+		latLo, latHi, latC := geoCell(latMin, GeoLatMin, GeoLatMax, step),
+			geoCell(latMax, GeoLatMin, GeoLatMax, step), geoCell(q.Lat, GeoLatMin, GeoLatMax, step)
+		lonLo, lonHi, lonC := geoCell(lonMin, GeoLongMin, GeoLongMax, step),
+			geoCell(lonMax, GeoLongMin, GeoLongMax, step), geoCell(q.Lon, GeoLongMin, GeoLongMax, step)
+		if latLo < latC-1 || latHi > latC+1 || lonLo < lonC-1 || lonHi > lonC+1 {
+			continue
+		}
+
+		cells := int64(1) << step
+		shift := 2 * (geoStep - step)
+		seen := make(map[uint64]bool)
+		var ranges [][2]uint64
+		for lat := max(latLo, 0); lat <= min(latHi, cells-1); lat++ {
+			for lon := lonLo; lon <= lonHi; lon++ {
+				// longitudes wrap around the antimeridian
+				hash := spreadBits(uint64(lat)) | spreadBits(uint64((lon%cells+cells)%cells))<<1
+				if !seen[hash] {
+					seen[hash] = true
+					ranges = append(ranges, [2]uint64{hash << shift, (hash + 1) << shift})
+				}
+			}
+		}
+		return ranges
+	}
+
+	// the shape spans most of the globe
+	return [][2]uint64{{0, 1 << (2 * geoStep)}}
+}
+
+// geoCell returns the index of the cell holding v among the 2^step cells
+// dividing [lo, hi]. Values outside the range give indexes outside
+// [0, 2^step), except hi itself, which belongs to the last cell.
+func geoCell(v, lo, hi float64, step int) int64 {
+	idx := int64(math.Floor((v - lo) / (hi - lo) * (1 << geoStep)))
+	if v == hi {
+		idx--
+	}
+	return idx >> (geoStep - step)
+}
@@ -0,0 +1,98 @@
+package db
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+// geoMatchesAll returns the members a search over every point should find.
+func geoMatchesAll(points []GeoPoint, q GeoQuery) []string {
+	var out []string
+	for _, p := range points {
+		lon, lat := GeohashDecode(GeohashEncode(p.Lon, p.Lat))
+		if q.ByBox {
+			if geoLatDistance(lat, q.Lat) > q.Height/2 || GeoDistance(lon, lat, q.Lon, lat) > q.Width/2 {
+				continue
+			}
+		} else if GeoDistance(q.Lon, q.Lat, lon, lat) > q.Radius {
+			continue
+		}
+		out = append(out, p.Member)
+	}
+	slices.Sort(out)
+	return out
+}
+
+func TestGeoSearchMatchesFullScan(t *testing.T) {
+	d := New()
+	rnd := rand.New(rand.NewPCG(1, 2))
+
+	var points []GeoPoint
+	for i := range 5000 {
+		p := GeoPoint{
+			Member: fmt.Sprint("p", i),
+			Lon:    rnd.Float64()*360 - 180,
+			Lat:    rnd.Float64()*170 - 85,
+		}
+		if i%2 == 0 {
+			// crowd some points around the antimeridian and the poles
+			p.Lon = 179 + rnd.Float64()*2
+			if p.Lon >= 180 {
+				p.Lon -= 360
+			}
+			if i%4 == 0 {
+				p.Lat = 80 + rnd.Float64()*5
+			}
+		}
+		points = append(points, p)
+	}
+	if _, err := d.GeoAdd("geo", false, false, false, points); err != nil {
+		t.Fatal(err)
+	}
+
+	centers := [][2]float64{{0, 0}, {179.9, 0}, {-179.9, 83}, {13.36, 38.11}, {180, 85}}
+	for _, c := range centers {
+		for _, r := range []float64{0, 1000, 50e3, 500e3, 3000e3, 20000e3} {
+			queries := []GeoQuery{
+				{Lon: c[0], Lat: c[1], Radius: r},
+				{Lon: c[0], Lat: c[1], ByBox: true, Width: 2 * r, Height: r},
+			}
+			for _, q := range queries {
+				found, err := d.GeoSearch("geo", q)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var got []string
+				for _, p := range found {
+					got = append(got, p.Member)
+				}
+				slices.Sort(got)
+				if want := geoMatchesAll(points, q); !slices.Equal(got, want) {
+					t.Errorf("search %+v found %d members, want %d", q, len(got), len(want))
+				}
+			}
+		}
+	}
+}
+
+func TestGeoSearchAfterUpdate(t *testing.T) {
+	d := New()
+	d.GeoAdd("geo", false, false, false, []GeoPoint{{Member: "a", Lon: 10, Lat: 10}})
+
+	q := GeoQuery{Lon: 10, Lat: 10, Radius: 1000}
+	if found, _ := d.GeoSearch("geo", q); len(found) != 1 {
+		t.Fatalf("found %d members, want 1", len(found))
+	}
+
+	// the score index built by the search follows the move
+	d.GeoAdd("geo", false, false, false, []GeoPoint{{Member: "a", Lon: -50, Lat: -20}})
+	if found, _ := d.GeoSearch("geo", q); len(found) != 0 {
+		t.Errorf("found %d members at the old position, want 0", len(found))
+	}
+	q.Lon, q.Lat = -50, -20
+	if found, _ := d.GeoSearch("geo", q); len(found) != 1 {
+		t.Errorf("found %d members at the new position, want 1", len(found))
+	}
+}
@@ -0,0 +1,376 @@
+package db
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrHashNotInteger = errors.New("ERR hash value is not an integer")
+	ErrHashNotFloat   = errors.New("ERR hash value is not a float")
+	ErrOverflow       = errors.New("ERR increment or decrement would overflow")
+	ErrNaN            = errors.New("ERR increment would produce NaN or Infinity")
+)
+
+// Hash Datastructure
+
+// hashAt returns the hash item stored at key, or nil if there is none or all
+// of its fields have expired. Callers must hold d.mu.
+func (d *DB) hashAt(key string) (*item, error) {
+	itm := d.lookup(key)
+	if itm == nil {
+		return nil, nil
+	}
+	if itm.Type != HashType {
+		return nil, ErrWrongType
+	}
+	if !itm.hasLiveFields(time.Now()) {
+		return nil, nil
+	}
+	return itm, nil
+}
+
+// hashSet sets a field of a hash item. Callers must hold d.mu for writing.
+func (i *item) hashSet(field, value string) {
+	if _, ok := i.HashValue[field]; !ok {
+		i.index.add(field)
+	}
+	i.HashValue[field] = value
+}
+
+// hashDelete removes a field of a hash item. Callers must hold d.mu for
+// writing.
+func (i *item) hashDelete(field string) {
+	if _, ok := i.HashValue[field]; ok {
+		delete(i.HashValue, field)
+		i.index.remove(field)
+	}
+}
+
+// hashForUpdate is hashAt for writers: expired fields are removed before the
+// item is returned. Callers must hold d.mu for writing.
+func (d *DB) hashForUpdate(key string) (*item, error) {
+	itm, err := d.hashAt(key)
+	if err != nil || itm == nil {
+		return nil, err
+	}
+	d.purgeFields(key, itm, time.Now())
+	return itm, nil
+}
+
+// hashForWrite returns the hash item at key, creating an empty one when the
+// key does not exist. Callers must hold d.mu for writing.
+func (d *DB) hashForWrite(key string) (*item, error) {
+	itm, err := d.hashForUpdate(key)
+	if err != nil {
+		return nil, err
+	}
+	if itm == nil {
+		itm = &item{Type: HashType, HashValue: make(map[string]string)}
+		d.setItem(key, itm)
+		d.notify(notifyNew, "new", key)
+	}
+	return itm, nil
+}
+
+// HSet sets the given field/value pairs and returns how many fields were
+// newly created.
+func (d *DB) HSet(key string, pairs ...string) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	itm, err := d.hashForWrite(key)
+	if err != nil {
+		return 0, err
+	}
+
+	added := 0
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if _, ok := itm.HashValue[pairs[i]]; !ok {
+			added++
+		}
+		itm.hashSet(pairs[i], pairs[i+1])
+		delete(itm.FieldExpires, pairs[i])
+	}
+
+	d.modified(key)
+	d.notify(notifyHash, "hset", key)
+	return added, nil
+}
+
+func (d *DB) HSetNX(key, field, value string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	itm, err := d.hashForWrite(key)
+	if err != nil {
+		return false, err
+	}
+
+	if _, ok := itm.HashValue[field]; ok {
+		return false, nil
+	}
+	itm.hashSet(field, value)
+
+	d.modified(key)
+	d.notify(notifyHash, "hset", key)
+	return true, nil
+}
+
+func (d *DB) HGet(key, field string) (string, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	itm, err := d.hashAt(key)
+	if err != nil || itm == nil {
+		return "", false
+	}
+
+	return itm.hashGet(field, time.Now())
+}
+
+// HMGet returns the values of fields; ok[i] is false for missing fields.
+func (d *DB) HMGet(key string, fields ...string) (vals []string, ok []bool, err error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	itm, err := d.hashAt(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	vals = make([]string, len(fields))
+	ok = make([]bool, len(fields))
+	if itm == nil {
+		return vals, ok, nil
+	}
+	now := time.Now()
+	for i, f := range fields {
+		vals[i], ok[i] = itm.hashGet(f, now)
+	}
+	return vals, ok, nil
+}
+
+func (d *DB) HGetAll(key string) []string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	itm, err := d.hashAt(key)
+	if err != nil || itm == nil {
+		return nil
+	}
+
+	hash := itm.liveHash(time.Now())
+	resultstring := make([]string, 0, len(hash)*2)
+
+	for k, v := range hash {
+		resultstring = append(resultstring, k)
+		resultstring = append(resultstring, v)
+	}
+
+	return resultstring
+}
+
+// HDel removes fields and deletes the key once the hash is empty.
+func (d *DB) HDel(key string, fields ...string) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	itm, err := d.hashForUpdate(key)
+	if err != nil || itm == nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, f := range fields {
+		if _, ok := itm.HashValue[f]; ok {
+			itm.hashDelete(f)
+			delete(itm.FieldExpires, f)
+			removed++
+		}
+	}
+
+	if removed > 0 {
+		d.modified(key)
+		d.notify(notifyHash, "hdel", key)
+	}
+	if len(itm.HashValue) == 0 {
+		d.deleteItem(key)
+		d.notify(notifyGeneric, "del", key)
+	}
+	return removed, nil
+}
+
+func (d *DB) HExists(key, field string) (bool, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	itm, err := d.hashAt(key)
+	if err != nil || itm == nil {
+		return false, err
+	}
+
+	_, ok := itm.hashGet(field, time.Now())
+	return ok, nil
+}
+
+func (d *DB) HLen(key string) (int, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	itm, err := d.hashAt(key)
+	if err != nil || itm == nil {
+		return 0, err
+	}
+	return len(itm.liveHash(time.Now())), nil
+}
+
+func (d *DB) HStrLen(key, field string) (int, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	itm, err := d.hashAt(key)
+	if err != nil || itm == nil {
+		return 0, err
+	}
+	val, _ := itm.hashGet(field, time.Now())
+	return len(val), nil
+}
+
+func (d *DB) HKeys(key string) ([]string, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	itm, err := d.hashAt(key)
+	if err != nil || itm == nil {
+		return nil, err
+	}
+
+	hash := itm.liveHash(time.Now())
+	keys := make([]string, 0, len(hash))
+	for k := range hash {
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+func (d *DB) HVals(key string) ([]string, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	itm, err := d.hashAt(key)
+	if err != nil || itm == nil {
+		return nil, err
+	}
+
+	hash := itm.liveHash(time.Now())
+	vals := make([]string, 0, len(hash))
+	for _, v := range hash {
+		vals = append(vals, v)
+	}
+	return vals, nil
+}
+
+func (d *DB) HIncrBy(key, field string, delta int64) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	itm, err := d.hashForWrite(key)
+	if err != nil {
+		return 0, err
+	}
+
+	var cur int64
+	if v, ok := itm.HashValue[field]; ok {
+		cur, err = strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return 0, ErrHashNotInteger
+		}
+	}
+
+	if (delta > 0 && cur > math.MaxInt64-delta) || (delta < 0 && cur < math.MinInt64-delta) {
+		return 0, ErrOverflow
+	}
+
+	cur += delta
+	itm.hashSet(field, strconv.FormatInt(cur, 10))
+
+	d.modified(key)
+	d.notify(notifyHash, "hincrby", key)
+	return cur, nil
+}
+
+func (d *DB) HIncrByFloat(key, field string, delta float64) (string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	itm, err := d.hashForWrite(key)
+	if err != nil {
+		return "", err
+	}
+
+	var cur float64
+	if v, ok := itm.HashValue[field]; ok {
+		cur, err = strconv.ParseFloat(v, 64)
+		if err != nil || math.IsNaN(cur) || math.IsInf(cur, 0) {
+			return "", ErrHashNotFloat
+		}
+	}
+
+	cur += delta
+	if math.IsNaN(cur) || math.IsInf(cur, 0) {
+		if len(itm.HashValue) == 0 {
+			d.deleteItem(key)
+		}
+		return "", ErrNaN
+	}
+
+	val := strconv.FormatFloat(cur, 'f', -1, 64)
+	itm.hashSet(field, val)
+
+	d.modified(key)
+	d.notify(notifyHash, "hincrbyfloat", key)
+	return val, nil
+}
+
+// HRandField returns random fields together with their values. Like
+// SRandMember, a negative count allows the same field to repeat.
+func (d *DB) HRandField(key string, count int) (fields, vals []string, err error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	itm, err := d.hashAt(key)
+	if err != nil || itm == nil {
+		return nil, nil, err
+	}
+
+	hash := itm.liveHash(time.Now())
+	all := make([]string, 0, len(hash))
+	for f := range hash {
+		all = append(all, f)
+	}
+
+	if len(all) == 0 {
+		return nil, nil, nil
+	}
+	if count < 0 {
+		fields = make([]string, -count)
+		for i := range fields {
+			fields[i] = all[rand.Intn(len(all))]
+		}
+	} else {
+		rand.Shuffle(len(all), func(i, j int) {
+			all[i], all[j] = all[j], all[i]
+		})
+		fields = all[:min(count, len(all))]
+	}
+
+	vals = make([]string, len(fields))
+	for i, f := range fields {
+		vals[i] = hash[f]
+	}
+	return fields, vals, nil
+}
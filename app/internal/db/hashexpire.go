@@ -0,0 +1,346 @@
+package db
+
+import (
+	"time"
+)
+
+// Hash field expiration
+
+// FieldTTL describes how HGETEX and HSETEX update field expirations. The
+// zero value clears any existing TTL.
+type FieldTTL struct {
+	At   time.Time // new expiry, used when Set is true
+	Set  bool
+	Keep bool // leave existing TTLs untouched
+}
+
+func (i *item) fieldExpired(field string, now time.Time) bool {
+	t, ok := i.FieldExpires[field]
+	return ok && !t.After(now)
+}
+
+func (i *item) hashGet(field string, now time.Time) (string, bool) {
+	if i.fieldExpired(field, now) {
+		return "", false
+	}
+	val, ok := i.HashValue[field]
+	return val, ok
+}
+
+// liveHash returns the fields that have not expired. The underlying map is
+// returned as is when no field carries a TTL.
+func (i *item) liveHash(now time.Time) map[string]string {
+	if len(i.FieldExpires) == 0 {
+		return i.HashValue
+	}
+
+	live := make(map[string]string, len(i.HashValue))
+	for f, v := range i.HashValue {
+		if !i.fieldExpired(f, now) {
+			live[f] = v
+		}
+	}
+	return live
+}
+
+func (i *item) hasLiveFields(now time.Time) bool {
+	if len(i.FieldExpires) < len(i.HashValue) {
+		return true
+	}
+	for _, t := range i.FieldExpires {
+		if t.After(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// setFieldTTL applies ttl to field. Callers must hold d.mu for writing.
+func (i *item) setFieldTTL(field string, ttl FieldTTL) {
+	switch {
+	case ttl.Keep:
+	case ttl.Set:
+		if i.FieldExpires == nil {
+			i.FieldExpires = make(map[string]time.Time)
+		}
+		i.FieldExpires[field] = ttl.At
+	default:
+		delete(i.FieldExpires, field)
+	}
+}
+
+// purgeFields drops expired fields from the hash at key and deletes the key
+// once no field is left. Callers must hold d.mu for writing.
+func (d *DB) purgeFields(key string, itm *item, now time.Time) int {
+	purged := 0
+	for f, t := range itm.FieldExpires {
+		if !t.After(now) {
+			itm.hashDelete(f)
+			delete(itm.FieldExpires, f)
+			purged++
+		}
+	}
+
+	if purged > 0 {
+		d.modified(key)
+		d.notify(notifyHash, "hexpired", key)
+		if len(itm.HashValue) == 0 {
+			d.deleteItem(key)
+			d.notify(notifyGeneric, "del", key)
+		}
+	}
+	return purged
+}
+
+// HExpire sets the expiry of fields to at, subject to cond (NX, XX, GT, LT or
+// empty). It returns one status per field: -2 if the field does not exist, 0
+// if cond was not met, 1 if the expiry was set and 2 if the field was deleted
+// because at is not in the future.
+func (d *DB) HExpire(key string, at time.Time, cond string, fields ...string) ([]int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	itm, err := d.hashForUpdate(key)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]int, len(fields))
+	if itm == nil {
+		for i := range result {
+			result[i] = -2
+		}
+		return result, nil
+	}
+
+	now := time.Now()
+	set, deleted := false, false
+	for i, f := range fields {
+		if _, ok := itm.HashValue[f]; !ok {
+			result[i] = -2
+			continue
+		}
+
+		// a field without a TTL counts as expiring never
+		cur, has := itm.FieldExpires[f]
+		switch cond {
+		case "NX":
+			if has {
+				continue
+			}
+		case "XX":
+			if !has {
+				continue
+			}
+		case "GT":
+			if !has || !at.After(cur) {
+				continue
+			}
+		case "LT":
+			if has && !at.Before(cur) {
+				continue
+			}
+		}
+
+		if !at.After(now) {
+			itm.hashDelete(f)
+			delete(itm.FieldExpires, f)
+			result[i] = 2
+			deleted = true
+			continue
+		}
+
+		itm.setFieldTTL(f, FieldTTL{At: at, Set: true})
+		result[i] = 1
+		set = true
+	}
+
+	if set || deleted {
+		d.modified(key)
+	}
+	if set {
+		d.notify(notifyHash, "hexpire", key)
+	}
+	if deleted {
+		d.notify(notifyHash, "hdel", key)
+	}
+	if len(itm.HashValue) == 0 {
+		d.deleteItem(key)
+		d.notify(notifyGeneric, "del", key)
+	}
+	return result, nil
+}
+
+// HPersist removes the TTL of fields. It returns one status per field: -2 if
+// the field does not exist, -1 if it has no TTL and 1 if the TTL was removed.
+func (d *DB) HPersist(key string, fields ...string) ([]int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	itm, err := d.hashForUpdate(key)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]int, len(fields))
+	changed := false
+	for i, f := range fields {
+		switch {
+		case itm == nil || !hasField(itm, f):
+			result[i] = -2
+		case itm.FieldExpires[f].IsZero():
+			result[i] = -1
+		default:
+			delete(itm.FieldExpires, f)
+			result[i] = 1
+			changed = true
+		}
+	}
+	if changed {
+		d.modified(key)
+		d.notify(notifyHash, "hpersist", key)
+	}
+	return result, nil
+}
+
+// HExpiresAt returns the expiry of each field. The zero Time means the field
+// has no TTL; exists[i] is false for fields that do not exist.
+func (d *DB) HExpiresAt(key string, fields ...string) (expires []time.Time, exists []bool, err error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	itm, err := d.hashAt(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	expires = make([]time.Time, len(fields))
+	exists = make([]bool, len(fields))
+	if itm == nil {
+		return expires, exists, nil
+	}
+
+	now := time.Now()
+	for i, f := range fields {
+		_, exists[i] = itm.hashGet(f, now)
+		expires[i] = itm.FieldExpires[f]
+	}
+	return expires, exists, nil
+}
+
+// HGetEx returns the values of fields and updates the TTL of those that
+// exist. Fields whose new expiry is not in the future are deleted after being
+// read.
+func (d *DB) HGetEx(key string, ttl FieldTTL, fields ...string) (vals []string, ok []bool, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	itm, err := d.hashForUpdate(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	vals = make([]string, len(fields))
+	ok = make([]bool, len(fields))
+	if itm == nil {
+		return vals, ok, nil
+	}
+
+	now := time.Now()
+	changed, deleted := false, false
+	for i, f := range fields {
+		vals[i], ok[i] = itm.HashValue[f]
+		if !ok[i] || ttl.Keep {
+			continue
+		}
+
+		if ttl.Set && !ttl.At.After(now) {
+			itm.hashDelete(f)
+			delete(itm.FieldExpires, f)
+			deleted = true
+		} else {
+			itm.setFieldTTL(f, ttl)
+			changed = true
+		}
+	}
+	if changed || deleted {
+		d.modified(key)
+	}
+	switch {
+	case deleted:
+		d.notify(notifyHash, "hdel", key)
+	case changed && ttl.Set:
+		d.notify(notifyHash, "hexpire", key)
+	case changed:
+		d.notify(notifyHash, "hpersist", key)
+	}
+
+	if len(itm.HashValue) == 0 {
+		d.deleteItem(key)
+		d.notify(notifyGeneric, "del", key)
+	}
+	return vals, ok, nil
+}
+
+// HSetEx sets field/value pairs and applies ttl to them. With cond "FNX" the
+// write only happens if none of the fields exist, with "FXX" only if all of
+// them do. It reports whether the fields were set.
+func (d *DB) HSetEx(key, cond string, ttl FieldTTL, pairs ...string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	itm, err := d.hashForUpdate(key)
+	if err != nil {
+		return false, err
+	}
+
+	for i := 0; i+1 < len(pairs); i += 2 {
+		exists := itm != nil && hasField(itm, pairs[i])
+		if (cond == "FNX" && exists) || (cond == "FXX" && !exists) {
+			return false, nil
+		}
+	}
+
+	created := itm == nil
+	if created {
+		itm = &item{Type: HashType, HashValue: make(map[string]string)}
+		d.setItem(key, itm)
+	}
+
+	expired := ttl.Set && !ttl.At.After(time.Now())
+	for i := 0; i+1 < len(pairs); i += 2 {
+		f := pairs[i]
+		if expired {
+			itm.hashDelete(f)
+			delete(itm.FieldExpires, f)
+			continue
+		}
+		itm.hashSet(f, pairs[i+1])
+		itm.setFieldTTL(f, ttl)
+	}
+
+	d.modified(key)
+	if expired {
+		d.notify(notifyHash, "hdel", key)
+	} else {
+		if created {
+			d.notify(notifyNew, "new", key)
+		}
+		d.notify(notifyHash, "hset", key)
+		if ttl.Set {
+			d.notify(notifyHash, "hexpire", key)
+		}
+	}
+	if len(itm.HashValue) == 0 {
+		d.deleteItem(key)
+		if !created {
+			d.notify(notifyGeneric, "del", key)
+		}
+	}
+	return true, nil
+}
+
+func hasField(itm *item, field string) bool {
+	_, ok := itm.HashValue[field]
+	return ok
+}
@@ -0,0 +1,130 @@
+package db
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestHExpireConditions(t *testing.T) {
+	d := New()
+	d.HSet("h", "a", "1", "b", "2")
+	now := time.Now()
+	hour, twoHours := now.Add(time.Hour), now.Add(2*time.Hour)
+
+	tests := []struct {
+		cond   string
+		at     time.Time
+		fields []string
+		want   []int
+	}{
+		{"XX", hour, []string{"a"}, []int{0}}, // no TTL yet
+		{"NX", hour, []string{"a", "missing"}, []int{1, -2}},
+		{"NX", twoHours, []string{"a"}, []int{0}},
+		{"GT", now.Add(30 * time.Minute), []string{"a"}, []int{0}},
+		{"GT", twoHours, []string{"a"}, []int{1}},
+		{"GT", twoHours, []string{"b"}, []int{0}}, // no TTL counts as never expiring
+		{"LT", twoHours, []string{"a"}, []int{0}},
+		{"LT", hour, []string{"a", "b"}, []int{1, 1}},
+		{"XX", twoHours, []string{"b"}, []int{1}},
+		{"", now.Add(-time.Second), []string{"a"}, []int{2}},
+	}
+	for _, tt := range tests {
+		got, err := d.HExpire("h", tt.at, tt.cond, tt.fields...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("HEXPIRE %s %q = %v, want %v", tt.cond, tt.fields, got, tt.want)
+		}
+	}
+
+	if _, ok := d.HGet("h", "a"); ok {
+		t.Error("field expired by a past HEXPIRE is still there")
+	}
+	if got, _ := d.HExpire("missing", hour, "", "a"); !slices.Equal(got, []int{-2}) {
+		t.Errorf("HEXPIRE on a missing key = %v", got)
+	}
+	d.Set("s", "v", 0)
+	if _, err := d.HExpire("s", hour, "", "a"); err != ErrWrongType {
+		t.Errorf("HEXPIRE on a string: err = %v", err)
+	}
+}
+
+func TestHPersist(t *testing.T) {
+	d := New()
+	d.HSet("h", "a", "1", "b", "2")
+	d.HExpire("h", time.Now().Add(time.Hour), "", "a")
+
+	got, err := d.HPersist("h", "a", "b", "missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{1, -1, -2}; !slices.Equal(got, want) {
+		t.Errorf("HPERSIST = %v, want %v", got, want)
+	}
+	if expires, _, _ := d.HExpiresAt("h", "a"); !expires[0].IsZero() {
+		t.Errorf("field still expires at %v", expires[0])
+	}
+	if got, _ := d.HPersist("missing", "a"); !slices.Equal(got, []int{-2}) {
+		t.Errorf("HPERSIST on a missing key = %v", got)
+	}
+}
+
+func TestHashFieldActiveExpiry(t *testing.T) {
+	d := New()
+	d.HSet("h", "a", "1", "b", "2")
+	d.HSet("gone", "a", "1")
+	d.HExpire("h", time.Now().Add(20*time.Millisecond), "", "a")
+	d.HExpire("gone", time.Now().Add(20*time.Millisecond), "", "a")
+	time.Sleep(30 * time.Millisecond)
+
+	d.cleanup()
+	if _, ok := d.store["h"].HashValue["a"]; ok {
+		t.Error("cleanup kept an expired field")
+	}
+	if _, ok := d.store["h"].FieldExpires["a"]; ok {
+		t.Error("cleanup kept the TTL of an expired field")
+	}
+	if v, _ := d.HGet("h", "b"); v != "2" {
+		t.Errorf("field without a TTL = %q, want 2", v)
+	}
+	if d.Exists("gone") != 0 {
+		t.Error("cleanup kept a hash whose fields all expired")
+	}
+}
+
+func TestHashFieldTTLPersistence(t *testing.T) {
+	d := New()
+	at := time.Now().Add(time.Hour).Truncate(time.Millisecond)
+	d.HSet("h", "a", "1", "b", "2", "c", "3")
+	d.HExpire("h", at, "", "a")
+	d.HExpire("h", time.Now().Add(20*time.Millisecond), "", "b")
+	d.HSet("gone", "a", "1")
+	d.HExpire("gone", time.Now().Add(20*time.Millisecond), "", "a")
+
+	file := filepath.Join(t.TempDir(), "dump.json")
+	if err := d.Save(file); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(30 * time.Millisecond)
+
+	loaded := New()
+	if err := loaded.Load(file); err != nil {
+		t.Fatal(err)
+	}
+	expires, exists, _ := loaded.HExpiresAt("h", "a", "b", "c")
+	if !expires[0].Equal(at) {
+		t.Errorf("field a expires at %v after loading, want %v", expires[0], at)
+	}
+	if exists[1] {
+		t.Error("field b expired while saved but was loaded")
+	}
+	if !exists[2] || !expires[2].IsZero() {
+		t.Errorf("field c loaded as exists=%v expires=%v", exists[2], expires[2])
+	}
+	if loaded.Exists("gone") != 0 {
+		t.Error("hash whose fields all expired while saved was loaded")
+	}
+}
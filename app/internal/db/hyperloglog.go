@@ -0,0 +1,472 @@
+package db
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// HyperLogLog values are stored as strings using the same layout as Redis,
+// so a PFADD'ed key can be copied between the two byte for byte:
+//
+//	"HYLL" | encoding (1 byte) | 3 unused bytes | cached cardinality (8 bytes, LE) | registers
+//
+// Registers use either the dense encoding (16384 packed 6-bit counters) or
+// the sparse run-length encoding with ZERO, XZERO and VAL opcodes.
+
+const (
+	hllP            = 14
+	hllQ            = 64 - hllP
+	hllRegisters    = 1 << hllP
+	hllPMask        = hllRegisters - 1
+	hllBits         = 6
+	hllRegisterMax  = 1<<hllBits - 1
+	hllHeaderSize   = 16
+	hllDenseSize    = hllHeaderSize + (hllRegisters*hllBits+7)/8
+	hllDense        = 0
+	hllSparse       = 1
+	hllSparseValMax = 32
+	hllAlphaInf     = 0.721347520444481703680 // 1 / (2 ln 2)
+
+	hllSparseZeroMaxLen  = 64
+	hllSparseXZeroMaxLen = 16384
+	hllSparseValMaxLen   = 4
+)
+
+var (
+	ErrInvalidHLL = errors.New("WRONGTYPE Key is not a valid HyperLogLog string value.")
+	ErrCorruptHLL = errors.New("INVALIDOBJ Corrupted HLL object detected")
+)
+
+// newHLL returns an empty sparse HyperLogLog with a valid cached cardinality
+// of zero.
+func newHLL() []byte {
+	b := make([]byte, hllHeaderSize, hllHeaderSize+2)
+	copy(b, "HYLL")
+	b[4] = hllSparse
+	n := hllRegisters - 1
+	return append(b, 0x40|byte(n>>8), byte(n))
+}
+
+func hllValid(b []byte) bool {
+	if len(b) < hllHeaderSize || string(b[:4]) != "HYLL" {
+		return false
+	}
+	switch b[4] {
+	case hllDense:
+		return len(b) == hllDenseSize
+	case hllSparse:
+		return true
+	}
+	return false
+}
+
+func hllCachedCard(b []byte) (int64, bool) {
+	if b[15]&0x80 != 0 {
+		return 0, false
+	}
+	return int64(binary.LittleEndian.Uint64(b[8:16])), true
+}
+
+func hllSetCachedCard(b []byte, card int64) {
+	binary.LittleEndian.PutUint64(b[8:16], uint64(card))
+}
+
+func hllInvalidateCache(b []byte) {
+	b[15] |= 0x80
+}
+
+// murmurHash64A is the MurmurHash2 64-bit variant used by Redis to hash
+// HyperLogLog elements.
+func murmurHash64A(data []byte, seed uint64) uint64 {
+	const m = 0xc6a4a7935bd1e995
+	const r = 47
+
+	h := seed ^ (uint64(len(data)) * m)
+
+	for len(data) >= 8 {
+		k := binary.LittleEndian.Uint64(data)
+		k *= m
+		k ^= k >> r
+		k *= m
+
+		h ^= k
+		h *= m
+		data = data[8:]
+	}
+
+	if len(data) > 0 {
+		for i := len(data) - 1; i >= 0; i-- {
+			h ^= uint64(data[i]) << (8 * i)
+		}
+		h *= m
+	}
+
+	h ^= h >> r
+	h *= m
+	h ^= h >> r
+	return h
+}
+
+// hllPatLen returns the register an element maps to and the length of the
+// 000..1 pattern used to update it.
+func hllPatLen(elem string) (int, uint8) {
+	hash := murmurHash64A([]byte(elem), 0xadc83b19)
+	index := int(hash & hllPMask)
+	hash >>= hllP
+	hash |= 1 << hllQ
+
+	count := uint8(1)
+	for bit := uint64(1); hash&bit == 0; bit <<= 1 {
+		count++
+	}
+	return index, count
+}
+
+func denseGet(regs []byte, i int) uint8 {
+	pos := i * hllBits / 8
+	fb := uint(i * hllBits & 7)
+	b0 := uint(regs[pos])
+	var b1 uint
+	if pos+1 < len(regs) {
+		b1 = uint(regs[pos+1])
+	}
+	return uint8((b0>>fb | b1<<(8-fb)) & hllRegisterMax)
+}
+
+func denseSet(regs []byte, i int, v uint8) {
+	pos := i * hllBits / 8
+	fb := uint(i * hllBits & 7)
+	regs[pos] &^= byte(hllRegisterMax << fb)
+	regs[pos] |= byte(uint(v) << fb)
+	if pos+1 < len(regs) {
+		regs[pos+1] &^= byte(hllRegisterMax >> (8 - fb))
+		regs[pos+1] |= byte(uint(v) >> (8 - fb))
+	}
+}
+
+// hllDecode expands any HyperLogLog encoding into one byte per register.
+func hllDecode(b []byte) ([]uint8, error) {
+	regs := make([]uint8, hllRegisters)
+	data := b[hllHeaderSize:]
+
+	if b[4] == hllDense {
+		for i := range regs {
+			regs[i] = denseGet(data, i)
+		}
+		return regs, nil
+	}
+
+	idx := 0
+	for p := 0; p < len(data); {
+		op := data[p]
+		switch {
+		case op&0xc0 == 0x00: // ZERO: 00xxxxxx
+			idx += int(op&0x3f) + 1
+			p++
+		case op&0xc0 == 0x40: // XZERO: 01xxxxxx yyyyyyyy
+			if p+1 >= len(data) {
+				return nil, ErrCorruptHLL
+			}
+			idx += (int(op&0x3f)<<8 | int(data[p+1])) + 1
+			p += 2
+		default: // VAL: 1vvvvvxx
+			val := (op>>2)&0x1f + 1
+			run := int(op&0x03) + 1
+			if idx+run > hllRegisters {
+				return nil, ErrCorruptHLL
+			}
+			for j := 0; j < run; j++ {
+				regs[idx+j] = val
+			}
+			idx += run
+			p++
+		}
+		if idx > hllRegisters {
+			return nil, ErrCorruptHLL
+		}
+	}
+
+	if idx != hllRegisters {
+		return nil, ErrCorruptHLL
+	}
+	return regs, nil
+}
+
+// hllEncode packs regs, preferring the sparse encoding when allowed and the
+// result stays within sparseMax bytes.
+func hllEncode(regs []uint8, sparse bool, sparseMax int) []byte {
+	if sparse {
+		if data, ok := sparseEncode(regs); ok && len(data) <= sparseMax {
+			b := make([]byte, hllHeaderSize, hllHeaderSize+len(data))
+			copy(b, "HYLL")
+			b[4] = hllSparse
+			return append(b, data...)
+		}
+	}
+
+	b := make([]byte, hllDenseSize)
+	copy(b, "HYLL")
+	b[4] = hllDense
+	for i, v := range regs {
+		denseSet(b[hllHeaderSize:], i, v)
+	}
+	return b
+}
+
+func sparseEncode(regs []uint8) ([]byte, bool) {
+	var out []byte
+	for i := 0; i < hllRegisters; {
+		v := regs[i]
+		run := 1
+		for i+run < hllRegisters && regs[i+run] == v {
+			run++
+		}
+		i += run
+
+		if v == 0 {
+			for run > 0 {
+				if run > hllSparseZeroMaxLen {
+					n := min(run, hllSparseXZeroMaxLen)
+					out = append(out, 0x40|byte((n-1)>>8), byte(n-1))
+					run -= n
+				} else {
+					out = append(out, byte(run-1))
+					run = 0
+				}
+			}
+			continue
+		}
+
+		if v > hllSparseValMax {
+			return nil, false
+		}
+		for run > 0 {
+			n := min(run, hllSparseValMaxLen)
+			out = append(out, 0x80|(v-1)<<2|byte(n-1))
+			run -= n
+		}
+	}
+	return out, true
+}
+
+// hllCount estimates the cardinality of regs with the estimator from Otmar
+// Ertl's "New cardinality estimation algorithms for HyperLogLog sketches",
+// as Redis does.
+func hllCount(regs []uint8) int64 {
+	var histo [64]int
+	for _, v := range regs {
+		histo[v]++
+	}
+
+	m := float64(hllRegisters)
+	z := m * hllTau((m-float64(histo[hllQ+1]))/m)
+	for j := hllQ; j >= 1; j-- {
+		z += float64(histo[j])
+		z *= 0.5
+	}
+	z += m * hllSigma(float64(histo[0])/m)
+	return int64(math.Round(hllAlphaInf * m * m / z))
+}
+
+func hllSigma(x float64) float64 {
+	if x == 1 {
+		return math.Inf(1)
+	}
+	y, z := 1.0, x
+	for {
+		x *= x
+		zPrime := z
+		z += x * y
+		y += y
+		if zPrime == z {
+			return z
+		}
+	}
+}
+
+func hllTau(x float64) float64 {
+	if x == 0 || x == 1 {
+		return 0
+	}
+	y, z := 1.0, 1-x
+	for {
+		x = math.Sqrt(x)
+		zPrime := z
+		y *= 0.5
+		z -= math.Pow(1-x, 2) * y
+		if zPrime == z {
+			return z / 3
+		}
+	}
+}
+
+// hllAt returns the HyperLogLog stored at key, or nil if the key is missing.
+// Callers must hold d.mu.
+func (d *DB) hllAt(key string) (*item, error) {
+	itm, err := d.stringAt(key)
+	if err != nil {
+		return nil, ErrInvalidHLL
+	}
+	if itm != nil && !hllValid([]byte(itm.str())) {
+		return nil, ErrInvalidHLL
+	}
+	return itm, nil
+}
+
+// MaxHLLSparseBytes is the largest sparse HyperLogLog before it is converted
+// to the dense encoding.
+func (d *DB) MaxHLLSparseBytes() int {
+	return int(d.hllSparseMaxBytes.Load())
+}
+
+func (d *DB) SetMaxHLLSparseBytes(n int) {
+	d.hllSparseMaxBytes.Store(int64(n))
+}
+
+// PFAdd adds elems to the HyperLogLog at key. It reports whether the
+// estimate may have changed, which includes creating the key.
+func (d *DB) PFAdd(key string, elems ...string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	itm, err := d.hllAt(key)
+	if err != nil {
+		return false, err
+	}
+
+	created := false
+	if itm == nil {
+		itm = &item{Type: StringType, StringValue: string(newHLL())}
+		d.setItem(key, itm)
+		created = true
+		d.notify(notifyNew, "new", key)
+	}
+
+	b := []byte(itm.str())
+	changed := false
+
+	if b[4] == hllDense {
+		regs := b[hllHeaderSize:]
+		for _, e := range elems {
+			idx, count := hllPatLen(e)
+			if denseGet(regs, idx) < count {
+				denseSet(regs, idx, count)
+				changed = true
+			}
+		}
+	} else {
+		regs, err := hllDecode(b)
+		if err != nil {
+			return false, err
+		}
+		for _, e := range elems {
+			idx, count := hllPatLen(e)
+			if regs[idx] < count {
+				regs[idx] = count
+				changed = true
+			}
+		}
+		if changed {
+			b = hllEncode(regs, true, d.MaxHLLSparseBytes())
+		}
+	}
+
+	if changed {
+		hllInvalidateCache(b)
+		itm.setStr(string(b))
+	}
+	if changed || created {
+		d.modified(key)
+		d.notify(notifyString, "pfadd", key)
+	}
+	return changed || created, nil
+}
+
+// PFCount estimates the cardinality of the union of the HyperLogLogs at
+// keys. With a single key the estimate is cached inside the value.
+func (d *DB) PFCount(keys ...string) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(keys) == 1 {
+		itm, err := d.hllAt(keys[0])
+		if err != nil || itm == nil {
+			return 0, err
+		}
+
+		b := []byte(itm.str())
+		if card, ok := hllCachedCard(b); ok {
+			return card, nil
+		}
+
+		regs, err := hllDecode(b)
+		if err != nil {
+			return 0, err
+		}
+		card := hllCount(regs)
+		hllSetCachedCard(b, card)
+		itm.setStr(string(b))
+		return card, nil
+	}
+
+	merged, _, err := d.hllMerge(keys)
+	if err != nil {
+		return 0, err
+	}
+	return hllCount(merged), nil
+}
+
+// PFMerge stores the union of srcs, and dst itself if it exists, at dst.
+func (d *DB) PFMerge(dst string, srcs ...string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	merged, allSparse, err := d.hllMerge(append([]string{dst}, srcs...))
+	if err != nil {
+		return err
+	}
+
+	b := hllEncode(merged, allSparse, d.MaxHLLSparseBytes())
+	hllInvalidateCache(b)
+
+	if itm := d.lookup(dst); itm != nil {
+		itm.setStr(string(b))
+	} else {
+		d.setItem(dst, &item{Type: StringType, StringValue: string(b)})
+		d.notify(notifyNew, "new", dst)
+	}
+	d.modified(dst)
+	d.notify(notifyString, "pfadd", dst)
+	return nil
+}
+
+// hllMerge returns the register-wise maximum of the HyperLogLogs at keys,
+// skipping missing keys, and whether all of them were sparse.
+func (d *DB) hllMerge(keys []string) ([]uint8, bool, error) {
+	merged := make([]uint8, hllRegisters)
+	allSparse := true
+
+	for _, k := range keys {
+		itm, err := d.hllAt(k)
+		if err != nil {
+			return nil, false, err
+		}
+		if itm == nil {
+			continue
+		}
+
+		b := []byte(itm.str())
+		if b[4] == hllDense {
+			allSparse = false
+		}
+		regs, err := hllDecode(b)
+		if err != nil {
+			return nil, false, err
+		}
+		for i, v := range regs {
+			merged[i] = max(merged[i], v)
+		}
+	}
+	return merged, allSparse, nil
+}
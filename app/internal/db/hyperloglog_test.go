@@ -0,0 +1,121 @@
+package db
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func hllEncoding(d *DB, key string) byte {
+	return d.store[key].str()[4]
+}
+
+func pfaddRange(t *testing.T, d *DB, key string, from, to int) {
+	t.Helper()
+	elems := make([]string, 0, to-from)
+	for i := from; i < to; i++ {
+		elems = append(elems, fmt.Sprint("elem:", i))
+	}
+	if _, err := d.PFAdd(key, elems...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// withinError reports whether got is within rel of want.
+func withinError(got int64, want int, rel float64) bool {
+	return math.Abs(float64(got)-float64(want)) <= rel*float64(want)
+}
+
+func TestHLLSparseToDense(t *testing.T) {
+	d := New()
+	pfaddRange(t, d, "hll", 0, 100)
+	if enc := hllEncoding(d, "hll"); enc != hllSparse {
+		t.Fatalf("100 elements use encoding %d, want sparse", enc)
+	}
+	sparse, _ := d.PFCount("hll")
+
+	pfaddRange(t, d, "hll", 100, 5000)
+	if enc := hllEncoding(d, "hll"); enc != hllDense {
+		t.Fatalf("5000 elements use encoding %d, want dense", enc)
+	}
+	if n := len(d.store["hll"].str()); n != hllDenseSize {
+		t.Errorf("dense value is %d bytes, want %d", n, hllDenseSize)
+	}
+
+	dense, _ := d.PFCount("hll")
+	if !withinError(sparse, 100, 0.02) || !withinError(dense, 5000, 0.02) {
+		t.Errorf("counts %d and %d, want about 100 and 5000", sparse, dense)
+	}
+}
+
+func TestHLLErrorBound(t *testing.T) {
+	d := New()
+	for i := 0; i < 100000; i += 1000 {
+		pfaddRange(t, d, "hll", i, i+1000)
+	}
+	if n, _ := d.PFCount("hll"); !withinError(n, 100000, 0.02) {
+		t.Errorf("PFCOUNT = %d, want 100000 within 2%%", n)
+	}
+}
+
+func TestHLLMergeSparseAndDense(t *testing.T) {
+	d := New()
+	pfaddRange(t, d, "dense", 0, 10000)
+	pfaddRange(t, d, "sparse", 9950, 10100) // 50 shared elements
+	if hllEncoding(d, "dense") != hllDense || hllEncoding(d, "sparse") != hllSparse {
+		t.Fatal("sources do not have the expected encodings")
+	}
+
+	union, _ := d.PFCount("dense", "sparse")
+	if err := d.PFMerge("merged", "dense", "sparse"); err != nil {
+		t.Fatal(err)
+	}
+	merged, _ := d.PFCount("merged")
+	if merged != union {
+		t.Errorf("PFCOUNT of the merge = %d, PFCOUNT of both keys = %d", merged, union)
+	}
+	if !withinError(merged, 10100, 0.02) {
+		t.Errorf("PFCOUNT of the merge = %d, want about 10100", merged)
+	}
+	if hllEncoding(d, "merged") != hllDense {
+		t.Error("merging a dense HLL gave a sparse one")
+	}
+}
+
+func TestHLLDecodeRedisPayload(t *testing.T) {
+	// The sparse example from the Redis HyperLogLog format description:
+	// register 1000 set to 2 and register 1020 set to 3, encoded as
+	// XZERO:1000 VAL:2,1 ZERO:19 VAL:3,1 XZERO:15363, with the cached
+	// cardinality marked stale.
+	payload := "HYLL\x01\x00\x00\x00" +
+		"\x00\x00\x00\x00\x00\x00\x00\x80" +
+		"\x43\xe7\x84\x12\x88\x7c\x02"
+
+	d := New()
+	d.Set("hll", payload, 0)
+	if n, err := d.PFCount("hll"); err != nil || n != 2 {
+		t.Fatalf("PFCOUNT = %d, %v; want 2", n, err)
+	}
+
+	regs, err := hllDecode([]byte(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, r := range regs {
+		want := uint8(0)
+		switch i {
+		case 1000:
+			want = 2
+		case 1020:
+			want = 3
+		}
+		if r != want {
+			t.Errorf("register %d = %d, want %d", i, r, want)
+		}
+	}
+
+	// re-encoding gives back the same registers
+	if got := hllEncode(regs, true, d.MaxHLLSparseBytes()); string(got[hllHeaderSize:]) != payload[hllHeaderSize:] {
+		t.Errorf("re-encoded registers = %q, want %q", got[hllHeaderSize:], payload[hllHeaderSize:])
+	}
+}
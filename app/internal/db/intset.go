@@ -0,0 +1,224 @@
+package db
+
+import (
+	"encoding/json"
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+)
+
+const (
+	encodingIntset    = "intset"
+	encodingHashtable = "hashtable"
+)
+
+// setValue stores set members using one of two encodings: a sorted integer
+// array while every member is an integer and the set is small, and a hash
+// table otherwise. The upgrade to a hash table is one-way.
+type setValue struct {
+	ints []int64
+	hash map[string]int // member to its position in list
+	list []string       // the members of hash, so one can be picked at random
+
+	index *scanIndex // the members of hash in SCAN order, once scanned
+}
+
+func newSetValue() *setValue {
+	return &setValue{}
+}
+
+// intsetMember reports whether m can live in an intset, i.e. it is the
+// canonical decimal form of a 64-bit integer.
+func intsetMember(m string) (int64, bool) {
+	v, err := strconv.ParseInt(m, 10, 64)
+	if err != nil || strconv.FormatInt(v, 10) != m {
+		return 0, false
+	}
+	return v, true
+}
+
+func (s *setValue) encoding() string {
+	if s.hash != nil {
+		return encodingHashtable
+	}
+	return encodingIntset
+}
+
+func (s *setValue) len() int {
+	if s == nil {
+		return 0
+	}
+	if s.hash != nil {
+		return len(s.hash)
+	}
+	return len(s.ints)
+}
+
+func (s *setValue) has(m string) bool {
+	if s == nil {
+		return false
+	}
+	if s.hash != nil {
+		_, ok := s.hash[m]
+		return ok
+	}
+	v, ok := intsetMember(m)
+	if !ok {
+		return false
+	}
+	_, found := slices.BinarySearch(s.ints, v)
+	return found
+}
+
+// add inserts m, upgrading to a hash table when m is not an integer or the
+// intset would grow past maxIntset entries. It reports whether m was new.
+func (s *setValue) add(m string, maxIntset int) bool {
+	if s.hash == nil {
+		if v, ok := intsetMember(m); ok {
+			i, found := slices.BinarySearch(s.ints, v)
+			if found {
+				return false
+			}
+			if len(s.ints) < maxIntset {
+				s.ints = slices.Insert(s.ints, i, v)
+				return true
+			}
+		}
+		s.upgrade()
+	}
+
+	if _, ok := s.hash[m]; ok {
+		return false
+	}
+	s.hash[m] = len(s.list)
+	s.list = append(s.list, m)
+	s.index.add(m)
+	return true
+}
+
+func (s *setValue) remove(m string) bool {
+	if s.hash != nil {
+		i, ok := s.hash[m]
+		if !ok {
+			return false
+		}
+		// move the last member into the hole
+		last := s.list[len(s.list)-1]
+		s.list[i], s.hash[last] = last, i
+		s.list = s.list[:len(s.list)-1]
+		delete(s.hash, m)
+		s.index.remove(m)
+		return true
+	}
+
+	v, ok := intsetMember(m)
+	if !ok {
+		return false
+	}
+	i, found := slices.BinarySearch(s.ints, v)
+	if !found {
+		return false
+	}
+	s.ints = slices.Delete(s.ints, i, i+1)
+	return true
+}
+
+func (s *setValue) upgrade() {
+	s.hash = make(map[string]int, len(s.ints))
+	s.list = make([]string, 0, len(s.ints))
+	for _, v := range s.ints {
+		m := strconv.FormatInt(v, 10)
+		s.hash[m] = len(s.list)
+		s.list = append(s.list, m)
+	}
+	s.ints = nil
+}
+
+// random returns a member picked uniformly at random. s must not be empty.
+func (s *setValue) random() string {
+	if s.hash != nil {
+		return s.list[rand.IntN(len(s.list))]
+	}
+	return strconv.FormatInt(s.ints[rand.IntN(len(s.ints))], 10)
+}
+
+// each calls fn for every member until fn returns false.
+func (s *setValue) each(fn func(m string) bool) {
+	if s == nil {
+		return
+	}
+	if s.hash != nil {
+		for m := range s.hash {
+			if !fn(m) {
+				return
+			}
+		}
+		return
+	}
+	for _, v := range s.ints {
+		if !fn(strconv.FormatInt(v, 10)) {
+			return
+		}
+	}
+}
+
+func (s *setValue) members() []string {
+	members := make([]string, 0, s.len())
+	s.each(func(m string) bool {
+		members = append(members, m)
+		return true
+	})
+	return members
+}
+
+// clone returns a deep copy of s in the same encoding.
+func (s *setValue) clone() *setValue {
+	if s == nil {
+		return nil
+	}
+	return &setValue{ints: slices.Clone(s.ints), hash: maps.Clone(s.hash), list: slices.Clone(s.list)}
+}
+
+// Snapshots keep the original map layout regardless of encoding, so older
+// snapshot files load unchanged.
+
+func (s *setValue) MarshalJSON() ([]byte, error) {
+	m := make(map[string]struct{}, s.len())
+	s.each(func(member string) bool {
+		m[member] = struct{}{}
+		return true
+	})
+	return json.Marshal(m)
+}
+
+func (s *setValue) UnmarshalJSON(data []byte) error {
+	var m map[string]struct{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	s.ints, s.hash, s.list = nil, make(map[string]int, len(m)), make([]string, 0, len(m))
+	for member := range m {
+		s.hash[member] = len(s.list)
+		s.list = append(s.list, member)
+	}
+	return nil
+}
+
+// compact converts a hash table back to an intset when every member fits.
+// It is used after loading a snapshot.
+func (s *setValue) compact(maxIntset int) {
+	if s.hash == nil || len(s.hash) > maxIntset {
+		return
+	}
+	ints := make([]int64, 0, len(s.hash))
+	for m := range s.hash {
+		v, ok := intsetMember(m)
+		if !ok {
+			return
+		}
+		ints = append(ints, v)
+	}
+	slices.Sort(ints)
+	s.ints, s.hash, s.list = ints, nil, nil
+}
@@ -0,0 +1,170 @@
+package db
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"time"
+)
+
+var (
+	ErrNoSuchKey  = errors.New("ERR no such key")
+	ErrSameObject = errors.New("ERR source and destination objects are the same")
+)
+
+// clone returns a deep copy of the item, including its TTLs.
+func (i *item) clone() *item {
+	c := *i
+	c.index, c.scores = nil, nil
+	c.bitmap = slices.Clone(i.bitmap)
+	c.ListValue = slices.Clone(i.ListValue)
+	c.SetValue = i.SetValue.clone()
+	c.HashValue = maps.Clone(i.HashValue)
+	c.ZSetValue = maps.Clone(i.ZSetValue)
+	c.FieldExpires = maps.Clone(i.FieldExpires)
+	return &c
+}
+
+// Exists returns how many of keys exist. A key given twice is counted twice.
+func (d *DB) Exists(keys ...string) int {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	n := 0
+	for _, key := range keys {
+		if d.lookup(key) != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// Touch is Exists for TOUCH. There is no access tracking, so touching a key
+// only reports whether it is alive.
+func (d *DB) Touch(keys ...string) int {
+	return d.Exists(keys...)
+}
+
+// Type returns the type of the value stored at key, or "none".
+func (d *DB) Type(key string) string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	itm := d.lookup(key)
+	if itm == nil {
+		return "none"
+	}
+	return string(itm.Type)
+}
+
+// Rename moves the value at src to dst, overwriting dst. The TTL moves with
+// the value.
+func (d *DB) Rename(src, dst string) error {
+	_, err := d.rename(src, dst, false)
+	return err
+}
+
+// RenameNX is Rename that does nothing when dst already exists. It reports
+// whether the key was renamed.
+func (d *DB) RenameNX(src, dst string) (bool, error) {
+	return d.rename(src, dst, true)
+}
+
+func (d *DB) rename(src, dst string, nx bool) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	itm := d.lookup(src)
+	if itm == nil {
+		return false, ErrNoSuchKey
+	}
+	if src == dst {
+		return !nx, nil
+	}
+	if nx && d.lookup(dst) != nil {
+		return false, nil
+	}
+
+	d.deleteItem(src)
+	d.setItem(dst, itm)
+	d.modified(src, dst)
+	d.notify(notifyGeneric, "rename_from", src)
+	d.notify(notifyGeneric, "rename_to", dst)
+	return true, nil
+}
+
+// Copy stores a copy of the value at src, including its TTL, at dst. Unless
+// replace is set an existing dst is left alone. It reports whether the value
+// was copied.
+func (d *DB) Copy(src, dst string, replace bool) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if src == dst {
+		return false, ErrSameObject
+	}
+	itm := d.lookup(src)
+	if itm == nil {
+		return false, nil
+	}
+	existed := d.lookup(dst) != nil
+	if !replace && existed {
+		return false, nil
+	}
+
+	d.setItem(dst, itm.clone())
+	d.modified(dst)
+	if !existed {
+		d.notify(notifyNew, "new", dst)
+	}
+	d.notify(notifyGeneric, "copy_to", dst)
+	return true, nil
+}
+
+// DBSize returns the number of keys that have not expired.
+func (d *DB) DBSize() int {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	n := 0
+	for key := range d.store {
+		if d.lookup(key) != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// RandomKey returns a random live key. ok is false when the database is
+// empty.
+func (d *DB) RandomKey() (key string, ok bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	// map iteration order is randomized
+	for key := range d.store {
+		if d.lookup(key) != nil {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// KeyspaceInfo returns the number of live keys and how many of them have a
+// TTL.
+func (d *DB) KeyspaceInfo() (keys, expires int) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	now := time.Now()
+	for _, itm := range d.store {
+		if itm.expired(now) {
+			continue
+		}
+		keys++
+		if !itm.ExpiresAt.IsZero() {
+			expires++
+		}
+	}
+	return keys, expires
+}
@@ -0,0 +1,88 @@
+package db
+
+// Keyspace notifications are published through the regular pub/sub as
+// __keyspace@0__:<key> with the event as payload and __keyevent@0__:<event>
+// with the key as payload. notify-keyspace-events selects the channel kinds
+// and the classes of events that are published.
+
+const (
+	notifyKeyspace = 1 << iota // K
+	notifyKeyevent             // E
+	notifyGeneric              // g: del, expire, rename, ...
+	notifyString               // $
+	notifyList                 // l
+	notifySet                  // s
+	notifyHash                 // h
+	notifyZSet                 // z
+	notifyExpired              // x
+	notifyEvicted              // e
+	notifyStream               // t
+	notifyKeyMiss              // m
+	notifyNew                  // n
+
+	// notifyAll is the A flag. Key misses and new keys are not included.
+	notifyAll = notifyGeneric | notifyString | notifyList | notifySet | notifyHash |
+		notifyZSet | notifyExpired | notifyEvicted | notifyStream
+)
+
+// keyspaceEventFlags parses a notify-keyspace-events value. Unknown
+// characters are ignored.
+func keyspaceEventFlags(s string) int {
+	flags := 0
+	for _, c := range s {
+		switch c {
+		case 'A':
+			flags |= notifyAll
+		case 'K':
+			flags |= notifyKeyspace
+		case 'E':
+			flags |= notifyKeyevent
+		case 'g':
+			flags |= notifyGeneric
+		case '$':
+			flags |= notifyString
+		case 'l':
+			flags |= notifyList
+		case 's':
+			flags |= notifySet
+		case 'h':
+			flags |= notifyHash
+		case 'z':
+			flags |= notifyZSet
+		case 'x':
+			flags |= notifyExpired
+		case 'e':
+			flags |= notifyEvicted
+		case 't':
+			flags |= notifyStream
+		case 'm':
+			flags |= notifyKeyMiss
+		case 'n':
+			flags |= notifyNew
+		}
+	}
+	return flags
+}
+
+// SetNotifyKeyspaceEvents sets the classes of events to publish from a
+// notify-keyspace-events value such as "KEA".
+func (d *DB) SetNotifyKeyspaceEvents(flags string) {
+	d.notifyFlags.Store(int64(keyspaceEventFlags(flags)))
+}
+
+// notify publishes event for key if its class is enabled. Events about the
+// whole keyspace, such as flushall, have an empty key and only go to the
+// keyevent channel. It only takes the pub/sub lock, so callers may hold d.mu.
+func (d *DB) notify(class int, event, key string) {
+	flags := int(d.notifyFlags.Load())
+	if flags&class == 0 {
+		return
+	}
+
+	if flags&notifyKeyspace != 0 && key != "" {
+		d.Publish("__keyspace@0__:"+key, event)
+	}
+	if flags&notifyKeyevent != 0 {
+		d.Publish("__keyevent@0__:"+event, key)
+	}
+}
@@ -0,0 +1,104 @@
+package db
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// keyevents returns the queued keyevent notifications as "event key".
+func keyevents(s *Subscriber) []string {
+	if n, _ := s.Pending(); n == 0 {
+		return nil
+	}
+	msgs, _ := s.Receive()
+	var events []string
+	for _, m := range msgs {
+		event := strings.TrimPrefix(m.Channel, "__keyevent@0__:")
+		events = append(events, strings.TrimSpace(event+" "+m.Payload))
+	}
+	return events
+}
+
+func TestKeyspaceEvents(t *testing.T) {
+	d := New()
+	d.SetNotifyKeyspaceEvents("EA")
+	s := d.NewSubscriber()
+	d.PSubscribe(s, "__keyevent@0__:*")
+
+	soon := time.Now().Add(time.Hour)
+	tests := []struct {
+		name string
+		run  func()
+		want []string
+	}{
+		{"INCR", func() { d.IncrBy("n", 1) }, []string{"incrby n"}},
+		{"INCRBYFLOAT", func() { d.IncrByFloat("n", 0.5) }, []string{"incrbyfloat n"}},
+		{"APPEND", func() { d.Append("s", "x") }, []string{"append s"}},
+		{"SETRANGE", func() { d.SetRange("s", 2, "y") }, []string{"setrange s"}},
+		{"MSET", func() { d.MSet("a", "1", "b", "2") }, []string{"set a", "set b"}},
+		{"GETEX", func() { d.GetEx("a", soon, false) }, []string{"expire a"}},
+		{"GETEX PERSIST", func() { d.GetEx("a", time.Time{}, true) }, []string{"persist a"}},
+		{"GETDEL", func() { d.GetDel("a") }, []string{"del a"}},
+		{"SETBIT", func() { d.SetBit("bits", 3, 1) }, []string{"setbit bits"}},
+		{"BITOP", func() { d.BitOp("NOT", "not", "bits") }, []string{"set not"}},
+		{"BITFIELD", func() {
+			d.BitField("bits", []BitFieldOp{{Kind: "SET", Width: 8, Value: 1, Overflow: "WRAP"}})
+		}, []string{"setbit bits"}},
+		{"PFADD", func() { d.PFAdd("hll", "a") }, []string{"pfadd hll"}},
+		{"PFMERGE", func() { d.PFMerge("hll2", "hll") }, []string{"pfadd hll2"}},
+		{"GEOADD", func() {
+			d.GeoAdd("geo", false, false, false, []GeoPoint{{Member: "m", Lon: 1, Lat: 1}})
+		}, []string{"zadd geo"}},
+		{"GEOSEARCHSTORE", func() {
+			d.GeoSearchStore("near", "geo", GeoQuery{Lon: 1, Lat: 1, Radius: 10}, false, 1)
+		}, []string{"geosearchstore near"}},
+		{"SADD", func() { d.SAdd("set", "1", "2", "3") }, []string{"sadd set"}},
+		{"SPOP", func() { d.SPop("set", 1) }, []string{"spop set"}},
+		{"SMOVE", func() {
+			d.SAdd("one", "x")
+			keyevents(s)
+			d.SMove("one", "other", "x")
+		}, []string{"srem one", "del one", "sadd other"}},
+		{"SUNIONSTORE", func() { d.SUnionStore("union", "other") }, []string{"sunionstore union"}},
+		{"SINTERSTORE empty", func() { d.SInterStore("union", "missing") }, []string{"del union"}},
+		{"HSETNX", func() { d.HSetNX("h", "f", "1") }, []string{"hset h"}},
+		{"HINCRBY", func() { d.HIncrBy("h", "f", 1) }, []string{"hincrby h"}},
+		{"HINCRBYFLOAT", func() { d.HIncrByFloat("h", "f", 1.5) }, []string{"hincrbyfloat h"}},
+		{"HEXPIRE", func() { d.HExpire("h", soon, "", "f") }, []string{"hexpire h"}},
+		{"HPERSIST", func() { d.HPersist("h", "f") }, []string{"hpersist h"}},
+		{"HSETEX", func() {
+			d.HSetEx("h", "", FieldTTL{At: soon, Set: true}, "g", "1")
+		}, []string{"hset h", "hexpire h"}},
+		{"COPY", func() { d.Copy("h", "h2", false) }, []string{"copy_to h2"}},
+		{"RESTORE", func() {
+			payload, _, _, _ := d.Dump("h")
+			d.Restore("h3", payload, time.Time{}, false)
+		}, []string{"restore h3"}},
+		{"SORT STORE", func() {
+			d.SortStore("other", SortOptions{Count: -1, Alpha: true, Store: "sorted"})
+		}, []string{"sortstore sorted"}},
+		{"FLUSHALL", func() { d.Flush() }, []string{"flushall"}},
+	}
+	for _, tt := range tests {
+		tt.run()
+		if got := keyevents(s); !slices.Equal(got, tt.want) {
+			t.Errorf("%s published %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestKeyspaceEventsNew(t *testing.T) {
+	d := New()
+	d.SetNotifyKeyspaceEvents("En$")
+	s := d.NewSubscriber()
+	d.PSubscribe(s, "__keyevent@0__:*")
+
+	d.IncrBy("n", 1)
+	d.IncrBy("n", 1)
+	want := []string{"new n", "incrby n", "incrby n"}
+	if got := keyevents(s); !slices.Equal(got, want) {
+		t.Errorf("published %q, want %q", got, want)
+	}
+}
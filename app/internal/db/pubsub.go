@@ -0,0 +1,377 @@
+package db
+
+import (
+	"redis-go/internal/helper"
+	"sync"
+	"time"
+)
+
+// Pub/sub state has its own lock so publishing never waits on keyspace
+// operations.
+
+// Message is a published message as delivered to a subscriber. Pattern is
+// set when the subscriber matched the channel through a pattern, and Shard
+// when it was published to a shard channel. Invalidate marks a client
+// tracking invalidation, whose payload is Keys, nil meaning all keys.
+type Message struct {
+	Pattern    string
+	Channel    string
+	Payload    string
+	Shard      bool
+	Invalidate bool
+	Keys       []string
+}
+
+// Subscriber is the pub/sub identity of one client. All of its channel and
+// pattern subscriptions deliver into a single queue, so messages reach the
+// client in publish order. The queue is the client's pub/sub output buffer
+// and is bounded by the pubsub class of client-output-buffer-limit: a
+// subscriber that exceeds it is disconnected rather than losing messages.
+type Subscriber struct {
+	channels map[string]struct{}
+	patterns map[string]struct{}
+	shards   map[string]struct{} // shard channels
+
+	mu        sync.Mutex
+	queue     []Message
+	pending   int64     // bytes queued or taken but not yet flushed
+	softSince time.Time // when pending first reached the soft limit
+	closed    bool
+	overflow  bool
+	wake      chan struct{}
+	done      chan struct{} // closed with the subscriber
+}
+
+func (d *DB) NewSubscriber() *Subscriber {
+	return &Subscriber{
+		channels: make(map[string]struct{}),
+		patterns: make(map[string]struct{}),
+		shards:   make(map[string]struct{}),
+		wake:     make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+}
+
+// Receive waits for queued messages and returns all of them. ok is false
+// once the subscriber is closed. The messages still count against the output
+// buffer limit until they are passed to Flushed.
+func (s *Subscriber) Receive() (msgs []Message, ok bool) {
+	for {
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			return nil, false
+		}
+		if len(s.queue) > 0 {
+			msgs, s.queue = s.queue, nil
+			s.mu.Unlock()
+			return msgs, true
+		}
+		s.mu.Unlock()
+
+		select {
+		case <-s.wake:
+		case <-s.done:
+		}
+	}
+}
+
+// Flushed releases the output buffer space of messages returned by Receive
+// once they have been written to the client.
+func (s *Subscriber) Flushed(msgs []Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+	for _, msg := range msgs {
+		s.pending -= msg.size()
+	}
+	if s.pending <= 0 {
+		s.pending, s.softSince = 0, time.Time{}
+	}
+}
+
+// Done is closed when the subscriber is closed.
+func (s *Subscriber) Done() <-chan struct{} {
+	return s.done
+}
+
+// Overflowed reports whether the subscriber was closed for exceeding its
+// output buffer limit.
+func (s *Subscriber) Overflowed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.overflow
+}
+
+// Pending returns the number of queued messages and the approximate size in
+// bytes of the output buffer, which includes messages taken by Receive but
+// not yet flushed.
+func (s *Subscriber) Pending() (messages int, bytes int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.queue), s.pending
+}
+
+// size approximates the encoded size of the message push.
+func (m Message) size() int64 {
+	n := len(m.Pattern) + len(m.Channel) + len(m.Payload) + 48
+	for _, k := range m.Keys {
+		n += len(k) + 16
+	}
+	return int64(n)
+}
+
+// SetPubSubOutputLimit sets the pubsub output buffer limit for subscribers.
+func (d *DB) SetPubSubOutputLimit(hard, soft int64, softSeconds int) {
+	d.pubsubHardLimit.Store(hard)
+	d.pubsubSoftLimit.Store(soft)
+	d.pubsubSoftSeconds.Store(int64(softSeconds))
+}
+
+// PubSubStats returns how many messages were lost to subscribers closed for
+// exceeding their output buffer limit, and how many subscribers that was.
+func (d *DB) PubSubStats() (dropped, disconnected int64) {
+	return d.pubsubDropped.Load(), d.pubsubDisconnected.Load()
+}
+
+// count returns the number of channel and pattern subscriptions. Callers
+// must hold d.psMu.
+func (s *Subscriber) count() int {
+	return len(s.channels) + len(s.patterns)
+}
+
+// SubscriptionCount returns how many channels, patterns and shard channels s
+// is subscribed to.
+func (d *DB) SubscriptionCount(s *Subscriber) int {
+	d.psMu.RLock()
+	defer d.psMu.RUnlock()
+
+	return s.count() + len(s.shards)
+}
+
+// Subscriptions returns the channels, patterns and shard channels s is
+// subscribed to.
+func (d *DB) Subscriptions(s *Subscriber) (channels, patterns, shards []string) {
+	d.psMu.RLock()
+	defer d.psMu.RUnlock()
+
+	for ch := range s.channels {
+		channels = append(channels, ch)
+	}
+	for p := range s.patterns {
+		patterns = append(patterns, p)
+	}
+	for ch := range s.shards {
+		shards = append(shards, ch)
+	}
+	return channels, patterns, shards
+}
+
+// Subscribe adds channel to s and returns its subscription count.
+func (d *DB) Subscribe(s *Subscriber, channel string) int {
+	d.psMu.Lock()
+	defer d.psMu.Unlock()
+
+	addSubscriber(d.subscribers, channel, s)
+	s.channels[channel] = struct{}{}
+	return s.count()
+}
+
+// Unsubscribe removes channel from s and returns its subscription count.
+func (d *DB) Unsubscribe(s *Subscriber, channel string) int {
+	d.psMu.Lock()
+	defer d.psMu.Unlock()
+
+	removeSubscriber(d.subscribers, channel, s)
+	delete(s.channels, channel)
+	return s.count()
+}
+
+// PSubscribe subscribes s to every channel matching the glob pattern.
+func (d *DB) PSubscribe(s *Subscriber, pattern string) int {
+	d.psMu.Lock()
+	defer d.psMu.Unlock()
+
+	addSubscriber(d.patterns, pattern, s)
+	s.patterns[pattern] = struct{}{}
+	return s.count()
+}
+
+func (d *DB) PUnsubscribe(s *Subscriber, pattern string) int {
+	d.psMu.Lock()
+	defer d.psMu.Unlock()
+
+	removeSubscriber(d.patterns, pattern, s)
+	delete(s.patterns, pattern)
+	return s.count()
+}
+
+// CloseSubscriber drops every subscription of s and closes it.
+func (d *DB) CloseSubscriber(s *Subscriber) {
+	d.psMu.Lock()
+	defer d.psMu.Unlock()
+
+	d.dropSubscriptions(s)
+	s.close(false)
+}
+
+// dropSubscriptions removes s from every channel, pattern and shard channel.
+// Callers must hold d.psMu for writing.
+func (d *DB) dropSubscriptions(s *Subscriber) {
+	for channel := range s.channels {
+		removeSubscriber(d.subscribers, channel, s)
+	}
+	for pattern := range s.patterns {
+		removeSubscriber(d.patterns, pattern, s)
+	}
+	for channel := range s.shards {
+		d.removeShardSubscriber(channel, s)
+	}
+	clear(s.channels)
+	clear(s.patterns)
+	clear(s.shards)
+}
+
+// close marks s closed and returns how many queued messages it discarded.
+func (s *Subscriber) close(overflow bool) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return 0
+	}
+	n := len(s.queue)
+	s.closed, s.overflow = true, overflow
+	s.queue, s.pending = nil, 0
+	close(s.done)
+	return n
+}
+
+func addSubscriber(m map[string]map[*Subscriber]struct{}, name string, s *Subscriber) {
+	subs, ok := m[name]
+	if !ok {
+		subs = make(map[*Subscriber]struct{})
+		m[name] = subs
+	}
+	subs[s] = struct{}{}
+}
+
+func removeSubscriber(m map[string]map[*Subscriber]struct{}, name string, s *Subscriber) {
+	delete(m[name], s)
+	if len(m[name]) == 0 {
+		delete(m, name)
+	}
+}
+
+// deliver queues msg for s. It reports false when s is closed or has just
+// exceeded its output buffer limit, in which case the message is not
+// delivered. Callers must hold d.psMu.
+func (d *DB) deliver(s *Subscriber, msg Message, now time.Time) bool {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return false
+	}
+
+	s.queue = append(s.queue, msg)
+	s.pending += msg.size()
+
+	hard, soft := d.pubsubHardLimit.Load(), d.pubsubSoftLimit.Load()
+	softFor := time.Duration(d.pubsubSoftSeconds.Load()) * time.Second
+
+	over := hard > 0 && s.pending >= hard
+	if soft > 0 && s.pending >= soft {
+		if s.softSince.IsZero() {
+			s.softSince = now
+		} else if now.Sub(s.softSince) >= softFor {
+			over = true
+		}
+	} else {
+		s.softSince = time.Time{}
+	}
+
+	if !over {
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+		s.mu.Unlock()
+		return true
+	}
+	s.mu.Unlock()
+
+	// The subscriptions are removed by CloseSubscriber once the client is
+	// gone; until then nothing more is queued.
+	d.pubsubDropped.Add(int64(s.close(true)))
+	d.pubsubDisconnected.Add(1)
+	return false
+}
+
+// Publish delivers message to the subscribers of channel and of every
+// matching pattern. It returns the number of receivers.
+func (d *DB) Publish(channel string, message string) int {
+
+	d.psMu.RLock()
+	defer d.psMu.RUnlock()
+
+	now := time.Now()
+	n := 0
+	for s := range d.subscribers[channel] {
+		if d.deliver(s, Message{Channel: channel, Payload: message}, now) {
+			n++
+		}
+	}
+
+	for pattern, subs := range d.patterns {
+		if !helper.Match(pattern, channel, false) {
+			continue
+		}
+		for s := range subs {
+			if d.deliver(s, Message{Pattern: pattern, Channel: channel, Payload: message}, now) {
+				n++
+			}
+		}
+	}
+
+	return n
+}
+
+// Channels returns the channels with at least one subscriber, optionally
+// filtered by a glob pattern. Pattern subscriptions are not counted.
+func (d *DB) Channels(pattern string) []string {
+	d.psMu.RLock()
+	defer d.psMu.RUnlock()
+
+	channels := []string{}
+	for channel := range d.subscribers {
+		if pattern == "" || helper.Match(pattern, channel, false) {
+			channels = append(channels, channel)
+		}
+	}
+	return channels
+}
+
+// NumSub returns the number of subscribers of each channel.
+func (d *DB) NumSub(channels ...string) []int {
+	d.psMu.RLock()
+	defer d.psMu.RUnlock()
+
+	counts := make([]int, len(channels))
+	for i, channel := range channels {
+		counts[i] = len(d.subscribers[channel])
+	}
+	return counts
+}
+
+// NumPat returns the number of distinct patterns subscribed to.
+func (d *DB) NumPat() int {
+	d.psMu.RLock()
+	defer d.psMu.RUnlock()
+
+	return len(d.patterns)
+}
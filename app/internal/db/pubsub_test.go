@@ -0,0 +1,40 @@
+package db
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPubSubLimitCountsUnflushed(t *testing.T) {
+	d := New()
+	d.SetPubSubOutputLimit(1000, 0, 0)
+	s := d.NewSubscriber()
+	d.Subscribe(s, "c")
+
+	payload := strings.Repeat("x", 400)
+	d.Publish("c", payload)
+	msgs, _ := s.Receive()
+
+	// the taken message is still being written to the client
+	if _, bytes := s.Pending(); bytes == 0 {
+		t.Error("bytes taken by Receive are no longer counted")
+	}
+	d.Publish("c", payload)
+	s.Flushed(msgs)
+	if s.Overflowed() {
+		t.Fatal("subscriber closed below the limit")
+	}
+	if _, bytes := s.Pending(); bytes != (Message{Channel: "c", Payload: payload}).size() {
+		t.Errorf("pending = %d bytes after the first message was flushed", bytes)
+	}
+
+	msgs, _ = s.Receive()
+	d.Publish("c", payload)
+	d.Publish("c", payload)
+	if !s.Overflowed() {
+		t.Error("subscriber kept past the limit while its messages were unflushed")
+	}
+	if n := d.Publish("c", payload); n != 0 {
+		t.Errorf("PUBLISH to a closed subscriber reached %d", n)
+	}
+}
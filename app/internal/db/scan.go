@@ -0,0 +1,242 @@
+package db
+
+import (
+	"cmp"
+	"hash/maphash"
+	"iter"
+	"maps"
+	"math/bits"
+	"redis-go/internal/helper"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SCAN cursors follow the Redis dict scan: every name hashes to a bucket of
+// a power-of-two table sized after the collection, and buckets are visited
+// in reverse-binary order of their index. Growing the table splits a bucket
+// into buckets that come later in that order, so a cursor stays valid across
+// resizes and every element present for the whole scan is returned at least
+// once. Shrinking may return some elements twice.
+//
+// The bucket of a name in a table of 2^k buckets is the top k bits of its
+// reversed hash, so ordering names by reversed hash gives the visiting order
+// for every table size. A collection's scan index keeps its names in that
+// order; it is built by the first scan and then kept up to date by writers,
+// so each call only walks the buckets it returns.
+
+var scanSeed = maphash.MakeSeed()
+
+// scanEntry is a name in a scan index.
+type scanEntry struct {
+	rev  uint64 // reversed hash of name
+	name string
+}
+
+func compareScanEntries(a, b scanEntry) int {
+	return cmp.Or(cmp.Compare(a.rev, b.rev), strings.Compare(a.name, b.name))
+}
+
+func newScanEntry(name string) scanEntry {
+	return scanEntry{bits.Reverse64(maphash.String(scanSeed, name)), name}
+}
+
+// scanIndex orders the names of a collection for SCAN. A nil index is one
+// that was not built yet, and adding or removing names is a no-op.
+type scanIndex struct {
+	names *skiplist[scanEntry]
+}
+
+func newScanIndex(names iter.Seq[string]) *scanIndex {
+	x := &scanIndex{newSkiplist(compareScanEntries)}
+	for name := range names {
+		x.add(name)
+	}
+	return x
+}
+
+func (x *scanIndex) add(name string) {
+	if x != nil {
+		x.names.insert(newScanEntry(name))
+	}
+}
+
+func (x *scanIndex) remove(name string) {
+	if x != nil {
+		x.names.delete(newScanEntry(name))
+	}
+}
+
+// scanIndexOf returns the scan index in *x, building it from names first if
+// needed. Callers must hold d.mu for reading: scans running side by side
+// build an index only once, and writers, which hold d.mu for writing, update
+// built indexes without d.scanMu.
+func (d *DB) scanIndexOf(x **scanIndex, names iter.Seq[string]) *scanIndex {
+	d.scanMu.Lock()
+	defer d.scanMu.Unlock()
+
+	if *x == nil {
+		*x = newScanIndex(names)
+	}
+	return *x
+}
+
+// scanMask returns the index mask of a table holding n elements.
+func scanMask(n int) uint64 {
+	size := uint64(4)
+	for size < uint64(n) {
+		size <<= 1
+	}
+	return size - 1
+}
+
+// scanNames visits at least count non-empty buckets of a table holding n
+// elements, starting at cursor, and returns the names found along with the
+// cursor to resume from, 0 once the iteration is complete.
+func scanNames(n int, x *scanIndex, cursor uint64, count int) (uint64, []string) {
+	top := bits.Reverse64(scanMask(n)) // the bits of rev that select a bucket
+	start := bits.Reverse64(cursor) & top
+
+	var out []string
+	buckets := 0
+	prev := uint64(0)
+	for e := range x.names.from(scanEntry{rev: start}) {
+		bucket := e.rev & top
+		if buckets == 0 || bucket != prev {
+			if buckets == count {
+				// resume at the first bucket not returned yet
+				return bits.Reverse64(bucket), out
+			}
+			buckets++
+			prev = bucket
+		}
+		out = append(out, e.name)
+	}
+	return 0, out
+}
+
+// ScanOptions filters the elements returned by the SCAN family. Match is a
+// glob pattern and Type restricts SCAN to keys of one type.
+type ScanOptions struct {
+	Match string
+	Count int
+	Type  ValueType
+}
+
+func (o ScanOptions) count() int {
+	if o.Count <= 0 {
+		return 10
+	}
+	return o.Count
+}
+
+func (o ScanOptions) matches(name string) bool {
+	return o.Match == "" || o.Match == "*" || helper.Match(o.Match, name, false)
+}
+
+// Scan iterates the keyspace. Expired keys are skipped.
+func (d *DB) Scan(cursor uint64, opts ScanOptions) (uint64, []string) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	next, names := scanNames(len(d.store), d.scanIndexOf(&d.keys, maps.Keys(d.store)), cursor, opts.count())
+
+	keys := make([]string, 0, len(names))
+	for _, key := range names {
+		itm := d.lookup(key)
+		if itm == nil || !opts.matches(key) || (opts.Type != "" && itm.Type != opts.Type) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return next, keys
+}
+
+// Keys returns every live key matching pattern.
+func (d *DB) Keys(pattern string) []string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	opts := ScanOptions{Match: pattern}
+	keys := []string{}
+	for key := range d.store {
+		if d.lookup(key) != nil && opts.matches(key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// SScan iterates the members of a set. Small intset-encoded sets are
+// returned in a single call, as Redis does for compact encodings.
+func (d *DB) SScan(key string, cursor uint64, opts ScanOptions) (uint64, []string, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	s, err := d.setAt(key)
+	if err != nil || s == nil {
+		return 0, nil, err
+	}
+
+	var next uint64
+	var names []string
+	if s.encoding() == encodingIntset {
+		names = s.members()
+	} else {
+		next, names = scanNames(s.len(), d.scanIndexOf(&s.index, s.each), cursor, opts.count())
+	}
+
+	members := names[:0]
+	for _, m := range names {
+		if opts.matches(m) {
+			members = append(members, m)
+		}
+	}
+	return next, members, nil
+}
+
+// HScan iterates the fields of a hash and returns field/value pairs.
+// Expired fields are skipped.
+func (d *DB) HScan(key string, cursor uint64, opts ScanOptions) (uint64, []string, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	itm, err := d.hashAt(key)
+	if err != nil || itm == nil {
+		return 0, nil, err
+	}
+
+	now := time.Now()
+	next, fields := scanNames(len(itm.HashValue), d.scanIndexOf(&itm.index, maps.Keys(itm.HashValue)), cursor, opts.count())
+
+	var pairs []string
+	for _, f := range fields {
+		if itm.fieldExpired(f, now) || !opts.matches(f) {
+			continue
+		}
+		pairs = append(pairs, f, itm.HashValue[f])
+	}
+	return next, pairs, nil
+}
+
+// ZScan iterates the members of a sorted set and returns member/score
+// pairs.
+func (d *DB) ZScan(key string, cursor uint64, opts ScanOptions) (uint64, []string, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	itm, err := d.zsetAt(key)
+	if err != nil || itm == nil {
+		return 0, nil, err
+	}
+
+	next, members := scanNames(len(itm.ZSetValue), d.scanIndexOf(&itm.index, maps.Keys(itm.ZSetValue)), cursor, opts.count())
+
+	var pairs []string
+	for _, m := range members {
+		if opts.matches(m) {
+			pairs = append(pairs, m, strconv.FormatFloat(itm.ZSetValue[m], 'f', -1, 64))
+		}
+	}
+	return next, pairs, nil
+}
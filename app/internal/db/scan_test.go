@@ -0,0 +1,146 @@
+package db
+
+import (
+	"strconv"
+	"testing"
+)
+
+// scanAll runs a full SCAN with the given COUNT, calling between after every
+// call, and returns how often each key was seen.
+func scanAll(d *DB, count int, between func(call int)) map[string]int {
+	seen := make(map[string]int)
+	cursor := uint64(0)
+	for call := 0; ; call++ {
+		var keys []string
+		cursor, keys = d.Scan(cursor, ScanOptions{Count: count})
+		for _, k := range keys {
+			seen[k]++
+		}
+		if cursor == 0 {
+			return seen
+		}
+		between(call)
+	}
+}
+
+func TestScanReturnsEveryKey(t *testing.T) {
+	d := New()
+	for i := range 1000 {
+		d.Set("k"+strconv.Itoa(i), "v", 0)
+	}
+
+	for _, count := range []int{1, 10, 5000} {
+		seen := scanAll(d, count, func(int) {})
+		if len(seen) != 1000 {
+			t.Errorf("COUNT %d: saw %d keys, want 1000", count, len(seen))
+		}
+		for k, n := range seen {
+			if n != 1 {
+				t.Errorf("COUNT %d: saw %s %d times", count, k, n)
+			}
+		}
+	}
+}
+
+func TestScanWhileWriting(t *testing.T) {
+	d := New()
+	for i := range 100 {
+		d.Set("k"+strconv.Itoa(i), "v", 0)
+	}
+
+	// grow the keyspace past several table sizes and delete keys that are
+	// not part of the original set
+	seen := scanAll(d, 5, func(call int) {
+		for j := range 20 {
+			d.Set("new"+strconv.Itoa(call*20+j), "v", 0)
+		}
+		d.Delete("new" + strconv.Itoa(call))
+	})
+	for i := range 100 {
+		if seen["k"+strconv.Itoa(i)] == 0 {
+			t.Errorf("k%d was never returned", i)
+		}
+	}
+	if d.keys.names.len != len(d.store) {
+		t.Errorf("scan index holds %d keys, the keyspace %d", d.keys.names.len, len(d.store))
+	}
+}
+
+func TestCollectionScans(t *testing.T) {
+	d := New()
+	var members, pairs []string
+	for i := range 300 {
+		members = append(members, "m"+strconv.Itoa(i))
+		pairs = append(pairs, "f"+strconv.Itoa(i), "v")
+	}
+	if _, err := d.SAdd("s", members...); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.HSet("h", pairs...); err != nil {
+		t.Fatal(err)
+	}
+
+	seen := make(map[string]bool)
+	for cursor := uint64(0); ; {
+		var got []string
+		var err error
+		cursor, got, err = d.SScan("s", cursor, ScanOptions{Count: 7})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range got {
+			seen[m] = true
+		}
+		if cursor == 0 {
+			break
+		}
+		// members added and removed between calls keep the index in step
+		d.SAdd("s", "extra"+strconv.FormatUint(cursor, 10))
+		d.SRem("s", "m0")
+	}
+	if len(seen) < 299 {
+		t.Errorf("SSCAN saw %d members", len(seen))
+	}
+
+	fields := 0
+	for cursor := uint64(0); ; {
+		var got []string
+		var err error
+		cursor, got, err = d.HScan("h", cursor, ScanOptions{Count: 7})
+		if err != nil {
+			t.Fatal(err)
+		}
+		fields += len(got) / 2
+		if cursor == 0 {
+			break
+		}
+	}
+	if fields != 300 {
+		t.Errorf("HSCAN saw %d fields, want 300", fields)
+	}
+}
+
+func TestConcurrentScans(t *testing.T) {
+	d := New()
+	for i := range 100 {
+		d.Set("k"+strconv.Itoa(i), "v", 0)
+	}
+
+	done := make(chan bool)
+	for range 2 {
+		go func() {
+			done <- len(scanAll(d, 3, func(int) {})) >= 100
+		}()
+	}
+	go func() {
+		for i := range 100 {
+			d.Set("w"+strconv.Itoa(i), "v", 0)
+		}
+		done <- true
+	}()
+	for range 3 {
+		if !<-done {
+			t.Error("a scan missed keys")
+		}
+	}
+}
@@ -0,0 +1,394 @@
+package db
+
+import (
+	"math/rand/v2"
+)
+
+// Set Datastructure
+
+// setAt returns the set stored at key. A missing key yields a nil set, which
+// behaves as an empty set. Callers must hold d.mu.
+func (d *DB) setAt(key string) (*setValue, error) {
+	itm := d.lookup(key)
+	if itm == nil {
+		return nil, nil
+	}
+	if itm.Type != SetType {
+		return nil, ErrWrongType
+	}
+	return itm.SetValue, nil
+}
+
+// storeSet replaces whatever lives at key with members, deleting the key when
+// the set is empty, and publishes event. Callers must hold d.mu for writing.
+func (d *DB) storeSet(key string, members *setValue, event string) {
+	existed := d.lookup(key) != nil
+	if members.len() == 0 {
+		d.deleteItem(key)
+		d.modified(key)
+		if existed {
+			d.notify(notifyGeneric, "del", key)
+		}
+		return
+	}
+
+	d.setItem(key, &item{Type: SetType, SetValue: members})
+	d.modified(key)
+	if !existed {
+		d.notify(notifyNew, "new", key)
+	}
+	d.notify(notifySet, event, key)
+}
+
+func (d *DB) SAdd(key string, members ...string) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	itm := d.lookup(key)
+	created := itm == nil
+	if created {
+		itm = &item{Type: SetType, SetValue: newSetValue()}
+	}
+	if itm.Type != SetType {
+		return 0, ErrWrongType
+	}
+
+	maxIntset := d.MaxIntsetEntries()
+	added := 0
+	for _, m := range members {
+		if itm.SetValue.add(m, maxIntset) {
+			added++
+		}
+	}
+
+	if added == 0 {
+		return 0, nil
+	}
+
+	d.setItem(key, itm)
+	d.modified(key)
+	if created {
+		d.notify(notifyNew, "new", key)
+	}
+	d.notify(notifySet, "sadd", key)
+	return added, nil
+}
+
+func (d *DB) SRem(key string, members ...string) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	set, err := d.setAt(key)
+	if err != nil || set == nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, m := range members {
+		if set.remove(m) {
+			removed++
+		}
+	}
+
+	if removed > 0 {
+		d.modified(key)
+		d.notify(notifySet, "srem", key)
+	}
+	if set.len() == 0 {
+		d.deleteItem(key)
+		d.notify(notifyGeneric, "del", key)
+	}
+	return removed, nil
+}
+
+func (d *DB) SMembers(key string) []string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	set, err := d.setAt(key)
+	if err != nil || set == nil {
+		return nil
+	}
+
+	return set.members()
+}
+
+func (d *DB) SIsMember(key, member string) (bool, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	set, err := d.setAt(key)
+	if err != nil {
+		return false, err
+	}
+
+	return set.has(member), nil
+}
+
+func (d *DB) SMIsMember(key string, members ...string) ([]bool, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	set, err := d.setAt(key)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]bool, len(members))
+	for i, m := range members {
+		result[i] = set.has(m)
+	}
+	return result, nil
+}
+
+func (d *DB) SCard(key string) (int, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	set, err := d.setAt(key)
+	if err != nil {
+		return 0, err
+	}
+	return set.len(), nil
+}
+
+// SPop removes and returns up to count random members from the set at key.
+func (d *DB) SPop(key string, count int) ([]string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	set, err := d.setAt(key)
+	if err != nil || set == nil {
+		return nil, err
+	}
+	if count == 0 {
+		return []string{}, nil
+	}
+
+	if count >= set.len() {
+		d.deleteItem(key)
+		d.modified(key)
+		d.notify(notifySet, "spop", key)
+		d.notify(notifyGeneric, "del", key)
+		return set.members(), nil
+	}
+
+	popped := make([]string, count)
+	for i := range popped {
+		popped[i] = set.random()
+		set.remove(popped[i])
+	}
+	d.modified(key)
+	d.notify(notifySet, "spop", key)
+	return popped, nil
+}
+
+// SRandMember returns random members without removing them. A positive count
+// returns up to count distinct members; a negative count returns exactly
+// -count members, possibly repeated.
+func (d *DB) SRandMember(key string, count int) ([]string, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	set, err := d.setAt(key)
+	if err != nil || set == nil {
+		return nil, err
+	}
+
+	if count < 0 {
+		result := make([]string, -count)
+		for i := range result {
+			result[i] = set.random()
+		}
+		return result, nil
+	}
+
+	members := set.members()
+	rand.Shuffle(len(members), func(i, j int) {
+		members[i], members[j] = members[j], members[i]
+	})
+	if count < len(members) {
+		members = members[:count]
+	}
+	return members, nil
+}
+
+func (d *DB) SMove(src, dst, member string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	srcSet, err := d.setAt(src)
+	if err != nil {
+		return false, err
+	}
+	dstSet, err := d.setAt(dst)
+	if err != nil {
+		return false, err
+	}
+
+	if !srcSet.has(member) {
+		return false, nil
+	}
+	if src == dst {
+		return true, nil
+	}
+
+	srcSet.remove(member)
+	if srcSet.len() == 0 {
+		d.deleteItem(src)
+	}
+
+	if dstSet == nil {
+		dstSet = newSetValue()
+		d.setItem(dst, &item{Type: SetType, SetValue: dstSet})
+		d.notify(notifyNew, "new", dst)
+	}
+	added := dstSet.add(member, d.MaxIntsetEntries())
+
+	d.modified(src, dst)
+	d.notify(notifySet, "srem", src)
+	if srcSet.len() == 0 {
+		d.notify(notifyGeneric, "del", src)
+	}
+	if added {
+		d.notify(notifySet, "sadd", dst)
+	}
+	return true, nil
+}
+
+// setsAt collects the sets stored at keys. Callers must hold d.mu.
+func (d *DB) setsAt(keys []string) ([]*setValue, error) {
+	sets := make([]*setValue, len(keys))
+	for i, k := range keys {
+		set, err := d.setAt(k)
+		if err != nil {
+			return nil, err
+		}
+		sets[i] = set
+	}
+	return sets, nil
+}
+
+// interSets intersects sets, stopping once limit members are found when limit
+// is positive.
+func interSets(sets []*setValue, limit, maxIntset int) *setValue {
+	result := newSetValue()
+	if len(sets) == 0 {
+		return result
+	}
+
+	// iterate over the smallest set to keep the work proportional to it
+	smallest := 0
+	for i, s := range sets {
+		if s.len() < sets[smallest].len() {
+			smallest = i
+		}
+	}
+
+	sets[smallest].each(func(m string) bool {
+		for i, s := range sets {
+			if i != smallest && !s.has(m) {
+				return true
+			}
+		}
+		result.add(m, maxIntset)
+		return limit <= 0 || result.len() < limit
+	})
+	return result
+}
+
+func unionSets(sets []*setValue, maxIntset int) *setValue {
+	result := newSetValue()
+	for _, s := range sets {
+		s.each(func(m string) bool {
+			result.add(m, maxIntset)
+			return true
+		})
+	}
+	return result
+}
+
+func diffSets(sets []*setValue, maxIntset int) *setValue {
+	result := newSetValue()
+	if len(sets) == 0 {
+		return result
+	}
+	sets[0].each(func(m string) bool {
+		for _, s := range sets[1:] {
+			if s.has(m) {
+				return true
+			}
+		}
+		result.add(m, maxIntset)
+		return true
+	})
+	return result
+}
+
+func (d *DB) SInter(keys ...string) ([]string, error) {
+	return d.setAlgebra(keys, func(sets []*setValue, maxIntset int) *setValue {
+		return interSets(sets, 0, maxIntset)
+	})
+}
+
+func (d *DB) SUnion(keys ...string) ([]string, error) {
+	return d.setAlgebra(keys, unionSets)
+}
+
+func (d *DB) SDiff(keys ...string) ([]string, error) {
+	return d.setAlgebra(keys, diffSets)
+}
+
+func (d *DB) SInterStore(dst string, keys ...string) (int, error) {
+	return d.setAlgebraStore(dst, "sinterstore", keys, func(sets []*setValue, maxIntset int) *setValue {
+		return interSets(sets, 0, maxIntset)
+	})
+}
+
+func (d *DB) SUnionStore(dst string, keys ...string) (int, error) {
+	return d.setAlgebraStore(dst, "sunionstore", keys, unionSets)
+}
+
+func (d *DB) SDiffStore(dst string, keys ...string) (int, error) {
+	return d.setAlgebraStore(dst, "sdiffstore", keys, diffSets)
+}
+
+// SInterCard returns the cardinality of the intersection of keys, stopping
+// early once limit is reached. A limit of 0 means no limit.
+func (d *DB) SInterCard(limit int, keys ...string) (int, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	sets, err := d.setsAt(keys)
+	if err != nil {
+		return 0, err
+	}
+	return interSets(sets, limit, d.MaxIntsetEntries()).len(), nil
+}
+
+func (d *DB) setAlgebra(keys []string, op func([]*setValue, int) *setValue) ([]string, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	sets, err := d.setsAt(keys)
+	if err != nil {
+		return nil, err
+	}
+	return op(sets, d.MaxIntsetEntries()).members(), nil
+}
+
+// setAlgebraStore computes op over keys and stores the result at dst while
+// holding the write lock, so the read and the write happen atomically.
+func (d *DB) setAlgebraStore(dst, event string, keys []string, op func([]*setValue, int) *setValue) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	sets, err := d.setsAt(keys)
+	if err != nil {
+		return 0, err
+	}
+
+	result := op(sets, d.MaxIntsetEntries())
+	d.storeSet(dst, result, event)
+	return result.len(), nil
+}
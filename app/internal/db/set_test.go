@@ -0,0 +1,109 @@
+package db
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestSPop(t *testing.T) {
+	for _, prefix := range []string{"", "m"} { // intset and hash table
+		d := New()
+		var all []string
+		for i := range 100 {
+			all = append(all, fmt.Sprint(prefix, i))
+		}
+		d.SAdd("s", all...)
+
+		var popped []string
+		for _, count := range []int{1, 10, 39} {
+			got, err := d.SPop("s", count)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != count {
+				t.Fatalf("SPOP %d returned %d members", count, len(got))
+			}
+			popped = append(popped, got...)
+		}
+
+		left := d.SMembers("s")
+		if len(left) != 50 {
+			t.Fatalf("%d members left, want 50", len(left))
+		}
+		for _, m := range left {
+			if ok, _ := d.SIsMember("s", m); !ok {
+				t.Errorf("member %q listed but not found", m)
+			}
+		}
+
+		rest, _ := d.SPop("s", 100)
+		popped = append(popped, rest...)
+		slices.Sort(popped)
+		slices.Sort(all)
+		if !slices.Equal(popped, all) {
+			t.Errorf("popped %d members, want each of the %d once", len(popped), len(all))
+		}
+		if d.Exists("s") != 0 {
+			t.Error("popping every member left the key")
+		}
+	}
+}
+
+func TestSPopFair(t *testing.T) {
+	seen := make(map[string]int)
+	for range 2000 {
+		d := New()
+		d.SAdd("s", "a", "b", "c", "d")
+		got, _ := d.SPop("s", 1)
+		seen[got[0]]++
+	}
+	for _, m := range []string{"a", "b", "c", "d"} {
+		if seen[m] < 350 {
+			t.Errorf("%q popped %d times out of 2000, want about 500", m, seen[m])
+		}
+	}
+}
+
+func TestSetNoopWritesKeepVersion(t *testing.T) {
+	d := New()
+	d.SAdd("s", "a", "b")
+	_, _, before, _ := d.Dump("s")
+
+	if n, _ := d.SAdd("s", "a", "b"); n != 0 {
+		t.Fatalf("SADD of existing members added %d", n)
+	}
+	if got, _ := d.SPop("s", 0); len(got) != 0 || got == nil {
+		t.Fatalf("SPOP 0 = %#v, want an empty list", got)
+	}
+	if _, _, after, _ := d.Dump("s"); after != before {
+		t.Error("a write that changed nothing marked the set modified")
+	}
+
+	d.SAdd("s", "c")
+	if _, _, after, _ := d.Dump("s"); after == before {
+		t.Error("SADD of a new member did not mark the set modified")
+	}
+}
+
+func TestSRandMemberNegative(t *testing.T) {
+	d := New()
+	d.SAdd("s", "a", "b", "c")
+	got, err := d.SRandMember("s", -100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 100 {
+		t.Fatalf("SRANDMEMBER -100 returned %d members", len(got))
+	}
+	seen := make(map[string]bool)
+	for _, m := range got {
+		if m != "a" && m != "b" && m != "c" {
+			t.Fatalf("SRANDMEMBER returned %q, which is not a member", m)
+		}
+		seen[m] = true
+	}
+	if len(seen) != 3 {
+		t.Errorf("100 picks saw only %d of 3 members", len(seen))
+	}
+}
@@ -0,0 +1,106 @@
+package db
+
+import (
+	"redis-go/internal/helper"
+	"time"
+)
+
+// Shard channels are a namespace of their own: SPUBLISH only reaches
+// SSUBSCRIBE subscribers and is never matched by patterns. Channels are kept
+// per hash slot, like keys, so that in a cluster a slot's channels can be
+// served by the node owning it.
+
+// shardSubscribers returns the subscribers of a shard channel. Callers must
+// hold d.psMu.
+func (d *DB) shardSubscribers(channel string) map[*Subscriber]struct{} {
+	return d.shards[helper.KeySlot(channel)][channel]
+}
+
+// removeShardSubscriber removes s from a shard channel. Callers must hold
+// d.psMu for writing.
+func (d *DB) removeShardSubscriber(channel string, s *Subscriber) {
+	slot := helper.KeySlot(channel)
+	channels, ok := d.shards[slot]
+	if !ok {
+		return
+	}
+	removeSubscriber(channels, channel, s)
+	if len(channels) == 0 {
+		delete(d.shards, slot)
+	}
+}
+
+// SSubscribe adds shard channel to s and returns its shard subscription
+// count.
+func (d *DB) SSubscribe(s *Subscriber, channel string) int {
+	d.psMu.Lock()
+	defer d.psMu.Unlock()
+
+	slot := helper.KeySlot(channel)
+	channels, ok := d.shards[slot]
+	if !ok {
+		channels = make(map[string]map[*Subscriber]struct{})
+		d.shards[slot] = channels
+	}
+	addSubscriber(channels, channel, s)
+	s.shards[channel] = struct{}{}
+	return len(s.shards)
+}
+
+// SUnsubscribe removes shard channel from s and returns its shard
+// subscription count.
+func (d *DB) SUnsubscribe(s *Subscriber, channel string) int {
+	d.psMu.Lock()
+	defer d.psMu.Unlock()
+
+	if _, ok := s.shards[channel]; ok {
+		d.removeShardSubscriber(channel, s)
+		delete(s.shards, channel)
+	}
+	return len(s.shards)
+}
+
+// SPublish delivers message to the subscribers of shard channel and returns
+// the number of receivers.
+func (d *DB) SPublish(channel, message string) int {
+	d.psMu.RLock()
+	defer d.psMu.RUnlock()
+
+	now := time.Now()
+	n := 0
+	for s := range d.shardSubscribers(channel) {
+		if d.deliver(s, Message{Channel: channel, Payload: message, Shard: true}, now) {
+			n++
+		}
+	}
+	return n
+}
+
+// ShardChannels returns the shard channels with at least one subscriber,
+// optionally filtered by a glob pattern.
+func (d *DB) ShardChannels(pattern string) []string {
+	d.psMu.RLock()
+	defer d.psMu.RUnlock()
+
+	channels := []string{}
+	for _, slot := range d.shards {
+		for channel := range slot {
+			if pattern == "" || helper.Match(pattern, channel, false) {
+				channels = append(channels, channel)
+			}
+		}
+	}
+	return channels
+}
+
+// ShardNumSub returns the number of subscribers of each shard channel.
+func (d *DB) ShardNumSub(channels ...string) []int {
+	d.psMu.RLock()
+	defer d.psMu.RUnlock()
+
+	counts := make([]int, len(channels))
+	for i, channel := range channels {
+		counts[i] = len(d.shardSubscribers(channel))
+	}
+	return counts
+}
@@ -0,0 +1,106 @@
+package db
+
+import (
+	"iter"
+	"math/rand/v2"
+)
+
+// skiplist is an ordered set of values, used where a collection stored in a
+// map also needs to be walked in order: SCAN cursors and score ranges.
+
+const skiplistMaxLevel = 32
+
+type skiplist[T any] struct {
+	cmp   func(a, b T) int
+	head  skipNode[T]
+	level int
+	len   int
+}
+
+type skipNode[T any] struct {
+	val  T
+	next []*skipNode[T]
+}
+
+func newSkiplist[T any](cmp func(a, b T) int) *skiplist[T] {
+	return &skiplist[T]{
+		cmp:   cmp,
+		head:  skipNode[T]{next: make([]*skipNode[T], skiplistMaxLevel)},
+		level: 1,
+	}
+}
+
+// randomLevel picks a node height, each level half as likely as the one
+// below it.
+func randomLevel() int {
+	level := 1
+	for level < skiplistMaxLevel && rand.IntN(2) == 0 {
+		level++
+	}
+	return level
+}
+
+// path returns, for each level, the last node before v.
+func (s *skiplist[T]) path(v T) [skiplistMaxLevel]*skipNode[T] {
+	var update [skiplistMaxLevel]*skipNode[T]
+	n := &s.head
+	for l := s.level - 1; l >= 0; l-- {
+		for n.next[l] != nil && s.cmp(n.next[l].val, v) < 0 {
+			n = n.next[l]
+		}
+		update[l] = n
+	}
+	return update
+}
+
+// insert adds v and reports whether it was not present yet.
+func (s *skiplist[T]) insert(v T) bool {
+	update := s.path(v)
+	if n := update[0].next[0]; n != nil && s.cmp(n.val, v) == 0 {
+		return false
+	}
+
+	level := randomLevel()
+	for l := s.level; l < level; l++ {
+		update[l] = &s.head
+	}
+	s.level = max(s.level, level)
+
+	node := &skipNode[T]{val: v, next: make([]*skipNode[T], level)}
+	for l := range level {
+		node.next[l] = update[l].next[l]
+		update[l].next[l] = node
+	}
+	s.len++
+	return true
+}
+
+// delete removes v and reports whether it was present.
+func (s *skiplist[T]) delete(v T) bool {
+	update := s.path(v)
+	node := update[0].next[0]
+	if node == nil || s.cmp(node.val, v) != 0 {
+		return false
+	}
+
+	for l := range node.next {
+		update[l].next[l] = node.next[l]
+	}
+	for s.level > 1 && s.head.next[s.level-1] == nil {
+		s.level--
+	}
+	s.len--
+	return true
+}
+
+// from iterates the values in order, starting at the first one not less
+// than v.
+func (s *skiplist[T]) from(v T) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for n := s.path(v)[0].next[0]; n != nil; n = n.next[0] {
+			if !yield(n.val) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package db
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrSortScore = errors.New("ERR One or more scores can't be converted into double")
+
+// SortOptions describes a SORT. By and Get are key patterns where the first
+// '*' is replaced by the element and a trailing "->field" reads a hash
+// field; the pattern "#" stands for the element itself. A By pattern without
+// '*' disables sorting. Count < 0 means no limit.
+type SortOptions struct {
+	By     string
+	Get    []string
+	Offset int
+	Count  int
+	Desc   bool
+	Alpha  bool
+	Store  string
+}
+
+// Sort returns the sorted elements of the list, set or sorted set at key, or
+// the values of the GET patterns for each of them. ok[i] is false for values
+// that could not be looked up.
+func (d *DB) Sort(key string, opts SortOptions) (vals []string, ok []bool, err error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.sort(key, opts)
+}
+
+// SortStore runs the sort and stores the result as a list at opts.Store,
+// with missing values stored as empty strings. It returns the list length.
+func (d *DB) SortStore(key string, opts SortOptions) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	vals, _, err := d.sort(key, opts)
+	if err != nil {
+		return 0, err
+	}
+
+	existed := d.lookup(opts.Store) != nil
+	if len(vals) == 0 {
+		d.deleteItem(opts.Store)
+		d.modified(opts.Store)
+		if existed {
+			d.notify(notifyGeneric, "del", opts.Store)
+		}
+		return 0, nil
+	}
+
+	d.setItem(opts.Store, &item{Type: ListType, ListValue: vals})
+	d.modified(opts.Store)
+	if !existed {
+		d.notify(notifyNew, "new", opts.Store)
+	}
+	d.notify(notifyList, "sortstore", opts.Store)
+	return len(vals), nil
+}
+
+type sortEntry struct {
+	elem   string
+	score  float64
+	weight string
+	found  bool
+}
+
+// sort implements SORT. Callers must hold d.mu.
+func (d *DB) sort(key string, opts SortOptions) ([]string, []bool, error) {
+	itm := d.lookup(key)
+
+	var elems []string
+	isSet := false
+	if itm != nil {
+		switch itm.Type {
+		case ListType:
+			elems = append(elems, itm.ListValue...)
+		case SetType:
+			elems = itm.SetValue.members()
+			isSet = true
+		case ZSetType:
+			elems = zsetMembers(itm.ZSetValue)
+		default:
+			return nil, nil, ErrWrongType
+		}
+	}
+
+	dontSort := opts.By != "" && !strings.Contains(opts.By, "*")
+	sortBy := opts.By
+	if dontSort && isSet && opts.Store != "" {
+		// set order is random; sort the stored result to keep it stable
+		dontSort, sortBy, opts.Alpha = false, "", true
+	}
+
+	entries := make([]sortEntry, len(elems))
+	for i, e := range elems {
+		entries[i].elem = e
+		if dontSort {
+			continue
+		}
+
+		weight, found := e, true
+		if sortBy != "" {
+			weight, found = d.lookupPattern(sortBy, e)
+		}
+		if opts.Alpha {
+			entries[i].weight, entries[i].found = weight, found
+			continue
+		}
+		if found {
+			score, err := strconv.ParseFloat(weight, 64)
+			if err != nil || math.IsNaN(score) {
+				return nil, nil, ErrSortScore
+			}
+			entries[i].score = score
+		}
+	}
+
+	if !dontSort {
+		sort.SliceStable(entries, func(i, j int) bool {
+			c := compareSortEntries(&entries[i], &entries[j], opts.Alpha, sortBy != "")
+			if opts.Desc {
+				return c > 0
+			}
+			return c < 0
+		})
+	} else if itm != nil && itm.Type == ZSetType && opts.Desc {
+		// unsorted sorted sets keep their own order, which DESC reverses
+		for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+			entries[i], entries[j] = entries[j], entries[i]
+		}
+	}
+
+	start, end := opts.Offset, len(entries)
+	if start < 0 {
+		start = 0
+	}
+	if start > len(entries) {
+		start = len(entries)
+	}
+	if opts.Count >= 0 && opts.Count < end-start {
+		end = start + opts.Count
+	}
+	entries = entries[start:end]
+
+	var vals []string
+	var ok []bool
+	for _, e := range entries {
+		if len(opts.Get) == 0 {
+			vals, ok = append(vals, e.elem), append(ok, true)
+			continue
+		}
+		for _, pattern := range opts.Get {
+			v, found := d.lookupPattern(pattern, e.elem)
+			vals, ok = append(vals, v), append(ok, found)
+		}
+	}
+	return vals, ok, nil
+}
+
+// compareSortEntries orders entries by score, or by weight with alpha.
+// Missing weights sort first, and ties are broken by the element itself.
+func compareSortEntries(a, b *sortEntry, alpha, byPattern bool) int {
+	c := 0
+	switch {
+	case !alpha:
+		if a.score < b.score {
+			c = -1
+		} else if a.score > b.score {
+			c = 1
+		}
+	case byPattern:
+		switch {
+		case !a.found && !b.found:
+		case !a.found:
+			c = -1
+		case !b.found:
+			c = 1
+		default:
+			c = strings.Compare(a.weight, b.weight)
+		}
+	default:
+		c = strings.Compare(a.elem, b.elem)
+	}
+	if c == 0 {
+		c = strings.Compare(a.elem, b.elem)
+	}
+	return c
+}
+
+// lookupPattern resolves a SORT BY or GET pattern for elem. Callers must
+// hold d.mu.
+func (d *DB) lookupPattern(pattern, elem string) (string, bool) {
+	if pattern == "#" {
+		return elem, true
+	}
+
+	star := strings.IndexByte(pattern, '*')
+	if star < 0 {
+		return "", false
+	}
+
+	keyPattern, field := pattern, ""
+	if arrow := strings.Index(pattern[star+1:], "->"); arrow >= 0 && star+1+arrow+2 < len(pattern) {
+		keyPattern = pattern[:star+1+arrow]
+		field = pattern[star+1+arrow+2:]
+	}
+	key := keyPattern[:star] + elem + keyPattern[star+1:]
+
+	itm := d.lookup(key)
+	if itm == nil {
+		return "", false
+	}
+	if field != "" {
+		if itm.Type != HashType {
+			return "", false
+		}
+		return itm.hashGet(field, time.Now())
+	}
+	if itm.Type != StringType {
+		return "", false
+	}
+	return itm.str(), true
+}
+
+// zsetMembers returns the members of a sorted set ordered by score, then
+// member.
+func zsetMembers(z map[string]float64) []string {
+	members := make([]string, 0, len(z))
+	for m := range z {
+		members = append(members, m)
+	}
+	sort.Slice(members, func(i, j int) bool {
+		si, sj := z[members[i]], z[members[j]]
+		if si != sj {
+			return si < sj
+		}
+		return members[i] < members[j]
+	})
+	return members
+}
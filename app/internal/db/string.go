@@ -0,0 +1,390 @@
+package db
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"time"
+)
+
+// MaxStringSize mirrors Redis' proto-max-bulk-len default of 512MB.
+const MaxStringSize = 512 << 20
+
+var (
+	ErrNotInteger     = errors.New("ERR value is not an integer or out of range")
+	ErrNotFloat       = errors.New("ERR value is not a valid float")
+	ErrStringTooLarge = errors.New("ERR string exceeds maximum allowed size (proto-max-bulk-len)")
+)
+
+// String Datastructure
+
+// str returns the value of a string item.
+func (i *item) str() string {
+	if i.bitmap != nil {
+		return string(i.bitmap)
+	}
+	return i.StringValue
+}
+
+// strLen returns the length of a string item, 0 for a nil one.
+func (i *item) strLen() int {
+	if i == nil {
+		return 0
+	}
+	if i.bitmap != nil {
+		return len(i.bitmap)
+	}
+	return len(i.StringValue)
+}
+
+// byteAt returns byte n of a string item, 0 past its end or for a nil item.
+func (i *item) byteAt(n int) byte {
+	switch {
+	case n >= i.strLen():
+		return 0
+	case i.bitmap != nil:
+		return i.bitmap[n]
+	}
+	return i.StringValue[n]
+}
+
+// setStr replaces the value of a string item.
+func (i *item) setStr(s string) {
+	i.StringValue, i.bitmap = s, nil
+}
+
+// bytes returns the value of a string item as a byte slice, zero-padded to
+// at least n bytes, that stays the item's value: changes to it change the
+// item in place. Callers must hold d.mu for writing.
+func (i *item) bytes(n int) []byte {
+	if i.bitmap == nil {
+		i.bitmap = append([]byte{}, i.StringValue...)
+		i.StringValue = ""
+	}
+	if n > len(i.bitmap) {
+		i.bitmap = append(i.bitmap, make([]byte, n-len(i.bitmap))...)
+	}
+	return i.bitmap
+}
+
+// stringAt returns the string item stored at key, or nil if there is none.
+// Callers must hold d.mu.
+func (d *DB) stringAt(key string) (*item, error) {
+	itm := d.lookup(key)
+	if itm == nil {
+		return nil, nil
+	}
+	if itm.Type != StringType {
+		return nil, ErrWrongType
+	}
+	return itm, nil
+}
+
+// stringForWrite returns the string item at key, creating an empty one when
+// the key does not exist. Callers must hold d.mu for writing.
+func (d *DB) stringForWrite(key string) (*item, error) {
+	itm, err := d.stringAt(key)
+	if err != nil {
+		return nil, err
+	}
+	if itm == nil {
+		itm = &item{Type: StringType}
+		d.setItem(key, itm)
+		d.notify(notifyNew, "new", key)
+	}
+	return itm, nil
+}
+
+// IncrBy adds delta to the integer stored at key, keeping its TTL.
+func (d *DB) IncrBy(key string, delta int64) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	itm, err := d.stringAt(key)
+	if err != nil {
+		return 0, err
+	}
+
+	var cur int64
+	if itm != nil {
+		cur, err = strconv.ParseInt(itm.str(), 10, 64)
+		if err != nil {
+			return 0, ErrNotInteger
+		}
+	}
+
+	if (delta > 0 && cur > math.MaxInt64-delta) || (delta < 0 && cur < math.MinInt64-delta) {
+		return 0, ErrOverflow
+	}
+
+	cur += delta
+	itm, _ = d.stringForWrite(key)
+	itm.setStr(strconv.FormatInt(cur, 10))
+
+	d.modified(key)
+	d.notify(notifyString, "incrby", key)
+	return cur, nil
+}
+
+// IncrByFloat adds delta to the number stored at key, keeping its TTL.
+func (d *DB) IncrByFloat(key string, delta float64) (string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	itm, err := d.stringAt(key)
+	if err != nil {
+		return "", err
+	}
+
+	var cur float64
+	if itm != nil {
+		cur, err = strconv.ParseFloat(itm.str(), 64)
+		if err != nil || math.IsNaN(cur) || math.IsInf(cur, 0) {
+			return "", ErrNotFloat
+		}
+	}
+
+	cur += delta
+	if math.IsNaN(cur) || math.IsInf(cur, 0) {
+		return "", ErrNaN
+	}
+
+	itm, _ = d.stringForWrite(key)
+	val := strconv.FormatFloat(cur, 'f', -1, 64)
+	itm.setStr(val)
+
+	d.modified(key)
+	d.notify(notifyString, "incrbyfloat", key)
+	return val, nil
+}
+
+func (d *DB) Append(key, val string) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	itm, err := d.stringAt(key)
+	if err != nil {
+		return 0, err
+	}
+	if itm != nil && itm.strLen()+len(val) > MaxStringSize {
+		return 0, ErrStringTooLarge
+	}
+
+	itm, _ = d.stringForWrite(key)
+	if itm.bitmap != nil {
+		itm.bitmap = append(itm.bitmap, val...)
+	} else {
+		itm.StringValue += val
+	}
+
+	d.modified(key)
+	d.notify(notifyString, "append", key)
+	return itm.strLen(), nil
+}
+
+func (d *DB) StrLen(key string) (int, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	itm, err := d.stringAt(key)
+	if err != nil || itm == nil {
+		return 0, err
+	}
+	return itm.strLen(), nil
+}
+
+// GetRange returns the substring between the inclusive offsets start and
+// end. Negative offsets count from the end of the string.
+func (d *DB) GetRange(key string, start, end int) (string, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	itm, err := d.stringAt(key)
+	if err != nil || itm == nil {
+		return "", err
+	}
+
+	s := itm.str()
+	if start < 0 && end < 0 && start > end {
+		return "", nil
+	}
+	if start < 0 {
+		start = max(len(s)+start, 0)
+	}
+	if end < 0 {
+		end = max(len(s)+end, 0)
+	}
+	if end >= len(s) {
+		end = len(s) - 1
+	}
+	if start > end || len(s) == 0 {
+		return "", nil
+	}
+	return s[start : end+1], nil
+}
+
+// SetRange overwrites part of the string at key starting at offset, padding
+// with zero bytes when offset lies past the end. It returns the new length.
+func (d *DB) SetRange(key string, offset int, val string) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	itm, err := d.stringAt(key)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(val) == 0 {
+		if itm == nil {
+			return 0, nil
+		}
+		return itm.strLen(), nil
+	}
+	if offset > MaxStringSize-len(val) {
+		return 0, ErrStringTooLarge
+	}
+
+	itm, _ = d.stringForWrite(key)
+	copy(itm.bytes(offset + len(val))[offset:], val)
+
+	d.modified(key)
+	d.notify(notifyString, "setrange", key)
+	return itm.strLen(), nil
+}
+
+func (d *DB) GetDel(key string) (string, bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	itm, err := d.stringAt(key)
+	if err != nil || itm == nil {
+		return "", false, err
+	}
+
+	d.deleteItem(key)
+	d.modified(key)
+	d.notify(notifyGeneric, "del", key)
+	return itm.str(), true, nil
+}
+
+// GetEx returns the string at key and updates its expiry: a non-zero
+// expiresAt sets it, persist removes it, otherwise the TTL is left alone. A
+// deadline that already passed deletes the key.
+func (d *DB) GetEx(key string, expiresAt time.Time, persist bool) (string, bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	itm, err := d.stringAt(key)
+	if err != nil || itm == nil {
+		return "", false, err
+	}
+
+	switch {
+	case persist:
+		if !itm.ExpiresAt.IsZero() {
+			itm.ExpiresAt = time.Time{}
+			d.modified(key)
+			d.notify(notifyGeneric, "persist", key)
+		}
+	case !expiresAt.IsZero():
+		if !expiresAt.After(time.Now()) {
+			d.deleteItem(key)
+			d.modified(key)
+			d.notify(notifyGeneric, "del", key)
+		} else {
+			itm.ExpiresAt = expiresAt
+			d.modified(key)
+			d.notify(notifyGeneric, "expire", key)
+		}
+	}
+	return itm.str(), true, nil
+}
+
+// GetSet stores val at key, discarding any TTL, and returns the old value.
+func (d *DB) GetSet(key, val string) (string, bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	itm, err := d.stringAt(key)
+	if err != nil {
+		return "", false, err
+	}
+
+	if itm == nil {
+		d.notify(notifyNew, "new", key)
+	}
+	d.setItem(key, &item{Type: StringType, StringValue: val})
+	d.modified(key)
+	d.notify(notifyString, "set", key)
+
+	if itm == nil {
+		return "", false, nil
+	}
+	return itm.str(), true, nil
+}
+
+// MGet returns the values stored at keys. Keys that are missing or do not
+// hold a string report ok[i] == false.
+func (d *DB) MGet(keys ...string) (vals []string, ok []bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	vals = make([]string, len(keys))
+	ok = make([]bool, len(keys))
+	for i, k := range keys {
+		if itm, _ := d.stringAt(k); itm != nil {
+			vals[i], ok[i] = itm.str(), true
+		}
+	}
+	return vals, ok
+}
+
+// MSet sets every key/value pair atomically.
+func (d *DB) MSet(pairs ...string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if d.lookup(pairs[i]) == nil {
+			d.notify(notifyNew, "new", pairs[i])
+		}
+		d.setItem(pairs[i], &item{Type: StringType, StringValue: pairs[i+1]})
+		d.modified(pairs[i])
+		d.notify(notifyString, "set", pairs[i])
+	}
+}
+
+// MSetNX sets the pairs only if none of the keys exist.
+func (d *DB) MSetNX(pairs ...string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if d.lookup(pairs[i]) != nil {
+			return false
+		}
+	}
+
+	for i := 0; i+1 < len(pairs); i += 2 {
+		d.notify(notifyNew, "new", pairs[i])
+		d.setItem(pairs[i], &item{Type: StringType, StringValue: pairs[i+1]})
+		d.modified(pairs[i])
+		d.notify(notifyString, "set", pairs[i])
+	}
+	return true
+}
+
+func (d *DB) SetNX(key, val string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.lookup(key) != nil {
+		return false
+	}
+
+	d.notify(notifyNew, "new", key)
+	d.setItem(key, &item{Type: StringType, StringValue: val})
+	d.modified(key)
+	d.notify(notifyString, "set", key)
+	return true
+}
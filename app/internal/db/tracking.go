@@ -0,0 +1,253 @@
+package db
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// Client side caching. A tracking client is told when keys it may have
+// cached change. In the default mode the DB remembers which keys each client
+// read and invalidates each key once, on its next change; in broadcast mode
+// the client hears about every change to keys under its prefixes.
+//
+// Invalidations are delivered as pub/sub messages on InvalidateChannel to
+// the client's target subscriber, which is the client itself or the client
+// it redirects to. RESP2 has no out of band replies, so as in Redis the
+// target only receives them while it has subscriptions.
+
+// InvalidateChannel is the channel invalidation messages are sent on.
+const InvalidateChannel = "__redis__:invalidate"
+
+// Tracker is the client side caching state of one client. Its fields are
+// guarded by d.trackMu.
+type Tracker struct {
+	on       bool
+	target   *Subscriber
+	bcast    bool
+	prefixes []string
+	noloop   bool
+
+	// keys holds the keys t is recorded for in d.tracked.
+	keys map[string]struct{}
+
+	// reads holds the keys the client's current command reads. They stay
+	// tracked when they change during the command, as the client may be
+	// about to cache a value read before the change.
+	reads map[string]struct{}
+
+	// ownWrites holds the keys the client's current command writes, which
+	// noloop trackers are not told about. Writes by other clients to the
+	// same keys during the command are attributed to it as well.
+	ownWrites map[string]struct{}
+}
+
+// TrackingOptions are the CLIENT TRACKING options the DB needs. Read
+// selection with OPTIN and OPTOUT is up to the caller of TrackKeys.
+type TrackingOptions struct {
+	Target   *Subscriber
+	BCast    bool
+	Prefixes []string
+	NoLoop   bool
+}
+
+func (d *DB) NewTracker() *Tracker {
+	return &Tracker{}
+}
+
+// EnableTracking turns tracking on for t, or changes its options if it is
+// already on. Broadcast prefixes are added to those already registered.
+func (d *DB) EnableTracking(t *Tracker, opts TrackingOptions) {
+	d.trackMu.Lock()
+	defer d.trackMu.Unlock()
+
+	prefixes := slices.Clone(opts.Prefixes)
+	if t.on && t.bcast {
+		prefixes = append(t.prefixes, prefixes...)
+	}
+	t.on, t.target, t.bcast, t.prefixes, t.noloop = true, opts.Target, opts.BCast, prefixes, opts.NoLoop
+
+	if d.trackers == nil {
+		d.trackers = make(map[*Tracker]struct{})
+	}
+	d.trackers[t] = struct{}{}
+}
+
+// DisableTracking turns tracking off for t and forgets the keys it read.
+func (d *DB) DisableTracking(t *Tracker) {
+	d.trackMu.Lock()
+	defer d.trackMu.Unlock()
+
+	d.untrack(t)
+	*t = Tracker{}
+	delete(d.trackers, t)
+}
+
+// BeginCommand records the keys t's client is about to read and write. In
+// the default mode the read keys are tracked before they are read, so no
+// change after the read goes unreported.
+func (d *DB) BeginCommand(t *Tracker, reads, writes []string) {
+	d.trackMu.Lock()
+	defer d.trackMu.Unlock()
+
+	if !t.on {
+		return
+	}
+	if len(reads) > 0 && !t.bcast {
+		t.reads = make(map[string]struct{}, len(reads))
+		for _, key := range reads {
+			d.track(t, key)
+			t.reads[key] = struct{}{}
+		}
+	}
+	if len(writes) > 0 && t.noloop {
+		t.ownWrites = make(map[string]struct{}, len(writes))
+		for _, key := range writes {
+			t.ownWrites[key] = struct{}{}
+		}
+	}
+}
+
+// EndCommand clears what BeginCommand recorded for the command.
+func (d *DB) EndCommand(t *Tracker) {
+	d.trackMu.Lock()
+	defer d.trackMu.Unlock()
+
+	t.reads, t.ownWrites = nil, nil
+}
+
+// track records that t read key. Callers must hold d.trackMu.
+func (d *DB) track(t *Tracker, key string) {
+	if d.tracked == nil {
+		d.tracked = make(map[string]map[*Tracker]struct{})
+	}
+	if d.tracked[key] == nil {
+		d.tracked[key] = make(map[*Tracker]struct{})
+	}
+	d.tracked[key][t] = struct{}{}
+
+	if t.keys == nil {
+		t.keys = make(map[string]struct{})
+	}
+	t.keys[key] = struct{}{}
+}
+
+// untrack removes t from the keys it is tracked for. Callers must hold
+// d.trackMu.
+func (d *DB) untrack(t *Tracker) {
+	for key := range t.keys {
+		delete(d.tracked[key], t)
+		if len(d.tracked[key]) == 0 {
+			delete(d.tracked, key)
+		}
+	}
+	t.keys = nil
+}
+
+// modified marks the DB dirty, stamps the items at keys with a new version
+// and invalidates keys. Callers must hold d.mu for writing.
+func (d *DB) modified(keys ...string) {
+	d.dirty = true
+	d.version++
+	for _, key := range keys {
+		if itm, ok := d.store[key]; ok {
+			itm.version = d.version
+		}
+	}
+	d.invalidate(keys...)
+}
+
+// invalidate tells the clients tracking keys that they changed.
+func (d *DB) invalidate(keys ...string) {
+	d.trackMu.Lock()
+	defer d.trackMu.Unlock()
+
+	if len(d.trackers) == 0 {
+		return
+	}
+
+	var pending map[*Tracker][]string
+	add := func(t *Tracker, key string) {
+		if !t.on || t.skips(key) {
+			return
+		}
+		if pending == nil {
+			pending = make(map[*Tracker][]string)
+		}
+		pending[t] = append(pending[t], key)
+	}
+
+	for _, key := range keys {
+		for t := range d.tracked[key] {
+			add(t, key)
+			if _, reading := t.reads[key]; !reading {
+				delete(d.tracked[key], t)
+				delete(t.keys, key)
+			}
+		}
+		if len(d.tracked[key]) == 0 {
+			delete(d.tracked, key)
+		}
+
+		for t := range d.trackers {
+			if t.bcast && t.matches(key) {
+				add(t, key)
+			}
+		}
+	}
+
+	for t, keys := range pending {
+		d.sendInvalidation(t, keys)
+	}
+}
+
+// invalidateAll tells every tracking client that all keys changed, with a
+// null key list.
+func (d *DB) invalidateAll() {
+	d.trackMu.Lock()
+	defer d.trackMu.Unlock()
+
+	for t := range d.trackers {
+		d.sendInvalidation(t, nil)
+		d.untrack(t)
+		for key := range t.reads {
+			d.track(t, key)
+		}
+	}
+}
+
+// skips reports whether a noloop tracker's own client is writing key.
+func (t *Tracker) skips(key string) bool {
+	if !t.noloop {
+		return false
+	}
+	_, ok := t.ownWrites[key]
+	return ok
+}
+
+// matches reports whether key falls under one of a broadcast tracker's
+// prefixes. No prefixes match every key.
+func (t *Tracker) matches(key string) bool {
+	if len(t.prefixes) == 0 {
+		return true
+	}
+	for _, p := range t.prefixes {
+		if strings.HasPrefix(key, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// sendInvalidation queues an invalidation of keys for t's target. Callers
+// must hold d.trackMu.
+func (d *DB) sendInvalidation(t *Tracker, keys []string) {
+	d.psMu.RLock()
+	defer d.psMu.RUnlock()
+
+	s := t.target
+	if s == nil || s.count()+len(s.shards) == 0 {
+		return
+	}
+	d.deliver(s, Message{Channel: InvalidateChannel, Keys: keys, Invalidate: true}, time.Now())
+}
@@ -0,0 +1,103 @@
+package db
+
+import (
+	"slices"
+	"testing"
+)
+
+// newTrackingClient returns a default mode tracker whose invalidations are
+// delivered to a subscriber listening on InvalidateChannel.
+func newTrackingClient(d *DB) (*Tracker, *Subscriber) {
+	s := d.NewSubscriber()
+	d.Subscribe(s, InvalidateChannel)
+	t := d.NewTracker()
+	d.EnableTracking(t, TrackingOptions{Target: s})
+	return t, s
+}
+
+// read tracks keys the way a read command does.
+func read(d *DB, t *Tracker, keys ...string) {
+	d.BeginCommand(t, keys, nil)
+	d.EndCommand(t)
+}
+
+func invalidated(s *Subscriber) []string {
+	if n, _ := s.Pending(); n == 0 {
+		return nil
+	}
+	msgs, _ := s.Receive()
+	var keys []string
+	for _, m := range msgs {
+		keys = append(keys, m.Keys...)
+	}
+	return keys
+}
+
+func TestTrackingInvalidatesOnce(t *testing.T) {
+	d := New()
+	tr, s := newTrackingClient(d)
+
+	read(d, tr, "k")
+	d.Set("k", "1", 0)
+	if got := invalidated(s); !slices.Equal(got, []string{"k"}) {
+		t.Fatalf("invalidated %q, want [k]", got)
+	}
+	d.Set("k", "2", 0)
+	if got := invalidated(s); got != nil {
+		t.Fatalf("invalidated %q after the key was forgotten", got)
+	}
+}
+
+func TestTrackingWriteDuringRead(t *testing.T) {
+	d := New()
+	tr, s := newTrackingClient(d)
+
+	// another client changes the key while the read command runs
+	d.BeginCommand(tr, []string{"k"}, nil)
+	d.Set("k", "1", 0)
+	d.EndCommand(tr)
+	if got := invalidated(s); !slices.Equal(got, []string{"k"}) {
+		t.Fatalf("invalidated %q, want [k]", got)
+	}
+
+	// the value read may predate that change, so the key stays tracked
+	d.Set("k", "2", 0)
+	if got := invalidated(s); !slices.Equal(got, []string{"k"}) {
+		t.Fatalf("invalidated %q after the read, want [k]", got)
+	}
+}
+
+func TestDisableTrackingForgetsKeys(t *testing.T) {
+	d := New()
+	tr, _ := newTrackingClient(d)
+
+	read(d, tr, "a", "b")
+	d.DisableTracking(tr)
+	if len(d.tracked) != 0 {
+		t.Fatalf("%d keys still tracked after DisableTracking", len(d.tracked))
+	}
+
+	other, _ := newTrackingClient(d)
+	read(d, other, "a")
+	d.EnableTracking(tr, TrackingOptions{})
+	read(d, tr, "a")
+	d.DisableTracking(tr)
+	if len(d.tracked["a"]) != 1 {
+		t.Fatalf("a is tracked by %d clients, want 1", len(d.tracked["a"]))
+	}
+}
+
+func TestFlushInvalidatesAll(t *testing.T) {
+	d := New()
+	tr, s := newTrackingClient(d)
+
+	read(d, tr, "k")
+	d.Flush()
+	msgs, _ := s.Receive()
+	if len(msgs) != 1 || msgs[0].Keys != nil {
+		t.Fatalf("got %+v, want one invalidation of all keys", msgs)
+	}
+	if len(d.tracked) != 0 || len(tr.keys) != 0 {
+		t.Fatalf("keys still tracked after FLUSHALL")
+	}
+}
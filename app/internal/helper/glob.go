@@ -0,0 +1,109 @@
+package helper
+
+import "unicode"
+
+// Match reports whether s matches the glob-style pattern used by KEYS, SCAN
+// MATCH and CONFIG GET. '*' matches any sequence, '?' any single character,
+// [abc] one of a set of characters ([^abc] negates it, [a-z] is a range) and
+// a backslash quotes the next character.
+func Match(pattern, s string, nocase bool) bool {
+	return match([]byte(pattern), []byte(s), nocase)
+}
+
+// match backtracks only to the most recent '*', so it runs in
+// O(len(p)*len(s)) however many stars the pattern has: a later star can
+// always absorb whatever an earlier one would have matched.
+func match(p, s []byte, nocase bool) bool {
+	pi, si := 0, 0
+	star, starS := -1, 0 // pattern index after the last '*' and where it started matching in s
+	for {
+		if pi < len(p) && p[pi] == '*' {
+			for pi < len(p) && p[pi] == '*' {
+				pi++
+			}
+			if pi == len(p) {
+				return true
+			}
+			star, starS = pi, si
+			continue
+		}
+		if pi < len(p) && si < len(s) {
+			if n, ok := matchOne(p[pi:], s[si], nocase); ok {
+				pi, si = pi+n, si+1
+				continue
+			}
+		}
+		if pi == len(p) && si == len(s) {
+			return true
+		}
+		// let the last star swallow one more character and retry
+		if star < 0 || starS >= len(s) {
+			return false
+		}
+		starS++
+		pi, si = star, starS
+	}
+}
+
+// matchOne matches c against the single-character token at the start of p,
+// which is not '*'. It returns the length of the token.
+func matchOne(p []byte, c byte, nocase bool) (int, bool) {
+	switch p[0] {
+	case '?':
+		return 1, true
+
+	case '[':
+		i := 1
+		not := i < len(p) && p[i] == '^'
+		if not {
+			i++
+		}
+
+		matched := false
+		for i < len(p) && p[i] != ']' {
+			switch {
+			case p[i] == '\\' && i+1 < len(p):
+				i++
+				if equalByte(p[i], c, nocase) {
+					matched = true
+				}
+			case i+2 < len(p) && p[i+1] == '-':
+				lo, hi, c := p[i], p[i+2], c
+				if lo > hi {
+					lo, hi = hi, lo
+				}
+				if nocase {
+					lo, hi, c = lower(lo), lower(hi), lower(c)
+				}
+				if c >= lo && c <= hi {
+					matched = true
+				}
+				i += 2
+			default:
+				if equalByte(p[i], c, nocase) {
+					matched = true
+				}
+			}
+			i++
+		}
+		// an unterminated class ends with the pattern
+		return min(i+1, len(p)), matched != not
+
+	case '\\':
+		if len(p) >= 2 {
+			return 2, equalByte(p[1], c, nocase)
+		}
+	}
+	return 1, equalByte(p[0], c, nocase)
+}
+
+func lower(c byte) byte {
+	return byte(unicode.ToLower(rune(c)))
+}
+
+func equalByte(a, b byte, nocase bool) bool {
+	if nocase {
+		return lower(a) == lower(b)
+	}
+	return a == b
+}
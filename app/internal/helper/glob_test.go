@@ -0,0 +1,58 @@
+package helper
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern, s string
+		nocase     bool
+		want       bool
+	}{
+		{"*", "", false, true},
+		{"*", "anything", false, true},
+		{"h?llo", "hello", false, true},
+		{"h?llo", "hllo", false, false},
+		{"h*llo", "heeeello", false, true},
+		{"h*llo", "hellox", false, false},
+		{"h[ae]llo", "hallo", false, true},
+		{"h[ae]llo", "hillo", false, false},
+		{"h[^e]llo", "hallo", false, true},
+		{"h[^e]llo", "hello", false, false},
+		{"h[a-b]llo", "hbllo", false, true},
+		{"h[b-a]llo", "hbllo", false, true},
+		{"h[a-b]llo", "hcllo", false, false},
+		{"h[a", "ha", false, true},
+		{`h\*llo`, "h*llo", false, true},
+		{`h\*llo`, "hello", false, false},
+		{`[\]]`, "]", false, true},
+		{`a\`, `a\`, false, true},
+		{"HELLO", "hello", true, true},
+		{"H[A-C]*", "hbx", true, true},
+		{"*a*b*c", "xxaxxbxxc", false, true},
+		{"*a*b*c", "xxaxxcxxb", false, false},
+		{"a*", "", false, false},
+		{"user:*:name", "user:1:2:name", false, true},
+	}
+	for _, tt := range tests {
+		if got := Match(tt.pattern, tt.s, tt.nocase); got != tt.want {
+			t.Errorf("Match(%q, %q, %v) = %v, want %v", tt.pattern, tt.s, tt.nocase, got, tt.want)
+		}
+	}
+}
+
+func TestMatchManyStars(t *testing.T) {
+	pattern := strings.Repeat("*a", 12) + "b"
+	s := strings.Repeat("a", 60)
+
+	start := time.Now()
+	if Match(pattern, s, false) {
+		t.Errorf("Match(%q, %q) = true, want false", pattern, s)
+	}
+	if d := time.Since(start); d > 100*time.Millisecond {
+		t.Errorf("matching took %v", d)
+	}
+}
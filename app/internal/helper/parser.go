@@ -49,6 +49,40 @@ func ParseCommand(arr []string) (string, []string, time.Duration, error) {
 		// Return structured args: [key, value]
 		return cmd, []string{key, value}, ttl, nil
 
+	case "INCR", "DECR", "STRLEN", "GETDEL", "GETEX", "MGET",
+		"BITCOUNT", "BITFIELD", "BITFIELD_RO", "PFADD", "PFCOUNT", "PFMERGE":
+		// Expected format: INCR key (e.g., MGET foo bar)
+		if len(args) < 1 {
+			return "", nil, 0, fmt.Errorf("error: %s requires a key", cmd)
+		}
+
+		return cmd, args, 0, nil
+
+	case "INCRBY", "DECRBY", "INCRBYFLOAT", "APPEND", "GETSET", "SETNX",
+		"GETBIT", "BITPOS":
+		// Expected format: INCRBY key increment (e.g., APPEND foo bar)
+		if len(args) < 2 {
+			return "", nil, 0, fmt.Errorf("error: %s requires key and value", cmd)
+		}
+
+		return cmd, args, 0, nil
+
+	case "MSET", "MSETNX":
+		// Expected format: MSET key value [key value ...]
+		if len(args) < 2 || len(args)%2 != 0 {
+			return "", nil, 0, fmt.Errorf("error: %s requires key/value pairs", cmd)
+		}
+
+		return cmd, args, 0, nil
+
+	case "GETRANGE", "SETRANGE", "SETEX", "PSETEX", "SETBIT", "BITOP":
+		// Expected format: GETRANGE key start end (e.g., SETEX foo 10 bar)
+		if len(args) < 3 {
+			return "", nil, 0, fmt.Errorf("error: %s requires three arguments", cmd)
+		}
+
+		return cmd, args, 0, nil
+
 	case "LPUSH":
 		// Expected format: LPUSH key value (e.g., LPUSH foo bar)
 		if len(args) < 2 {
@@ -115,6 +149,23 @@ func ParseCommand(arr []string) (string, []string, time.Duration, error) {
 
 		return cmd, argvals, 0, nil
 
+	case "SREM", "SISMEMBER", "SMISMEMBER", "SMOVE", "SINTERCARD",
+		"SINTERSTORE", "SUNIONSTORE", "SDIFFSTORE":
+		// Expected format: SREM key member [member ...] and friends
+		if len(args) < 2 {
+			return "", nil, 0, fmt.Errorf("error: %s requires at least two arguments", cmd)
+		}
+
+		return cmd, args, 0, nil
+
+	case "SCARD", "SPOP", "SRANDMEMBER", "SINTER", "SUNION", "SDIFF":
+		// Expected format: SCARD key, SPOP key [count], SINTER key [key ...]
+		if len(args) < 1 {
+			return "", nil, 0, fmt.Errorf("error: %s requires key", cmd)
+		}
+
+		return cmd, args, 0, nil
+
 	case "SMEMBERS":
 		// Expected format: SMEMBERS key
 		if len(args) < 1 {
@@ -137,16 +188,45 @@ func ParseCommand(arr []string) (string, []string, time.Duration, error) {
 		return cmd, []string{key, field}, 0, nil
 
 	case "HSET":
-		// Expected format: HSET key field value
+		// Expected format: HSET key field value [field value ...]
+		if len(args) < 3 || len(args)%2 == 0 {
+			return "", nil, 0, fmt.Errorf("error: HSET requires key and field/value pairs")
+		}
+
+		return cmd, args, 0, nil
+
+	case "HSETNX", "HINCRBY", "HINCRBYFLOAT":
+		// Expected format: HSETNX key field value (e.g., HINCRBY foo hits 1)
 		if len(args) < 3 {
-			return "", nil, 0, fmt.Errorf("error: HSET requires key, field, and value")
+			return "", nil, 0, fmt.Errorf("error: %s requires key, field, and value", cmd)
 		}
 
-		key := args[0]
-		field := args[1]
-		value := args[2]
+		return cmd, args, 0, nil
+
+	case "HDEL", "HEXISTS", "HMGET", "HSTRLEN":
+		// Expected format: HDEL key field [field ...]
+		if len(args) < 2 {
+			return "", nil, 0, fmt.Errorf("error: %s requires key and field", cmd)
+		}
+
+		return cmd, args, 0, nil
+
+	case "HEXPIRE", "HPEXPIRE", "HEXPIREAT", "HPEXPIREAT", "HTTL", "HPTTL",
+		"HEXPIRETIME", "HPEXPIRETIME", "HPERSIST", "HGETEX", "HSETEX":
+		// Expected format: HEXPIRE key seconds FIELDS numfields field [field ...]
+		if len(args) < 3 {
+			return "", nil, 0, fmt.Errorf("error: %s requires key and FIELDS", cmd)
+		}
+
+		return cmd, args, 0, nil
 
-		return cmd, []string{key, field, value}, 0, nil
+	case "HLEN", "HKEYS", "HVALS", "HRANDFIELD":
+		// Expected format: HLEN key
+		if len(args) < 1 {
+			return "", nil, 0, fmt.Errorf("error: %s requires key", cmd)
+		}
+
+		return cmd, args, 0, nil
 
 	case "HGETALL":
 		// Expected format: HGETALL key
@@ -158,8 +238,75 @@ func ParseCommand(arr []string) (string, []string, time.Duration, error) {
 
 		return cmd, []string{key}, 0, nil
 
-	case "GET", "DEL":
-		// Expected format: GET key (or DEL key)
+	case "GEOPOS", "GEOHASH":
+		// Expected format: GEOPOS key [member ...]
+		if len(args) < 1 {
+			return "", nil, 0, fmt.Errorf("error: %s requires key", cmd)
+		}
+
+		return cmd, args, 0, nil
+
+	case "GEOADD", "GEODIST", "GEOSEARCH", "GEOSEARCHSTORE":
+		// Expected format: GEOADD key longitude latitude member [longitude latitude member ...]
+		if len(args) < 3 {
+			return "", nil, 0, fmt.Errorf("error: %s requires at least three arguments", cmd)
+		}
+
+		return cmd, args, 0, nil
+
+	case "DEL", "UNLINK", "EXISTS", "TOUCH", "TYPE":
+		// Expected format: DEL key [key ...]
+		if len(args) < 1 {
+			return "", nil, 0, fmt.Errorf("error: %s requires a key (e.g., %s foo)", cmd, cmd)
+		}
+
+		return cmd, args, 0, nil
+
+	case "RENAME", "RENAMENX", "COPY":
+		// Expected format: RENAME key newkey (e.g., COPY foo bar REPLACE)
+		if len(args) < 2 {
+			return "", nil, 0, fmt.Errorf("error: %s requires key and newkey", cmd)
+		}
+
+		return cmd, args, 0, nil
+
+	case "SCAN", "KEYS":
+		// Expected format: SCAN cursor [MATCH pattern] [COUNT count] [TYPE type]
+		if len(args) < 1 {
+			return "", nil, 0, fmt.Errorf("error: %s requires an argument", cmd)
+		}
+
+		return cmd, args, 0, nil
+
+	case "SSCAN", "HSCAN", "ZSCAN":
+		// Expected format: SSCAN key cursor [MATCH pattern] [COUNT count]
+		if len(args) < 2 {
+			return "", nil, 0, fmt.Errorf("error: %s requires key and cursor", cmd)
+		}
+
+		return cmd, args, 0, nil
+
+	case "SORT", "SORT_RO":
+		// Expected format: SORT key [BY pattern] [LIMIT offset count] [GET pattern ...] [ASC | DESC] [ALPHA] [STORE destination]
+		if len(args) < 1 {
+			return "", nil, 0, fmt.Errorf("error: %s requires a key", cmd)
+		}
+
+		return cmd, args, 0, nil
+
+	case "DUMP", "RESTORE", "MIGRATE":
+		// Expected format: RESTORE key ttl serialized-value [REPLACE] (e.g., DUMP foo)
+		if len(args) < 1 {
+			return "", nil, 0, fmt.Errorf("error: %s requires a key", cmd)
+		}
+
+		return cmd, args, 0, nil
+
+	case "DBSIZE", "RANDOMKEY", "INFO":
+		return cmd, args, 0, nil
+
+	case "GET":
+		// Expected format: GET key
 		if len(args) < 1 {
 			return "", nil, 0, fmt.Errorf("error: %s requires a key (e.g., %s foo)", cmd, cmd)
 		}
@@ -169,28 +316,68 @@ func ParseCommand(arr []string) (string, []string, time.Duration, error) {
 		// Return structured args: [key]
 		return cmd, []string{key}, 0, nil // TTL is irrelevant, so 0
 
-	case "FLUSHALL":
+	case "OBJECT", "CONFIG", "PUBSUB", "ACL", "CLIENT":
+		// Expected format: OBJECT subcommand [arguments ...]
+		if len(args) < 1 {
+			return "", nil, 0, fmt.Errorf("error: %s requires a subcommand", cmd)
+		}
+
+		return cmd, args, 0, nil
+
+	case "FLUSHALL", "QUIT", "RESET":
+		return cmd, args, 0, nil
+
+	case "AUTH":
+		// Expected format: AUTH [username] password
+		if len(args) < 1 || len(args) > 2 {
+			return "", nil, 0, fmt.Errorf("error: AUTH requires [username] password")
+		}
+
 		return cmd, args, 0, nil
 
 	case "SUBSCRIBE":
-		// Expected format: SUBSCRIBE channel
+		// Expected format: SUBSCRIBE channel [channel ...]
 		if len(args) < 1 {
 			return "", nil, 0, fmt.Errorf("error: SUBSCRIBE requires channel")
 		}
 
-		channel := args[0]
-
-		return cmd, []string{channel}, 0, nil
+		return cmd, args, 0, nil
 
 	case "UNSUBSCRIBE":
-		// Expected format: UNSUBSCRIBE channel
+		// Expected format: UNSUBSCRIBE [channel ...]
+		return cmd, args, 0, nil
+
+	case "PSUBSCRIBE":
+		// Expected format: PSUBSCRIBE pattern [pattern ...]
 		if len(args) < 1 {
-			return "", nil, 0, fmt.Errorf("error: UNSUBSCRIBE requires channel")
+			return "", nil, 0, fmt.Errorf("error: PSUBSCRIBE requires pattern")
 		}
 
-		channel := args[0]
+		return cmd, args, 0, nil
+
+	case "PUNSUBSCRIBE":
+		// Expected format: PUNSUBSCRIBE [pattern ...]
+		return cmd, args, 0, nil
+
+	case "SSUBSCRIBE":
+		// Expected format: SSUBSCRIBE shardchannel [shardchannel ...]
+		if len(args) < 1 {
+			return "", nil, 0, fmt.Errorf("error: SSUBSCRIBE requires shard channel")
+		}
+
+		return cmd, args, 0, nil
+
+	case "SUNSUBSCRIBE":
+		// Expected format: SUNSUBSCRIBE [shardchannel ...]
+		return cmd, args, 0, nil
+
+	case "SPUBLISH":
+		// Expected format: SPUBLISH shardchannel message
+		if len(args) < 2 {
+			return "", nil, 0, fmt.Errorf("error: SPUBLISH requires shard channel and message")
+		}
 
-		return cmd, []string{channel}, 0, nil
+		return cmd, args[:2], 0, nil
 
 	case "PUBLISH":
 		// Expected format: PUBLISH channel message
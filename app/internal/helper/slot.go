@@ -0,0 +1,34 @@
+package helper
+
+import "strings"
+
+// SlotCount is the number of hash slots keys and shard channels map to.
+const SlotCount = 16384
+
+// KeySlot returns the hash slot of key: the CRC16 of the key modulo
+// SlotCount. If the key contains a non-empty {hash tag}, only the tag is
+// hashed, so related keys can be kept in the same slot.
+func KeySlot(key string) int {
+	if open := strings.IndexByte(key, '{'); open >= 0 {
+		if end := strings.IndexByte(key[open+1:], '}'); end > 0 {
+			key = key[open+1 : open+1+end]
+		}
+	}
+	return int(crc16(key)) & (SlotCount - 1)
+}
+
+// crc16 is CRC-16/XMODEM (polynomial 0x1021, initial value 0).
+func crc16(s string) uint16 {
+	var crc uint16
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for range 8 {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"strconv"
+	"strings"
 )
 
 // *2\r\n$3\r\nGET\r\n$3\r\nkey\r\n
@@ -44,3 +45,124 @@ func ReadArray(r *bufio.Reader) ([]string, error) {
 
 	return result, nil
 }
+
+// Reply is a decoded server reply, as read by clients of other instances.
+// Kind is the RESP type byte; Str holds simple strings, errors, integers and
+// bulk strings, and Elems the elements of an array. Null marks null bulk
+// strings and arrays.
+type Reply struct {
+	Kind  byte
+	Str   string
+	Null  bool
+	Elems []Reply
+}
+
+func (r Reply) IsError() bool {
+	return r.Kind == '-'
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), nil
+}
+
+// ReadReply reads one reply of any type.
+func ReadReply(r *bufio.Reader) (Reply, error) {
+	prefix, err := r.ReadByte()
+	if err != nil {
+		return Reply{}, err
+	}
+	line, err := readLine(r)
+	if err != nil {
+		return Reply{}, err
+	}
+
+	reply := Reply{Kind: prefix}
+	switch prefix {
+	case '+', '-', ':':
+		reply.Str = line
+	case '$':
+		n, err := strconv.Atoi(line)
+		if err != nil {
+			return Reply{}, fmt.Errorf("invalid bulk length %q", line)
+		}
+		if n < 0 {
+			reply.Null = true
+			break
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return Reply{}, err
+		}
+		reply.Str = string(data[:n])
+	case '*':
+		n, err := strconv.Atoi(line)
+		if err != nil {
+			return Reply{}, fmt.Errorf("invalid array length %q", line)
+		}
+		if n < 0 {
+			reply.Null = true
+			break
+		}
+		for range n {
+			elem, err := ReadReply(r)
+			if err != nil {
+				return Reply{}, err
+			}
+			reply.Elems = append(reply.Elems, elem)
+		}
+	default:
+		return Reply{}, fmt.Errorf("unexpected reply prefix %q", prefix)
+	}
+	return reply, nil
+}
+
+// Reply encoders. Command handlers return fully encoded RESP replies built
+// with these helpers.
+
+func SimpleString(s string) string {
+	return "+" + s + "\r\n"
+}
+
+func Error(msg string) string {
+	return "-" + msg + "\r\n"
+}
+
+func Integer(n int) string {
+	return ":" + strconv.Itoa(n) + "\r\n"
+}
+
+func BulkString(s string) string {
+	return "$" + strconv.Itoa(len(s)) + "\r\n" + s + "\r\n"
+}
+
+func NullBulkString() string {
+	return "$-1\r\n"
+}
+
+func NullArray() string {
+	return "*-1\r\n"
+}
+
+// Array wraps already encoded elements into a RESP array.
+func Array(elems ...string) string {
+	var b strings.Builder
+	b.WriteString("*" + strconv.Itoa(len(elems)) + "\r\n")
+	for _, e := range elems {
+		b.WriteString(e)
+	}
+	return b.String()
+}
+
+// BulkArray encodes vals as an array of bulk strings.
+func BulkArray(vals []string) string {
+	var b strings.Builder
+	b.WriteString("*" + strconv.Itoa(len(vals)) + "\r\n")
+	for _, v := range vals {
+		b.WriteString(BulkString(v))
+	}
+	return b.String()
+}
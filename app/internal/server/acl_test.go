@@ -0,0 +1,27 @@
+package server
+
+import (
+	"redis-go/internal/config"
+	"testing"
+)
+
+func TestACLUsers(t *testing.T) {
+	s := startServer(t, config.New(), &Server{})
+
+	admin := dial(t, s.Address)
+	admin.expect("OK", "ACL", "SETUSER", "app", "on", ">pw", "+@read", "+@write", "-@dangerous", "+acl|whoami", "~app:*", "&news")
+
+	c := dial(t, s.Address)
+	c.expect("OK", "AUTH", "app", "pw")
+	c.expect("app", "ACL", "WHOAMI")
+	c.expect("OK", "SET", "app:k", "v")
+	c.expect("-NOPERM No permissions to access a key", "GET", "other")
+	c.expect("-NOPERM User app has no permissions to run the 'flushall' command", "FLUSHALL")
+	c.expect("-NOPERM No permissions to access a key", "LRANGE", "other", "0", "-1")
+	c.expect("-NOPERM User app has no permissions to run the 'subscribe' command", "SUBSCRIBE", "news")
+
+	// disabling the user keeps the session but refuses new logins
+	admin.expect("OK", "ACL", "SETUSER", "app", "off")
+	d := dial(t, s.Address)
+	d.expect("-WRONGPASS invalid username-password pair or user is disabled.", "AUTH", "app", "pw")
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"net"
+	"redis-go/internal/commands"
+	"redis-go/internal/protocol"
+	"strings"
+)
+
+const (
+	errNoAuth    = "NOAUTH Authentication required."
+	errWrongPass = "WRONGPASS invalid username-password pair or user is disabled."
+	errNoPass    = "ERR AUTH <password> called without any password configured for the default user. Are you sure your configuration is correct?"
+	errDenied    = "DENIED Running in protected mode because protected mode is enabled and no password is set for the default user. " +
+		"In this mode connections are only accepted from the loopback interface. " +
+		"Set a password with CONFIG SET requirepass from a loopback connection, or disable protected mode with CONFIG SET protected-mode no " +
+		"or by starting the server with '--protected-mode no'."
+)
+
+// noAuthCommands may run before the client has authenticated.
+var noAuthCommands = map[string]bool{
+	"AUTH":  true,
+	"QUIT":  true,
+	"RESET": true,
+}
+
+// initialUser is the user new and reset connections are authenticated as:
+// the default user if it needs no password, otherwise none.
+func (s *Server) initialUser() string {
+	if s.Commands.GetACL().NoPass("default") {
+		return "default"
+	}
+	return ""
+}
+
+// denied reports whether protected mode refuses conn: the default user has
+// no password and the connection does not come from the loopback interface.
+func (s *Server) denied(conn net.Conn) bool {
+	if !s.Commands.GetConfig().Bool("protected-mode") || s.initialUser() == "" {
+		return false
+	}
+	addr, ok := conn.RemoteAddr().(*net.TCPAddr)
+	return ok && !addr.IP.IsLoopback()
+}
+
+// handleAuth runs AUTH and ACL WHOAMI and rejects commands from clients that
+// have not authenticated yet. It reports whether cmd was handled.
+func (s *Server) handleAuth(c *client, cmd string, args []string) bool {
+	if cmd == "AUTH" {
+		c.write(s.auth(c, args))
+		return true
+	}
+
+	if c.username() == "" && !noAuthCommands[cmd] {
+		c.write(protocol.Error(errNoAuth))
+		return true
+	}
+
+	if cmd == "ACL" && len(args) == 1 && strings.EqualFold(args[0], "WHOAMI") {
+		if reply := s.Commands.Authorize(c.caller(), cmd, args); reply != "" {
+			c.write(reply)
+		} else {
+			c.write(protocol.BulkString(c.username()))
+		}
+		return true
+	}
+	return false
+}
+
+// auth implements AUTH [username] password.
+func (s *Server) auth(c *client, args []string) string {
+	a := s.Commands.GetACL()
+
+	user, password := "default", args[0]
+	if len(args) == 2 {
+		user, password = args[0], args[1]
+	} else if a.NoPass("default") {
+		return protocol.Error(errNoPass)
+	}
+
+	if !a.Authenticate(user, password) {
+		a.Log("auth", "AUTH", user, (&commands.Caller{User: user, Addr: c.addr()}).String())
+		return protocol.Error(errWrongPass)
+	}
+	c.setUser(user)
+	return "+OK\r\n"
+}
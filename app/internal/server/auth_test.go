@@ -0,0 +1,21 @@
+package server
+
+import (
+	"redis-go/internal/config"
+	"testing"
+)
+
+func TestRequirePass(t *testing.T) {
+	cfg := config.New()
+	if err := cfg.Set("requirepass", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	s := startServer(t, cfg, &Server{})
+
+	c := dial(t, s.Address)
+	c.expect("-NOAUTH Authentication required.", "GET", "k")
+	c.expect("-WRONGPASS invalid username-password pair or user is disabled.", "AUTH", "wrong")
+	c.expect("OK", "AUTH", "secret")
+	c.expect("PONG", "PING")
+	c.expect("default", "ACL", "WHOAMI")
+}
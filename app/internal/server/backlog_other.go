@@ -0,0 +1,11 @@
+//go:build !unix
+
+package server
+
+import "net"
+
+// setBacklog is a no-op where the backlog cannot be changed after listening;
+// the system default applies.
+func setBacklog(ln net.Listener, backlog int) error {
+	return nil
+}
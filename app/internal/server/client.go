@@ -0,0 +1,219 @@
+package server
+
+import (
+	"bufio"
+	"log"
+	"net"
+	"redis-go/internal/commands"
+	"redis-go/internal/db"
+	"redis-go/internal/protocol"
+	"strings"
+	"sync"
+	"time"
+)
+
+// client is the state of one connection. Replies are written through write,
+// which serializes them on the connection with pub/sub messages.
+type client struct {
+	id      int64 // set when the client is added to the server's list
+	created time.Time
+	conn    net.Conn
+	r       *bufio.Reader
+
+	mu sync.Mutex // guards w
+	w  *bufio.Writer
+
+	sub *db.Subscriber
+
+	killOnce sync.Once
+	killed   chan struct{} // closed by kill
+
+	// stateMu guards the fields other connections read through CLIENT.
+	stateMu    sync.Mutex
+	user       string // ACL user, empty before the client has authenticated
+	name       string
+	lastCmd    string
+	lastActive time.Time
+	qbuf       int // bytes read ahead of the current command
+	noEvict    bool
+	connecting bool // the handshake deadline still applies
+
+	// CLIENT REPLY state, only used by the command loop. quiet drops the
+	// replies to the current command.
+	replyOff, skipNext, quiet bool
+
+	// closeAfterReply is set when the client kills itself with CLIENT KILL.
+	closeAfterReply bool
+
+	tracker  *db.Tracker
+	tracking tracking
+}
+
+func newClient(conn net.Conn, d *db.DB, user string) *client {
+	now := time.Now()
+	return &client{
+		created:    now,
+		conn:       conn,
+		r:          bufio.NewReader(conn),
+		w:          bufio.NewWriter(conn),
+		sub:        d.NewSubscriber(),
+		tracker:    d.NewTracker(),
+		killed:     make(chan struct{}),
+		user:       user,
+		lastActive: now,
+		connecting: true,
+	}
+}
+
+func (c *client) addr() string {
+	// Unix socket peers are unnamed; report the socket path instead
+	if _, ok := c.conn.RemoteAddr().(*net.UnixAddr); ok {
+		return c.conn.LocalAddr().String() + ":0"
+	}
+	return c.conn.RemoteAddr().String()
+}
+
+func (c *client) laddr() string {
+	return c.conn.LocalAddr().String()
+}
+
+func (c *client) username() string {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+
+	return c.user
+}
+
+func (c *client) setUser(user string) {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+
+	c.user = user
+}
+
+// caller identifies the client to the command registry.
+func (c *client) caller() *commands.Caller {
+	return &commands.Caller{User: c.username(), Addr: c.addr()}
+}
+
+// startCommand records cmd as the client's latest command and decides
+// whether its replies are sent.
+func (c *client) startCommand(cmd string) {
+	c.stateMu.Lock()
+	c.lastCmd = strings.ToLower(cmd)
+	c.lastActive = time.Now()
+	c.qbuf = c.r.Buffered()
+	c.stateMu.Unlock()
+
+	c.quiet = c.replyOff || c.skipNext
+	c.skipNext = false
+	c.tracking.caching, c.tracking.next = c.tracking.next, ""
+}
+
+// kill closes the connection. The command loop notices and cleans up.
+func (c *client) kill() {
+	c.killOnce.Do(func() {
+		close(c.killed)
+		c.conn.Close()
+	})
+}
+
+// write sends one or more encoded replies and flushes them. Nothing is sent
+// while CLIENT REPLY has turned replies off.
+func (c *client) write(replies ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.writeLocked(replies...)
+}
+
+// writeLocked is write for callers already holding c.mu.
+func (c *client) writeLocked(replies ...string) error {
+	if c.quiet {
+		return nil
+	}
+	return c.send(replies...)
+}
+
+// push sends pub/sub messages, which CLIENT REPLY does not silence.
+func (c *client) push(msgs ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.send(msgs...)
+}
+
+// send writes and flushes. Callers must hold c.mu.
+func (c *client) send(replies ...string) error {
+	for _, reply := range replies {
+		if _, err := c.w.WriteString(reply); err != nil {
+			return err
+		}
+	}
+	return c.w.Flush()
+}
+
+// forwardMessages writes published messages to the client until its
+// subscriber is closed.
+func (c *client) forwardMessages() {
+	for {
+		msgs, ok := c.sub.Receive()
+		if !ok {
+			return
+		}
+
+		replies := make([]string, len(msgs))
+		for i, msg := range msgs {
+			replies[i] = encodeMessage(msg)
+		}
+		// a failed write is noticed and cleaned up by the command loop
+		if c.push(replies...) == nil {
+			c.sub.Flushed(msgs)
+		}
+	}
+}
+
+// watchOutputLimit closes the connection when the subscriber is closed for
+// exceeding its output buffer limit. The forwarder may be stuck writing to
+// the slow client, so it cannot do this itself.
+func (c *client) watchOutputLimit() {
+	<-c.sub.Done()
+	if c.sub.Overflowed() {
+		log.Printf("client %s closed for overcoming of output buffer limits", c.conn.RemoteAddr())
+		c.kill()
+	}
+}
+
+func encodeMessage(msg db.Message) string {
+	if msg.Invalidate {
+		keys := protocol.NullArray()
+		if msg.Keys != nil {
+			keys = protocol.BulkArray(msg.Keys)
+		}
+		return protocol.Array(
+			protocol.BulkString("message"),
+			protocol.BulkString(msg.Channel),
+			keys,
+		)
+	}
+	if msg.Shard {
+		return protocol.Array(
+			protocol.BulkString("smessage"),
+			protocol.BulkString(msg.Channel),
+			protocol.BulkString(msg.Payload),
+		)
+	}
+	if msg.Pattern != "" {
+		return protocol.Array(
+			protocol.BulkString("pmessage"),
+			protocol.BulkString(msg.Pattern),
+			protocol.BulkString(msg.Channel),
+			protocol.BulkString(msg.Payload),
+		)
+	}
+	return protocol.Array(
+		protocol.BulkString("message"),
+		protocol.BulkString(msg.Channel),
+		protocol.BulkString(msg.Payload),
+	)
+}
@@ -0,0 +1,454 @@
+package server
+
+import (
+	"fmt"
+	"redis-go/internal/commands"
+	"redis-go/internal/protocol"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// clientList tracks the connected clients for CLIENT LIST and KILL.
+type clientList struct {
+	mu      sync.Mutex
+	nextID  int64
+	clients map[int64]*client
+}
+
+// add gives c its id and starts tracking it, unless max clients are
+// already connected.
+func (l *clientList) add(c *client, max int) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.clients) >= max {
+		return false
+	}
+	if l.clients == nil {
+		l.clients = make(map[int64]*client)
+	}
+	l.nextID++
+	c.id = l.nextID
+	l.clients[c.id] = c
+	return true
+}
+
+func (l *clientList) remove(c *client) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.clients, c.id)
+}
+
+func (l *clientList) get(id int64) (*client, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	c, ok := l.clients[id]
+	return c, ok
+}
+
+// all returns the clients ordered by id.
+func (l *clientList) all() []*client {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	clients := make([]*client, 0, len(l.clients))
+	for _, c := range l.clients {
+		clients = append(clients, c)
+	}
+	sort.Slice(clients, func(i, j int) bool { return clients[i].id < clients[j].id })
+	return clients
+}
+
+// pauseState is the effect of CLIENT PAUSE. changed is closed when the pause
+// is extended or lifted, so waiting clients look again.
+type pauseState struct {
+	until   time.Time
+	all     bool // pause all commands, not just writes
+	changed chan struct{}
+}
+
+// pause pauses commands until the given time. An existing pause is only
+// ever made longer and stricter.
+func (s *Server) pause(until time.Time, all bool) {
+	s.pauseMu.Lock()
+	defer s.pauseMu.Unlock()
+
+	p := &s.paused
+	if time.Now().Before(p.until) {
+		all = all || p.all
+		if p.until.After(until) {
+			until = p.until
+		}
+	}
+	if p.changed != nil {
+		close(p.changed)
+	}
+	*p = pauseState{until: until, all: all, changed: make(chan struct{})}
+}
+
+func (s *Server) unpause() {
+	s.pauseMu.Lock()
+	defer s.pauseMu.Unlock()
+
+	if s.paused.changed != nil {
+		close(s.paused.changed)
+	}
+	s.paused = pauseState{}
+}
+
+// waitUnpaused blocks while CLIENT PAUSE holds back cmd. It reports false
+// if the client was killed while waiting.
+func (s *Server) waitUnpaused(c *client, cmd string) bool {
+	for {
+		s.pauseMu.Lock()
+		p := s.paused
+		s.pauseMu.Unlock()
+
+		wait := time.Until(p.until)
+		if wait <= 0 || !(p.all || pausedForWrites(cmd)) {
+			return true
+		}
+
+		t := time.NewTimer(wait)
+		select {
+		case <-p.changed:
+		case <-t.C:
+		case <-c.killed:
+			t.Stop()
+			return false
+		}
+		t.Stop()
+	}
+}
+
+// pausedForWrites reports whether CLIENT PAUSE WRITE holds back cmd.
+func pausedForWrites(cmd string) bool {
+	return commands.InCategory(cmd, "write") || cmd == "PUBLISH" || cmd == "SPUBLISH"
+}
+
+// info describes c in the CLIENT LIST format.
+func (s *Server) info(c *client) string {
+	channels, patterns, shards := s.Commands.GetDB().Subscriptions(c.sub)
+	oll, omem := c.sub.Pending()
+
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+
+	flags := ""
+	if len(channels)+len(patterns)+len(shards) > 0 {
+		flags += "P"
+	}
+	if c.noEvict {
+		flags += "e"
+	}
+	if flags == "" {
+		flags = "N"
+	}
+
+	now := time.Now()
+	return fmt.Sprintf("id=%d addr=%s laddr=%s name=%s age=%d idle=%d flags=%s db=0 sub=%d psub=%d ssub=%d multi=-1 "+
+		"qbuf=%d qbuf-free=%d oll=%d omem=%d cmd=%s user=%s resp=2",
+		c.id, c.addr(), c.laddr(), c.name,
+		int(now.Sub(c.created).Seconds()), int(now.Sub(c.lastActive).Seconds()), flags,
+		len(channels), len(patterns), len(shards),
+		c.qbuf, c.r.Size()-c.qbuf, oll, omem, c.lastCmd, c.user)
+}
+
+// clientType is the CLIENT KILL / LIST TYPE of c. There are no replicas, so
+// a client is either normal or a subscriber.
+func (s *Server) clientType(c *client) string {
+	if s.Commands.GetDB().SubscriptionCount(c.sub) > 0 {
+		return "pubsub"
+	}
+	return "normal"
+}
+
+func validClientType(t string) bool {
+	switch t {
+	case "normal", "master", "replica", "slave", "pubsub":
+		return true
+	}
+	return false
+}
+
+// handleClient runs the CLIENT command, which needs the connection state.
+// It reports whether cmd was CLIENT.
+func (s *Server) handleClient(c *client, cmd string, args []string) bool {
+	if cmd != "CLIENT" {
+		return false
+	}
+	if reply := s.Commands.Authorize(c.caller(), cmd, args); reply != "" {
+		c.write(reply)
+		return true
+	}
+	if len(args) < 1 {
+		c.write("-ERR wrong number of arguments\r\n")
+		return true
+	}
+
+	switch sub := strings.ToUpper(args[0]); sub {
+	case "ID":
+		c.write(protocol.Integer(int(c.id)))
+
+	case "INFO":
+		c.write(protocol.BulkString(s.info(c) + "\n"))
+
+	case "LIST":
+		c.write(s.clientListCmd(args[1:]))
+
+	case "KILL":
+		c.write(s.clientKill(c, args[1:]))
+
+	case "SETNAME":
+		// CLIENT SETNAME name
+		if len(args) != 2 {
+			c.write("-ERR wrong number of arguments\r\n")
+			return true
+		}
+		for _, r := range args[1] {
+			if r <= ' ' || r > '~' {
+				c.write(protocol.Error("ERR Client names cannot contain spaces, newlines or special characters."))
+				return true
+			}
+		}
+		c.stateMu.Lock()
+		c.name = args[1]
+		c.stateMu.Unlock()
+		c.write(protocol.SimpleString("OK"))
+
+	case "GETNAME":
+		c.stateMu.Lock()
+		name := c.name
+		c.stateMu.Unlock()
+		if name == "" {
+			c.write(protocol.NullBulkString())
+		} else {
+			c.write(protocol.BulkString(name))
+		}
+
+	case "PAUSE":
+		// CLIENT PAUSE timeout [WRITE | ALL]
+		if len(args) < 2 || len(args) > 3 {
+			c.write("-ERR wrong number of arguments\r\n")
+			return true
+		}
+		ms, err := strconv.Atoi(args[1])
+		if err != nil || ms < 0 {
+			c.write(protocol.Error("ERR timeout is not an integer or out of range"))
+			return true
+		}
+		all := true
+		if len(args) == 3 {
+			switch strings.ToUpper(args[2]) {
+			case "ALL":
+			case "WRITE":
+				all = false
+			default:
+				c.write(protocol.Error("ERR syntax error"))
+				return true
+			}
+		}
+		s.pause(time.Now().Add(time.Duration(ms)*time.Millisecond), all)
+		c.write(protocol.SimpleString("OK"))
+
+	case "UNPAUSE":
+		s.unpause()
+		c.write(protocol.SimpleString("OK"))
+
+	case "REPLY":
+		// CLIENT REPLY ON | OFF | SKIP
+		if len(args) != 2 {
+			c.write("-ERR wrong number of arguments\r\n")
+			return true
+		}
+		switch strings.ToUpper(args[1]) {
+		case "ON":
+			c.replyOff, c.quiet = false, false
+			c.write(protocol.SimpleString("OK"))
+		case "OFF":
+			c.replyOff, c.quiet = true, true
+		case "SKIP":
+			c.skipNext, c.quiet = true, true
+		default:
+			c.write(protocol.Error("ERR syntax error"))
+		}
+
+	case "TRACKING":
+		c.write(s.clientTracking(c, args[1:]))
+
+	case "CACHING":
+		c.write(s.clientCaching(c, args[1:]))
+
+	case "GETREDIR":
+		c.write(protocol.Integer(c.redirectID()))
+
+	case "TRACKINGINFO":
+		c.write(s.trackingInfo(c))
+
+	case "NO-EVICT":
+		// CLIENT NO-EVICT ON | OFF
+		if len(args) != 2 {
+			c.write("-ERR wrong number of arguments\r\n")
+			return true
+		}
+		switch strings.ToUpper(args[1]) {
+		case "ON", "OFF":
+			c.stateMu.Lock()
+			c.noEvict = strings.EqualFold(args[1], "ON")
+			c.stateMu.Unlock()
+			c.write(protocol.SimpleString("OK"))
+		default:
+			c.write(protocol.Error("ERR syntax error"))
+		}
+
+	default:
+		c.write(protocol.Error("ERR unknown subcommand '" + args[0] + "'. Try CLIENT HELP."))
+	}
+	return true
+}
+
+// clientListCmd implements CLIENT LIST [TYPE type] [ID id [id ...]].
+func (s *Server) clientListCmd(args []string) string {
+	var typ string
+	var ids map[int64]bool
+
+	for i := 0; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "TYPE":
+			if i+1 == len(args) {
+				return protocol.Error("ERR syntax error")
+			}
+			i++
+			typ = strings.ToLower(args[i])
+			if !validClientType(typ) {
+				return protocol.Error("ERR Unknown client type '" + args[i] + "'")
+			}
+		case "ID":
+			if i+1 == len(args) {
+				return protocol.Error("ERR syntax error")
+			}
+			ids = make(map[int64]bool)
+			for i++; i < len(args); i++ {
+				id, err := strconv.ParseInt(args[i], 10, 64)
+				if err != nil || id <= 0 {
+					return protocol.Error("ERR Invalid client ID")
+				}
+				ids[id] = true
+			}
+		default:
+			return protocol.Error("ERR syntax error")
+		}
+	}
+
+	var b strings.Builder
+	for _, c := range s.clients.all() {
+		if typ != "" && s.clientType(c) != typ {
+			continue
+		}
+		if ids != nil && !ids[c.id] {
+			continue
+		}
+		b.WriteString(s.info(c))
+		b.WriteString("\n")
+	}
+	return protocol.BulkString(b.String())
+}
+
+// clientKill implements CLIENT KILL addr and CLIENT KILL filter value
+// [filter value ...]. Filters are ID, ADDR, LADDR, USER, TYPE, MAXAGE and
+// SKIPME, which defaults to yes.
+func (s *Server) clientKill(self *client, args []string) string {
+	if len(args) == 0 {
+		return "-ERR wrong number of arguments\r\n"
+	}
+
+	// the old form names one address and fails if nobody has it
+	if len(args) == 1 {
+		for _, c := range s.clients.all() {
+			if c.addr() == args[0] {
+				s.killClient(self, c)
+				return protocol.SimpleString("OK")
+			}
+		}
+		return protocol.Error("ERR No such client")
+	}
+	if len(args)%2 != 0 {
+		return protocol.Error("ERR syntax error")
+	}
+
+	var matchers []func(*client) bool
+	skipMe := true
+	for i := 0; i < len(args); i += 2 {
+		value := args[i+1]
+		switch strings.ToUpper(args[i]) {
+		case "ID":
+			id, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || id <= 0 {
+				return protocol.Error("ERR client-id should be greater than 0")
+			}
+			matchers = append(matchers, func(c *client) bool { return c.id == id })
+		case "ADDR":
+			matchers = append(matchers, func(c *client) bool { return c.addr() == value })
+		case "LADDR":
+			matchers = append(matchers, func(c *client) bool { return c.laddr() == value })
+		case "USER":
+			matchers = append(matchers, func(c *client) bool { return c.username() == value })
+		case "TYPE":
+			typ := strings.ToLower(value)
+			if !validClientType(typ) {
+				return protocol.Error("ERR Unknown client type '" + value + "'")
+			}
+			matchers = append(matchers, func(c *client) bool { return s.clientType(c) == typ })
+		case "MAXAGE":
+			secs, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || secs < 0 {
+				return protocol.Error("ERR syntax error")
+			}
+			matchers = append(matchers, func(c *client) bool {
+				return time.Since(c.created) >= time.Duration(secs)*time.Second
+			})
+		case "SKIPME":
+			switch strings.ToLower(value) {
+			case "yes":
+				skipMe = true
+			case "no":
+				skipMe = false
+			default:
+				return protocol.Error("ERR syntax error")
+			}
+		default:
+			return protocol.Error("ERR syntax error")
+		}
+	}
+
+	n := 0
+next:
+	for _, c := range s.clients.all() {
+		if skipMe && c == self {
+			continue
+		}
+		for _, match := range matchers {
+			if !match(c) {
+				continue next
+			}
+		}
+		s.killClient(self, c)
+		n++
+	}
+	return protocol.Integer(n)
+}
+
+// killClient closes c. A client killing itself is closed once its reply
+// has been written.
+func (s *Server) killClient(self, c *client) {
+	if c == self {
+		c.closeAfterReply = true
+		return
+	}
+	c.kill()
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"io"
+	"redis-go/internal/config"
+	"strings"
+	"testing"
+	"time"
+)
+
+// expectClosed fails the test unless the server closes the connection.
+func (c *testConn) expectClosed() {
+	c.t.Helper()
+	c.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := io.Copy(io.Discard, c.r); err != nil {
+		c.t.Errorf("connection was not closed: %v", err)
+	}
+}
+
+// expectBlocked fails the test if the server replies within d.
+func (c *testConn) expectBlocked(d time.Duration) {
+	c.t.Helper()
+	c.SetReadDeadline(time.Now().Add(d))
+	if _, err := c.r.Peek(1); !isTimeout(err) {
+		c.t.Errorf("server replied while it should be blocked (%v)", err)
+	}
+}
+
+func TestClientList(t *testing.T) {
+	s := startServer(t, config.New(), &Server{})
+
+	worker := dial(t, s.Address)
+	worker.expect("OK", "CLIENT", "SETNAME", "worker")
+	worker.expect("worker", "CLIENT", "GETNAME")
+	id := worker.do("CLIENT", "ID").Str
+
+	c := dial(t, s.Address)
+	list := strings.Split(strings.TrimSuffix(c.do("CLIENT", "LIST").Str, "\n"), "\n")
+	if len(list) != 2 {
+		t.Fatalf("CLIENT LIST has %d lines, want 2:\n%s", len(list), strings.Join(list, "\n"))
+	}
+	if !strings.HasPrefix(list[0], "id="+id+" addr="+worker.LocalAddr().String()+" ") ||
+		!strings.Contains(list[0], " name=worker ") || !strings.Contains(list[0], " cmd=client ") {
+		t.Errorf("worker is listed as %q", list[0])
+	}
+
+	only := c.do("CLIENT", "LIST", "ID", id).Str
+	if strings.Count(only, "\n") != 1 || !strings.HasPrefix(only, "id="+id+" ") {
+		t.Errorf("CLIENT LIST ID %s = %q", id, only)
+	}
+	c.expect("", "CLIENT", "LIST", "TYPE", "pubsub")
+	c.expect("-ERR Unknown client type 'bogus'", "CLIENT", "LIST", "TYPE", "bogus")
+}
+
+func TestClientKill(t *testing.T) {
+	s := startServer(t, config.New(), &Server{})
+	c := dial(t, s.Address)
+
+	byID := dial(t, s.Address)
+	c.expect("1", "CLIENT", "KILL", "ID", byID.do("CLIENT", "ID").Str)
+	byID.expectClosed()
+
+	byAddr := dial(t, s.Address)
+	c.expect("OK", "CLIENT", "KILL", byAddr.LocalAddr().String())
+	byAddr.expectClosed()
+	c.expect("-ERR No such client", "CLIENT", "KILL", byAddr.LocalAddr().String())
+
+	// SKIPME defaults to yes, so only the other normal client goes
+	other := dial(t, s.Address)
+	c.expect("1", "CLIENT", "KILL", "TYPE", "normal")
+	other.expectClosed()
+	c.expect("PONG", "PING")
+
+	c.expect("1", "CLIENT", "KILL", "TYPE", "normal", "SKIPME", "no")
+	c.expectClosed()
+}
+
+func TestClientPause(t *testing.T) {
+	s := startServer(t, config.New(), &Server{})
+	admin := dial(t, s.Address)
+	c := dial(t, s.Address)
+
+	admin.expect("OK", "CLIENT", "PAUSE", "10000", "WRITE")
+	c.expect("", "GET", "k")
+	if _, err := c.Write([]byte("*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$1\r\nv\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	c.expectBlocked(200 * time.Millisecond)
+
+	admin.expect("OK", "CLIENT", "UNPAUSE")
+	if reply := c.read(); reply.Str != "OK" {
+		t.Errorf("SET after UNPAUSE = %+v", reply)
+	}
+
+	// a pause also ends by itself
+	admin.expect("OK", "CLIENT", "PAUSE", "200")
+	start := time.Now()
+	c.expect("v", "GET", "k")
+	if waited := time.Since(start); waited < 100*time.Millisecond {
+		t.Errorf("GET ran after %v during CLIENT PAUSE ALL", waited)
+	}
+}
+
+func TestClientPauseKilled(t *testing.T) {
+	s := startServer(t, config.New(), &Server{})
+	admin := dial(t, s.Address)
+	c := dial(t, s.Address)
+	id := c.do("CLIENT", "ID").Str
+
+	admin.expect("OK", "CLIENT", "PAUSE", "10000")
+	if _, err := c.Write([]byte("*1\r\n$4\r\nPING\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	c.expectBlocked(100 * time.Millisecond)
+
+	// a paused client can still be killed
+	admin.expect("1", "CLIENT", "KILL", "ID", id)
+	c.expectClosed()
+}
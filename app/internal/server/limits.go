@@ -0,0 +1,75 @@
+package server
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"os"
+	"time"
+)
+
+// errMaxClients is sent to connections refused because maxclients clients
+// are already connected.
+const errMaxClients = "ERR max number of clients reached"
+
+// handshakeTimeout bounds the time a new connection may take to complete
+// its TLS handshake.
+var handshakeTimeout = 10 * time.Second
+
+// setKeepAlive applies tcp-keepalive to a new TCP connection. 0 turns
+// keepalive probes off.
+func (s *Server) setKeepAlive(conn net.Conn) {
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		conn = tlsConn.NetConn()
+	}
+	tc, ok := conn.(*net.TCPConn)
+	if !ok {
+		return
+	}
+	secs := s.Commands.GetConfig().Int("tcp-keepalive")
+	if secs == 0 {
+		tc.SetKeepAlive(false)
+		return
+	}
+	// like Redis, probe every third of the period after it has passed idle
+	period := time.Duration(secs) * time.Second
+	tc.SetKeepAliveConfig(net.KeepAliveConfig{
+		Enable:   true,
+		Idle:     period,
+		Interval: max(period/3, time.Second),
+		Count:    3,
+	})
+}
+
+// setIdleDeadline makes the client's next read fail once it has been idle
+// for the configured timeout. Subscribers are expected to sit idle and are
+// exempt, and connecting clients are bound by the handshake deadline.
+func (s *Server) setIdleDeadline(c *client) {
+	c.stateMu.Lock()
+	lastActive, connecting := c.lastActive, c.connecting
+	c.stateMu.Unlock()
+	if connecting {
+		return
+	}
+
+	secs := s.Commands.GetConfig().Int("timeout")
+	if secs == 0 || s.Commands.GetDB().SubscriptionCount(c.sub) > 0 {
+		c.conn.SetReadDeadline(time.Time{})
+		return
+	}
+	c.conn.SetReadDeadline(lastActive.Add(time.Duration(secs) * time.Second))
+}
+
+// watchTimeout applies a changed timeout to clients already waiting for
+// their next command.
+func (s *Server) watchTimeout() {
+	s.Commands.GetConfig().Watch("timeout", func(string) {
+		for _, c := range s.clients.all() {
+			s.setIdleDeadline(c)
+		}
+	})
+}
+
+func isTimeout(err error) bool {
+	return errors.Is(err, os.ErrDeadlineExceeded)
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"bufio"
+	"net"
+	"redis-go/internal/config"
+	"redis-go/internal/protocol"
+	"testing"
+	"time"
+)
+
+func TestMaxClients(t *testing.T) {
+	s := startServer(t, config.New(), &Server{})
+	c := dial(t, s.Address)
+	c.expect("OK", "CONFIG", "SET", "maxclients", "2")
+	second := dial(t, s.Address)
+
+	conn, err := net.Dial("tcp", s.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	refused := &testConn{t: t, Conn: conn, r: bufio.NewReader(conn)}
+	t.Cleanup(func() { conn.Close() })
+	if reply := refused.read(); reply.Str != errMaxClients {
+		t.Errorf("connection over maxclients got %+v", reply)
+	}
+	refused.expectClosed()
+
+	// the slot is given back once a client leaves
+	second.Close()
+	for range 100 {
+		conn, err := net.Dial("tcp", s.Address)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		reply, err := protocol.ReadReply(bufio.NewReader(conn))
+		conn.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reply.Str == "OK" {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error("a new client was refused after another one left")
+}
+
+func TestIdleTimeout(t *testing.T) {
+	s := startServer(t, config.New(), &Server{})
+	idle := dial(t, s.Address)
+	sub := dial(t, s.Address)
+	if got := strs(sub.do("SUBSCRIBE", "news")); len(got) != 3 {
+		t.Fatalf("SUBSCRIBE = %q", got)
+	}
+
+	// the new timeout applies to clients that are already waiting
+	c := dial(t, s.Address)
+	c.expect("OK", "CONFIG", "SET", "timeout", "1")
+	start := time.Now()
+	idle.expectClosed()
+	if waited := time.Since(start); waited < 500*time.Millisecond {
+		t.Errorf("idle client closed after %v, want about 1s", waited)
+	}
+
+	// subscribers are expected to sit idle
+	if got := strs(sub.do("PING")); len(got) != 2 || got[0] != "pong" {
+		t.Errorf("subscriber PING after the timeout = %q", got)
+	}
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"redis-go/internal/protocol"
+	"strings"
+)
+
+// subscribedModeCommands are the commands a client may run while it has
+// subscriptions.
+var subscribedModeCommands = map[string]bool{
+	"SUBSCRIBE":    true,
+	"UNSUBSCRIBE":  true,
+	"PSUBSCRIBE":   true,
+	"PUNSUBSCRIBE": true,
+	"SSUBSCRIBE":   true,
+	"SUNSUBSCRIBE": true,
+	"PING":         true,
+	"QUIT":         true,
+	"RESET":        true,
+}
+
+// subscriptionReply encodes a (un)subscribe confirmation. An empty name is
+// sent as a null bulk string.
+func subscriptionReply(kind, name string, count int) string {
+	nameReply := protocol.BulkString(name)
+	if name == "" {
+		nameReply = protocol.NullBulkString()
+	}
+	return protocol.Array(protocol.BulkString(kind), nameReply, protocol.Integer(count))
+}
+
+// handlePubSub runs the commands that depend on the client's subscriptions.
+// It reports whether cmd was one of them.
+func (s *Server) handlePubSub(c *client, cmd string, args []string) bool {
+	d := s.Commands.GetDB()
+
+	switch cmd {
+	case "SUBSCRIBE", "PSUBSCRIBE", "SSUBSCRIBE", "UNSUBSCRIBE", "PUNSUBSCRIBE", "SUNSUBSCRIBE":
+		if reply := s.Commands.Authorize(c.caller(), cmd, args); reply != "" {
+			c.write(reply)
+			return true
+		}
+	}
+
+	// Confirmations are written while holding the client's writer, so no
+	// message on a new channel can overtake its subscribe reply.
+	switch cmd {
+	case "SUBSCRIBE", "PSUBSCRIBE", "SSUBSCRIBE":
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		replies := make([]string, 0, len(args))
+		for _, name := range args {
+			var count int
+			switch cmd {
+			case "SUBSCRIBE":
+				count = d.Subscribe(c.sub, name)
+			case "PSUBSCRIBE":
+				count = d.PSubscribe(c.sub, name)
+			default:
+				count = d.SSubscribe(c.sub, name)
+			}
+			replies = append(replies, subscriptionReply(strings.ToLower(cmd), name, count))
+		}
+		c.writeLocked(replies...)
+		return true
+
+	case "UNSUBSCRIBE", "PUNSUBSCRIBE", "SUNSUBSCRIBE":
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		c.writeLocked(s.unsubscribe(c, cmd, args)...)
+		return true
+
+	case "PING":
+		if d.SubscriptionCount(c.sub) == 0 {
+			return false
+		}
+		// subscribed clients get PING replies in the push format
+		msg := ""
+		if len(args) > 0 {
+			msg = args[0]
+		}
+		c.write(protocol.Array(protocol.BulkString("pong"), protocol.BulkString(msg)))
+		return true
+	}
+
+	if d.SubscriptionCount(c.sub) > 0 && !subscribedModeCommands[cmd] {
+		c.write(protocol.Error("ERR Can't execute '" + strings.ToLower(cmd) +
+			"': only (P|S)SUBSCRIBE / (P|S)UNSUBSCRIBE / PING / QUIT / RESET are allowed in this context"))
+		return true
+	}
+	return false
+}
+
+// unsubscribe drops the named channels, patterns or shard channels, or all of
+// them when names is empty, and returns the confirmations. Callers must hold
+// c.mu.
+func (s *Server) unsubscribe(c *client, cmd string, names []string) []string {
+	d := s.Commands.GetDB()
+	kind := strings.ToLower(cmd)
+
+	if len(names) == 0 {
+		channels, patterns, shards := d.Subscriptions(c.sub)
+		switch cmd {
+		case "UNSUBSCRIBE":
+			names = channels
+		case "PUNSUBSCRIBE":
+			names = patterns
+		default:
+			names = shards
+		}
+		if len(names) == 0 {
+			// shard channels are counted apart from the others
+			count := len(channels) + len(patterns)
+			if cmd == "SUNSUBSCRIBE" {
+				count = len(shards)
+			}
+			return []string{subscriptionReply(kind, "", count)}
+		}
+	}
+
+	replies := make([]string, 0, len(names))
+	for _, name := range names {
+		var count int
+		switch cmd {
+		case "UNSUBSCRIBE":
+			count = d.Unsubscribe(c.sub, name)
+		case "PUNSUBSCRIBE":
+			count = d.PUnsubscribe(c.sub, name)
+		default:
+			count = d.SUnsubscribe(c.sub, name)
+		}
+		replies = append(replies, subscriptionReply(kind, name, count))
+	}
+	return replies
+}
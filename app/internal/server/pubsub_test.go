@@ -0,0 +1,138 @@
+package server
+
+import (
+	"io"
+	"redis-go/internal/config"
+	"redis-go/internal/protocol"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// strs flattens an array reply into its elements' strings.
+func strs(r protocol.Reply) []string {
+	var s []string
+	for _, e := range r.Elems {
+		s = append(s, e.Str)
+	}
+	return s
+}
+
+// expectPush reads a push and fails the test unless it is want.
+func (c *testConn) expectPush(want ...string) {
+	c.t.Helper()
+	if got := strs(c.read()); !slices.Equal(got, want) {
+		c.t.Errorf("push = %q, want %q", got, want)
+	}
+}
+
+// eventually retries the command until it replies want, as clients are
+// cleaned up after their connection is gone.
+func (c *testConn) eventually(want []string, args ...string) {
+	c.t.Helper()
+	var got []string
+	for range 100 {
+		if got = strs(c.do(args...)); slices.Equal(got, want) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.t.Errorf("%q = %q, want %q", args, got, want)
+}
+
+func TestSubscriptionCounts(t *testing.T) {
+	s := startServer(t, config.New(), &Server{})
+
+	sub := dial(t, s.Address)
+	if _, err := sub.Write([]byte(protocol.BulkArray([]string{"SUBSCRIBE", "a", "b", "c"}))); err != nil {
+		t.Fatal(err)
+	}
+	sub.expectPush("subscribe", "a", "1")
+	sub.expectPush("subscribe", "b", "2")
+	sub.expectPush("subscribe", "c", "3")
+	if got := strs(sub.do("PSUBSCRIBE", "news.*")); !slices.Equal(got, []string{"psubscribe", "news.*", "4"}) {
+		t.Errorf("PSUBSCRIBE = %q", got)
+	}
+	sub.expect("-ERR Can't execute 'get': only (P|S)SUBSCRIBE / (P|S)UNSUBSCRIBE / PING / QUIT / RESET are allowed in this context", "GET", "k")
+
+	c := dial(t, s.Address)
+	c.eventually([]string{"a", "1", "b", "1", "x", "0"}, "PUBSUB", "NUMSUB", "a", "b", "x")
+	c.expect("1", "PUBSUB", "NUMPAT")
+
+	sub.Close()
+	c.eventually([]string{"a", "0", "b", "0", "x", "0"}, "PUBSUB", "NUMSUB", "a", "b", "x")
+	c.expect("0", "PUBSUB", "NUMPAT")
+}
+
+func TestPSubscribe(t *testing.T) {
+	s := startServer(t, config.New(), &Server{})
+
+	sub := dial(t, s.Address)
+	if got := strs(sub.do("PSUBSCRIBE", "news.*")); !slices.Equal(got, []string{"psubscribe", "news.*", "1"}) {
+		t.Fatalf("PSUBSCRIBE = %q", got)
+	}
+
+	c := dial(t, s.Address)
+	c.expect("1", "PUBLISH", "news.tech", "hello")
+	c.expect("0", "PUBLISH", "sports", "goal")
+	c.expect("1", "PUBLISH", "news.art", "bye")
+	sub.expectPush("pmessage", "news.*", "news.tech", "hello")
+	sub.expectPush("pmessage", "news.*", "news.art", "bye")
+}
+
+func TestSSubscribe(t *testing.T) {
+	s := startServer(t, config.New(), &Server{})
+
+	sub := dial(t, s.Address)
+	if got := strs(sub.do("SSUBSCRIBE", "orders")); !slices.Equal(got, []string{"ssubscribe", "orders", "1"}) {
+		t.Fatalf("SSUBSCRIBE = %q", got)
+	}
+
+	c := dial(t, s.Address)
+	c.eventually([]string{"orders", "1"}, "PUBSUB", "SHARDNUMSUB", "orders")
+	c.expect("0", "PUBLISH", "orders", "not sharded")
+	c.expect("1", "SPUBLISH", "orders", "new")
+	sub.expectPush("smessage", "orders", "new")
+
+	if got := strs(sub.do("SUNSUBSCRIBE")); !slices.Equal(got, []string{"sunsubscribe", "orders", "0"}) {
+		t.Errorf("SUNSUBSCRIBE = %q", got)
+	}
+	sub.expect("PONG", "PING")
+}
+
+func TestPubSubOutputLimit(t *testing.T) {
+	s := startServer(t, config.New(), &Server{})
+
+	c := dial(t, s.Address)
+	c.expect("OK", "CONFIG", "SET", "client-output-buffer-limit", "pubsub 1mb 0 0")
+
+	// the subscriber never reads, so its socket fills up and then its
+	// output buffer
+	sub := dial(t, s.Address)
+	if got := strs(sub.do("SUBSCRIBE", "flood")); !slices.Equal(got, []string{"subscribe", "flood", "1"}) {
+		t.Fatalf("SUBSCRIBE = %q", got)
+	}
+
+	payload := strings.Repeat("x", 64<<10)
+	for range 1000 {
+		if c.do("PUBLISH", "flood", payload).Str == "0" {
+			break
+		}
+	}
+
+	info := c.do("INFO", "stats").Str
+	if !strings.Contains(info, "client_output_buffer_limit_disconnections:1\r\n") {
+		t.Errorf("INFO stats does not count the disconnection:\n%s", info)
+	}
+	if strings.Contains(info, "pubsub_dropped_messages:0\r\n") {
+		t.Errorf("INFO stats does not count the dropped messages:\n%s", info)
+	}
+	c.eventually([]string{"flood", "0"}, "PUBSUB", "NUMSUB", "flood")
+
+	// what was written before the limit was hit is followed by EOF
+	sub.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := io.Copy(io.Discard, sub.r); err != nil {
+		t.Errorf("subscriber connection was not closed: %v", err)
+	}
+}
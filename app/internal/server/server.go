@@ -1,30 +1,87 @@
 package server
 
 import (
-	"bufio"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"log"
 	"net"
 	"redis-go/internal/commands"
 	"redis-go/internal/helper"
 	"redis-go/internal/protocol"
-	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// Server accepts plaintext connections on Address, TLS connections on
+// TLSAddress and local connections on the UnixSocket path. Any of them may be
+// empty to disable that listener.
 type Server struct {
-	Address  string
-	Commands *commands.Registry
+	Address    string
+	TLSAddress string
+	UnixSocket string
+	Commands   *commands.Registry
+
+	tlsConfig atomic.Pointer[tls.Config]
+	clients   clientList
+
+	pauseMu sync.Mutex
+	paused  pauseState
 }
 
 func (s *Server) ListenAndServe() error {
 
-	ln, err := net.Listen("tcp", s.Address)
+	var listeners []net.Listener
+
+	if s.Address != "" {
+		ln, err := net.Listen("tcp", s.Address)
+		if err != nil {
+			return err
+		}
+		log.Printf("listening on %s", s.Address)
+		listeners = append(listeners, ln)
+	}
 
-	if err != nil {
-		return err
+	if s.TLSAddress != "" {
+		ln, err := s.listenTLS()
+		if err != nil {
+			return err
+		}
+		log.Printf("listening for TLS on %s", s.TLSAddress)
+		listeners = append(listeners, ln)
 	}
-	log.Printf("listening on %s", s.Address)
 
+	if s.UnixSocket != "" {
+		ln, err := s.listenUnix()
+		if err != nil {
+			return err
+		}
+		log.Printf("listening on unix socket %s", s.UnixSocket)
+		listeners = append(listeners, ln)
+	}
+
+	if len(listeners) == 0 {
+		return errors.New("no address to listen on")
+	}
+
+	s.watchTimeout()
+
+	backlog := s.Commands.GetConfig().Int("tcp-backlog")
+	for _, ln := range listeners {
+		if err := setBacklog(ln, backlog); err != nil {
+			log.Println("error setting tcp-backlog:", err)
+		}
+	}
+
+	for _, ln := range listeners[1:] {
+		go s.serve(ln)
+	}
+	s.serve(listeners[0])
+	return nil
+}
+
+func (s *Server) serve(ln net.Listener) {
 	for {
 		conn, err := ln.Accept()
 		if err != nil {
@@ -33,146 +90,132 @@ func (s *Server) ListenAndServe() error {
 		}
 		go s.handleConnection(conn)
 	}
-
 }
 
 func (s *Server) handleConnection(conn net.Conn) {
 	defer conn.Close()
-	fmt.Fprintf(conn, "+OK\r\n")
 
-	r := bufio.NewReader(conn)
-	w := bufio.NewWriter(conn)
-	defer w.Flush()
+	s.setKeepAlive(conn)
+
+	// Until the client is set up it has handshakeTimeout to complete the TLS
+	// handshake, and refusals must not block on a peer that does not read.
+	conn.SetDeadline(time.Now().Add(handshakeTimeout))
+
+	c := newClient(conn, s.Commands.GetDB(), s.initialUser())
+	if !s.clients.add(c, s.Commands.GetConfig().Int("maxclients")) {
+		fmt.Fprintf(conn, "-%s\r\n", errMaxClients)
+		return
+	}
+	defer s.clients.remove(c)
+
+	if tc, ok := conn.(*tls.Conn); ok {
+		if err := tc.Handshake(); err != nil {
+			log.Println("TLS handshake error:", err)
+			return
+		}
+	}
+
+	if s.denied(conn) {
+		fmt.Fprintf(conn, "-%s\r\n", errDenied)
+		return
+	}
+
+	conn.SetDeadline(time.Time{})
+	c.stateMu.Lock()
+	c.connecting = false
+	c.stateMu.Unlock()
+	fmt.Fprintf(conn, "+OK\r\n")
+	go c.forwardMessages()
+	go c.watchOutputLimit()
+	defer s.Commands.GetDB().CloseSubscriber(c.sub)
+	defer s.Commands.GetDB().DisableTracking(c.tracker)
 
 	var firstCommandIgnored bool
 
 	for {
 
-		arr, err := protocol.ReadArray(r)
+		s.setIdleDeadline(c)
+		arr, err := protocol.ReadArray(c.r)
 
+		if isTimeout(err) {
+			return
+		}
 		if err != nil {
-			fmt.Fprintf(conn, "-ERR resp parse error: %v\r\n", err)
+			c.write(fmt.Sprintf("-ERR resp parse error: %v\r\n", err))
 			return
 		}
 
 		if len(arr) == 0 {
-			conn.Write([]byte("-ERR empty command\r\n"))
+			c.write("-ERR empty command\r\n")
 			continue
 		}
 
 		cmd, args, ttl, err := helper.ParseCommand(arr)
 
 		if err != nil {
-			fmt.Fprintf(conn, "-ERR%v\r\n", err)
-			w.Flush()
+			c.write(fmt.Sprintf("-ERR%v\r\n", err))
 			continue
 		}
 
-		if cmd == "SUBSCRIBE" {
-			if len(args) < 1 {
-				fmt.Fprintf(w, "-ERR wrong number of arguments for 'subscribe' command\r\n")
-				w.Flush()
-				continue
-			}
-
-			channel := args[0]
-			subChan := s.Commands.GetDB().Subscribe(channel)
-
-			// Correct subscribe confirmation
-			fmt.Fprintf(w, "*3\r\n$9\r\nsubscribe\r\n$%d\r\n%s\r\n:1\r\n", len(channel), channel)
-			w.Flush()
-
-			log.Printf("client subscribed to channel %s", channel)
-
-			// Start listening for published messages
-			go func() {
-				for msg := range subChan {
-					fmt.Fprintf(w, "*3\r\n$7\r\nmessage\r\n$%d\r\n%s\r\n$%d\r\n%s\r\n",
-						len(channel), channel, len(msg), msg)
-					if err := w.Flush(); err != nil {
-						log.Println("subscriber flush error:", err)
-						s.Commands.GetDB().Unsubscribe(channel, subChan)
-						return
-					}
-				}
-			}()
+		// --- Ignore redis-cli's startup probe ---
+		if !firstCommandIgnored && cmd == "COMMAND" {
+			firstCommandIgnored = true
+			// log.Println("Ignoring redis-cli startup probe COMMAND DOCS")
 			continue
 		}
+		firstCommandIgnored = true
+		// ---------------------------------------
 
-		if cmd == "UNSUBSCRIBE" {
-			if len(args) < 1 {
-				fmt.Fprintf(w, "-ERR wrong number of arguments for 'unsubscribe' command\r\n")
-				w.Flush()
-				continue
-			}
+		c.startCommand(cmd)
 
-			channel := args[0]
-			s.Commands.GetDB().Unsubscribe(channel, nil) // you can manage this properly later
-			fmt.Fprintf(w, "*3\r\n$11\r\nunsubscribe\r\n$%d\r\n%s\r\n:0\r\n", len(channel), channel)
-			w.Flush()
+		if s.handleAuth(c, cmd, args) {
 			continue
 		}
 
-		if cmd == "PUBLISH" {
-			if len(args) < 2 {
-				fmt.Fprintf(w, "-ERR wrong number of arguments for 'publish' command\r\n")
-				w.Flush()
-				continue
+		if s.handleClient(c, cmd, args) {
+			if c.closeAfterReply {
+				return
 			}
-
-			channel, message := args[0], args[1]
-			count := s.Commands.GetDB().Publish(channel, message)
-			fmt.Fprintf(w, ":%d\r\n", count)
-			w.Flush()
 			continue
 		}
 
-		resp := s.Commands.Execute(cmd, args, ttl)
-
-		if cmd == "LRANGE" || cmd == "SMEMBERS" || cmd == "HGETALL" {
-			arr := strings.Split(resp, ",")
-			s.handleStringArrays(w, arr)
-			if err := w.Flush(); err != nil {
-				log.Println("flush error:", err)
-				return
-			}
-			continue
+		if !s.waitUnpaused(c, cmd) {
+			return
 		}
 
-		// --- Ignore redis-cli's startup probe ---
-		if !firstCommandIgnored && cmd == "COMMAND" {
-			firstCommandIgnored = true
-			// log.Println("Ignoring redis-cli startup probe COMMAND DOCS")
+		if s.handlePubSub(c, cmd, args) {
 			continue
 		}
-		firstCommandIgnored = true
-		// ---------------------------------------
 
-		if _, err := w.WriteString(resp); err != nil {
-			log.Println("write error:", err)
+		if cmd == "QUIT" {
+			c.write("+OK\r\n")
 			return
 		}
 
-		if err := w.Flush(); err != nil {
-			log.Println("flush error:", err)
-			return
+		if cmd == "RESET" {
+			c.mu.Lock()
+			s.unsubscribe(c, "UNSUBSCRIBE", nil)
+			s.unsubscribe(c, "PUNSUBSCRIBE", nil)
+			s.unsubscribe(c, "SUNSUBSCRIBE", nil)
+			c.replyOff, c.skipNext, c.quiet = false, false, false
+			s.stopTracking(c)
+			c.writeLocked("+RESET\r\n")
+			c.mu.Unlock()
+			c.stateMu.Lock()
+			c.user, c.noEvict = s.initialUser(), false
+			c.stateMu.Unlock()
+			continue
 		}
 
-	}
-
-}
-
-func (s *Server) handleStringArrays(w *bufio.Writer, arr []string) {
+		s.beforeExecute(c, cmd, args)
+		resp := s.Commands.Execute(c.caller(), cmd, args, ttl)
+		s.afterExecute(c)
 
-	if arr[0] == "+0\r\n" {
-		fmt.Fprintf(w, "*0\r\n")
-		return
-	}
-
-	fmt.Fprintf(w, "*%d\r\n", len(arr)) // send array header
+		if err := c.write(resp); err != nil {
+			log.Println("write error:", err)
+			return
+		}
 
-	for _, val := range arr {
-		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(val), val)
 	}
 
 }
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"bufio"
+	"net"
+	"redis-go/internal/commands"
+	"redis-go/internal/config"
+	"redis-go/internal/db"
+	"redis-go/internal/protocol"
+	"testing"
+	"time"
+)
+
+// freeAddr returns a loopback address with a port nothing listens on.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return ln.Addr().String()
+}
+
+// startServer runs a server on a free plaintext address and waits for it to
+// accept connections. The caller may set the other listener fields.
+func startServer(t *testing.T, cfg *config.Config, s *Server) *Server {
+	t.Helper()
+	s.Address = freeAddr(t)
+	s.Commands = commands.NewRegistry(db.New(), cfg)
+	go s.ListenAndServe()
+
+	for range 100 {
+		if conn, err := net.Dial("tcp", s.Address); err == nil {
+			conn.Close()
+			return s
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("server did not start on %s", s.Address)
+	return nil
+}
+
+type testConn struct {
+	t *testing.T
+	net.Conn
+	r *bufio.Reader
+}
+
+// newTestConn wraps conn and reads the server's greeting.
+func newTestConn(t *testing.T, conn net.Conn) *testConn {
+	t.Helper()
+	t.Cleanup(func() { conn.Close() })
+	c := &testConn{t: t, Conn: conn, r: bufio.NewReader(conn)}
+	if greeting := c.read(); greeting.Str != "OK" {
+		t.Fatalf("greeting = %+v", greeting)
+	}
+	return c
+}
+
+func dial(t *testing.T, addr string) *testConn {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newTestConn(t, conn)
+}
+
+func (c *testConn) read() protocol.Reply {
+	c.t.Helper()
+	c.SetReadDeadline(time.Now().Add(5 * time.Second))
+	reply, err := protocol.ReadReply(c.r)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return reply
+}
+
+// do sends a command and returns the reply.
+func (c *testConn) do(args ...string) protocol.Reply {
+	c.t.Helper()
+	if _, err := c.Write([]byte(protocol.BulkArray(args))); err != nil {
+		c.t.Fatal(err)
+	}
+	return c.read()
+}
+
+// expect sends a command and fails the test unless the reply is want, an
+// error reply being compared as "-" followed by its message.
+func (c *testConn) expect(want string, args ...string) {
+	c.t.Helper()
+	reply := c.do(args...)
+	got := reply.Str
+	if reply.IsError() {
+		got = "-" + got
+	}
+	if got != want {
+		c.t.Errorf("%q = %q, want %q", args, got, want)
+	}
+}
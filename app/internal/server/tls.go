@@ -0,0 +1,89 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"redis-go/internal/config"
+	"strings"
+)
+
+// tlsParams are the parameters the TLS configuration is built from. Setting
+// any of them reloads it.
+var tlsParams = []string{"tls-cert-file", "tls-key-file", "tls-ca-cert-file", "tls-auth-clients"}
+
+// listenTLS opens the TLS listener. Handshakes use the configuration current
+// at the time, so certificates can be replaced with CONFIG SET.
+func (s *Server) listenTLS() (net.Listener, error) {
+	cfg := s.Commands.GetConfig()
+	if err := s.reloadTLS(cfg); err != nil {
+		return nil, err
+	}
+	for _, name := range tlsParams {
+		cfg.Watch(name, func(string) {
+			if err := s.reloadTLS(cfg); err != nil {
+				log.Println("error reloading TLS configuration, keeping the previous one:", err)
+			}
+		})
+	}
+
+	ln, err := net.Listen("tcp", s.TLSAddress)
+	if err != nil {
+		return nil, err
+	}
+	return tls.NewListener(ln, &tls.Config{
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return s.tlsConfig.Load(), nil
+		},
+	}), nil
+}
+
+// reloadTLS builds the TLS configuration from cfg and, if it is valid,
+// replaces the current one.
+func (s *Server) reloadTLS(cfg *config.Config) error {
+	certFile, _ := cfg.Get("tls-cert-file")
+	keyFile, _ := cfg.Get("tls-key-file")
+	caFile, _ := cfg.Get("tls-ca-cert-file")
+	authClients, _ := cfg.Get("tls-auth-clients")
+
+	if certFile == "" || keyFile == "" {
+		return fmt.Errorf("tls-cert-file and tls-key-file must be set")
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+	tc := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	switch strings.ToLower(authClients) {
+	case "yes":
+		tc.ClientAuth = tls.RequireAndVerifyClientCert
+	case "optional":
+		tc.ClientAuth = tls.VerifyClientCertIfGiven
+	default:
+		tc.ClientAuth = tls.NoClientCert
+	}
+
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in %s", caFile)
+		}
+		tc.ClientCAs = pool
+	} else if tc.ClientAuth != tls.NoClientCert {
+		return fmt.Errorf("tls-ca-cert-file must be set to authenticate clients")
+	}
+
+	s.tlsConfig.Store(tc)
+	return nil
+}
@@ -0,0 +1,248 @@
+package server
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"redis-go/internal/config"
+	"redis-go/internal/protocol"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCert is a generated certificate and its key, PEM encoded.
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+// newTestCert generates a certificate for 127.0.0.1 signed by ca, or a self
+// signed CA certificate when ca is nil.
+func newTestCert(t *testing.T, name string, ca *testCert) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		BasicConstraintsValid: true,
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	parent, parentKey := tmpl, key
+	if ca == nil {
+		tmpl.IsCA = true
+		tmpl.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		parent, parentKey = ca.cert, ca.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+// write saves the certificate and key in dir and returns their paths.
+func (c *testCert) write(t *testing.T, dir, name string) (certFile, keyFile string) {
+	t.Helper()
+	certFile, keyFile = filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+	if err := os.WriteFile(certFile, c.certPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, c.keyPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+type tlsSetup struct {
+	cfg    *config.Config
+	srv    *Server
+	dir    string
+	ca     *testCert
+	client *testCert
+}
+
+// startTLSServer starts a server with a TLS listener whose certificate and
+// client CA come from a generated CA.
+func startTLSServer(t *testing.T, authClients string) *tlsSetup {
+	t.Helper()
+	ts := &tlsSetup{cfg: config.New(), dir: t.TempDir()}
+	ts.ca = newTestCert(t, "ca", nil)
+	ts.client = newTestCert(t, "client", ts.ca)
+
+	certFile, keyFile := newTestCert(t, "server", ts.ca).write(t, ts.dir, "server")
+	caFile, _ := ts.ca.write(t, ts.dir, "ca")
+	for name, value := range map[string]string{
+		"tls-cert-file":    certFile,
+		"tls-key-file":     keyFile,
+		"tls-ca-cert-file": caFile,
+		"tls-auth-clients": authClients,
+	} {
+		if err := ts.cfg.Set(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ts.srv = startServer(t, ts.cfg, &Server{TLSAddress: freeAddr(t)})
+	return ts
+}
+
+// dialTLS connects to the TLS listener, with a client certificate if given,
+// and returns the connection once the greeting has been read.
+func (ts *tlsSetup) dialTLS(t *testing.T, cert *testCert) (*testConn, *x509.Certificate, error) {
+	t.Helper()
+	pool := x509.NewCertPool()
+	pool.AddCert(ts.ca.cert)
+	tc := &tls.Config{RootCAs: pool, ServerName: "127.0.0.1"}
+	if cert != nil {
+		pair, err := tls.X509KeyPair(cert.certPEM, cert.keyPEM)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tc.Certificates = []tls.Certificate{pair}
+	}
+
+	conn, err := tls.Dial("tcp", ts.srv.TLSAddress, tc)
+	if err != nil {
+		return nil, nil, err
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	// with TLS 1.3 a refused client certificate shows up on the first read
+	c := &testConn{t: t, Conn: conn, r: bufio.NewReader(conn)}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := protocol.ReadReply(c.r); err != nil {
+		return nil, nil, err
+	}
+	return c, conn.ConnectionState().PeerCertificates[0], nil
+}
+
+func TestTLSClientAuth(t *testing.T) {
+	ts := startTLSServer(t, "yes")
+
+	c, _, err := ts.dialTLS(t, ts.client)
+	if err != nil {
+		t.Fatalf("with a client certificate: %v", err)
+	}
+	c.expect("PONG", "PING")
+
+	if _, _, err := ts.dialTLS(t, nil); err == nil {
+		t.Error("connected without a client certificate")
+	}
+
+	stranger := newTestCert(t, "stranger", newTestCert(t, "other ca", nil))
+	if _, _, err := ts.dialTLS(t, stranger); err == nil {
+		t.Error("connected with a certificate from another CA")
+	}
+
+	// plaintext connections are still served on port
+	dial(t, ts.srv.Address).expect("PONG", "PING")
+}
+
+func TestTLSOptionalClientAuth(t *testing.T) {
+	ts := startTLSServer(t, "optional")
+
+	for _, cert := range []*testCert{nil, ts.client} {
+		c, _, err := ts.dialTLS(t, cert)
+		if err != nil {
+			t.Fatalf("client certificate %v: %v", cert != nil, err)
+		}
+		c.expect("PONG", "PING")
+	}
+}
+
+func TestTLSReload(t *testing.T) {
+	ts := startTLSServer(t, "no")
+
+	certFile, keyFile := newTestCert(t, "renewed", ts.ca).write(t, ts.dir, "renewed")
+	dial(t, ts.srv.Address).expect("OK", "CONFIG", "SET", "tls-cert-file", certFile, "tls-key-file", keyFile)
+
+	_, peer, err := ts.dialTLS(t, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if peer.Subject.CommonName != "renewed" {
+		t.Errorf("server certificate is %q after reloading, want renewed", peer.Subject.CommonName)
+	}
+
+	// a certificate that cannot be loaded leaves the current one in place
+	ts.cfg.Set("tls-cert-file", filepath.Join(ts.dir, "missing.crt"))
+	if _, peer, err = ts.dialTLS(t, nil); err != nil {
+		t.Fatalf("after a failed reload: %v", err)
+	}
+	if peer.Subject.CommonName != "renewed" {
+		t.Errorf("server certificate is %q after a failed reload, want renewed", peer.Subject.CommonName)
+	}
+}
+
+func TestTLSHandshakeTimeout(t *testing.T) {
+	defer func(d time.Duration) { handshakeTimeout = d }(handshakeTimeout)
+	handshakeTimeout = 200 * time.Millisecond
+
+	ts := startTLSServer(t, "no")
+	if err := ts.cfg.Set("maxclients", "2"); err != nil {
+		t.Fatal(err)
+	}
+	admin := dial(t, ts.srv.Address)
+
+	// a peer that never sends a ClientHello counts towards maxclients
+	idle, err := net.Dial("tcp", ts.srv.TLSAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idle.Close()
+	for range 100 {
+		if strings.Count(admin.do("CLIENT", "LIST").Str, "\n") == 2 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	conn, err := net.Dial("tcp", ts.srv.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	reply, err := protocol.ReadReply(bufio.NewReader(conn))
+	if err != nil || reply.Str != errMaxClients {
+		t.Errorf("second client got %+v, %v, want %q", reply, err, errMaxClients)
+	}
+
+	// and is dropped once the handshake deadline passes
+	idle.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := idle.Read(make([]byte, 1)); err == nil || isTimeout(err) {
+		t.Errorf("idle TLS connection was not closed: %v", err)
+	}
+	dial(t, ts.srv.Address).expect("PONG", "PING")
+}
@@ -0,0 +1,233 @@
+package server
+
+import (
+	"redis-go/internal/commands"
+	"redis-go/internal/db"
+	"redis-go/internal/protocol"
+	"strconv"
+	"strings"
+)
+
+// tracking is a client's CLIENT TRACKING state. It is only used by the
+// command loop; the DB keeps its own copy of what it needs in the Tracker.
+type tracking struct {
+	on, bcast, optIn, optOut, noLoop bool
+
+	redirect int64 // client receiving the invalidations, 0 for itself
+	prefixes []string
+
+	// caching is the CLIENT CACHING answer for the current command, next
+	// the one for the command after it.
+	caching, next string
+}
+
+// clientTracking implements CLIENT TRACKING ON | OFF [REDIRECT id]
+// [PREFIX prefix ...] [BCAST] [OPTIN] [OPTOUT] [NOLOOP].
+func (s *Server) clientTracking(c *client, args []string) string {
+	if len(args) < 1 {
+		return "-ERR wrong number of arguments\r\n"
+	}
+
+	var t tracking
+	for i := 1; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "REDIRECT":
+			if i+1 == len(args) {
+				return protocol.Error("ERR syntax error")
+			}
+			i++
+			id, err := strconv.ParseInt(args[i], 10, 64)
+			if err != nil || id <= 0 {
+				return protocol.Error("ERR Invalid client ID")
+			}
+			t.redirect = id
+		case "PREFIX":
+			if i+1 == len(args) {
+				return protocol.Error("ERR syntax error")
+			}
+			i++
+			t.prefixes = append(t.prefixes, args[i])
+		case "BCAST":
+			t.bcast = true
+		case "OPTIN":
+			t.optIn = true
+		case "OPTOUT":
+			t.optOut = true
+		case "NOLOOP":
+			t.noLoop = true
+		default:
+			return protocol.Error("ERR syntax error")
+		}
+	}
+
+	switch strings.ToUpper(args[0]) {
+	case "OFF":
+		s.stopTracking(c)
+		return protocol.SimpleString("OK")
+	case "ON":
+	default:
+		return protocol.Error("ERR syntax error")
+	}
+
+	cur := &c.tracking
+	switch {
+	case len(t.prefixes) > 0 && !t.bcast:
+		return protocol.Error("ERR PREFIX option requires BCAST mode to be enabled")
+	case cur.on && cur.bcast != t.bcast:
+		return protocol.Error("ERR You can't switch BCAST mode on/off before disabling tracking for this client, and then re-enabling it with a different mode.")
+	case t.optIn && t.optOut:
+		return protocol.Error("ERR You can't use both OPTIN and OPTOUT")
+	case t.bcast && (t.optIn || t.optOut):
+		return protocol.Error("ERR OPTIN and OPTOUT are not compatible with BCAST")
+	case cur.on && (cur.optIn != t.optIn || cur.optOut != t.optOut):
+		return protocol.Error("ERR You can't switch OPTIN/OPTOUT mode before disabling tracking for this client, and then re-enabling it with a different mode.")
+	}
+	if err := checkPrefixes(cur.prefixes, t.prefixes); err != "" {
+		return protocol.Error(err)
+	}
+
+	target := c.sub
+	if t.redirect != 0 {
+		redir, ok := s.clients.get(t.redirect)
+		if !ok {
+			return protocol.Error("ERR The client ID you want redirect to does not exist")
+		}
+		target = redir.sub
+	}
+
+	s.Commands.GetDB().EnableTracking(c.tracker, db.TrackingOptions{
+		Target:   target,
+		BCast:    t.bcast,
+		Prefixes: t.prefixes,
+		NoLoop:   t.noLoop,
+	})
+	t.on = true
+	t.prefixes = append(cur.prefixes, t.prefixes...)
+	*cur = t
+	return protocol.SimpleString("OK")
+}
+
+// checkPrefixes returns an error if two broadcast prefixes overlap, one
+// being a prefix of the other.
+func checkPrefixes(existing, added []string) string {
+	for i, p := range added {
+		for _, q := range existing {
+			if strings.HasPrefix(p, q) || strings.HasPrefix(q, p) {
+				return "ERR Prefix '" + p + "' overlaps with an existing prefix '" + q + "'. Prefixes for a single client must not overlap."
+			}
+		}
+		for _, q := range added[i+1:] {
+			if strings.HasPrefix(p, q) || strings.HasPrefix(q, p) {
+				return "ERR Prefix '" + p + "' overlaps with another provided prefix '" + q + "'. Prefixes for a single client must not overlap."
+			}
+		}
+	}
+	return ""
+}
+
+func (s *Server) stopTracking(c *client) {
+	s.Commands.GetDB().DisableTracking(c.tracker)
+	c.tracking = tracking{}
+}
+
+// clientCaching implements CLIENT CACHING YES | NO, which opts the next
+// command in or out of tracking.
+func (s *Server) clientCaching(c *client, args []string) string {
+	if len(args) != 1 {
+		return "-ERR wrong number of arguments\r\n"
+	}
+	t := &c.tracking
+	if !t.on || (!t.optIn && !t.optOut) {
+		return protocol.Error("ERR CLIENT CACHING can be called only when the client is in tracking mode with OPTIN or OPTOUT mode enabled")
+	}
+	switch strings.ToLower(args[0]) {
+	case "yes":
+		if !t.optIn {
+			return protocol.Error("ERR CLIENT CACHING YES is only valid when tracking is enabled in OPTIN mode.")
+		}
+	case "no":
+		if !t.optOut {
+			return protocol.Error("ERR CLIENT CACHING NO is only valid when tracking is enabled in OPTOUT mode.")
+		}
+	default:
+		return protocol.Error("ERR syntax error")
+	}
+	t.next = strings.ToLower(args[0])
+	return protocol.SimpleString("OK")
+}
+
+// redirectID is what CLIENT GETREDIR reports: -1 when tracking is off, 0
+// when invalidations go to the client itself.
+func (c *client) redirectID() int {
+	if !c.tracking.on {
+		return -1
+	}
+	return int(c.tracking.redirect)
+}
+
+// trackingInfo implements CLIENT TRACKINGINFO.
+func (s *Server) trackingInfo(c *client) string {
+	t := &c.tracking
+	var flags []string
+	if !t.on {
+		flags = append(flags, "off")
+	} else {
+		flags = append(flags, "on")
+		for _, f := range []struct {
+			set  bool
+			name string
+		}{
+			{t.bcast, "bcast"},
+			{t.optIn, "optin"},
+			{t.optOut, "optout"},
+			{t.caching == "yes", "caching-yes"},
+			{t.caching == "no", "caching-no"},
+			{t.noLoop, "noloop"},
+		} {
+			if f.set {
+				flags = append(flags, f.name)
+			}
+		}
+		if t.redirect != 0 {
+			if _, ok := s.clients.get(t.redirect); !ok {
+				flags = append(flags, "broken_redirect")
+			}
+		}
+	}
+
+	return protocol.Array(
+		protocol.BulkString("flags"), protocol.BulkArray(flags),
+		protocol.BulkString("redirect"), protocol.Integer(c.redirectID()),
+		protocol.BulkString("prefixes"), protocol.BulkArray(t.prefixes),
+	)
+}
+
+// beforeExecute tells the DB which keys the command reads and writes. In
+// the default mode the keys a read command returns to the client are
+// tracked, and noloop clients are not sent invalidations for their own
+// writes.
+func (s *Server) beforeExecute(c *client, cmd string, args []string) {
+	t := &c.tracking
+	if !t.on {
+		return
+	}
+
+	var reads, writes []string
+	if !t.bcast && commands.InCategory(cmd, "read") &&
+		!(t.optIn && t.caching != "yes") && !(t.optOut && t.caching == "no") {
+		reads = commands.CommandKeys(cmd, args)
+	}
+	if t.noLoop && commands.InCategory(cmd, "write") {
+		writes = commands.CommandKeys(cmd, args)
+	}
+	if len(reads)+len(writes) > 0 {
+		s.Commands.GetDB().BeginCommand(c.tracker, reads, writes)
+	}
+}
+
+// afterExecute ends what beforeExecute began.
+func (s *Server) afterExecute(c *client) {
+	if c.tracking.on {
+		s.Commands.GetDB().EndCommand(c.tracker)
+	}
+}
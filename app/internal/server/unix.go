@@ -0,0 +1,36 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+)
+
+// listenUnix opens the Unix socket listener, removing a socket file left
+// behind by a previous run, and applies unixsocketperm.
+func (s *Server) listenUnix() (net.Listener, error) {
+	if fi, err := os.Lstat(s.UnixSocket); err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", s.UnixSocket)
+		}
+		if err := os.Remove(s.UnixSocket); err != nil {
+			return nil, err
+		}
+	}
+
+	ln, err := net.Listen("unix", s.UnixSocket)
+	if err != nil {
+		return nil, err
+	}
+
+	// 0 leaves the permissions to the umask
+	perm, _ := s.Commands.GetConfig().Get("unixsocketperm")
+	if mode, _ := strconv.ParseUint(perm, 8, 32); mode != 0 {
+		if err := os.Chmod(s.UnixSocket, os.FileMode(mode)); err != nil {
+			ln.Close()
+			return nil, err
+		}
+	}
+	return ln, nil
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"redis-go/internal/commands"
+	"redis-go/internal/config"
+	"redis-go/internal/db"
+	"strings"
+	"testing"
+	"time"
+)
+
+// dialUnix connects to the server's Unix socket once it is listening.
+func dialUnix(t *testing.T, path string) *testConn {
+	t.Helper()
+	for range 100 {
+		if conn, err := net.Dial("unix", path); err == nil {
+			return newTestConn(t, conn)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("server did not listen on %s", path)
+	return nil
+}
+
+func TestUnixSocketReplacesStaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kvd.sock")
+
+	// a previous run that died without removing its socket
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	ln.Close()
+	if _, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.New()
+	if err := cfg.Set("unixsocketperm", "700"); err != nil {
+		t.Fatal(err)
+	}
+	startServer(t, cfg, &Server{UnixSocket: path})
+
+	c := dialUnix(t, path)
+	c.expect("OK", "SET", "k", "v")
+	c.expect("v", "GET", "k")
+	if list := c.do("CLIENT", "LIST").Str; !strings.Contains(list, "addr="+path+":0 ") {
+		t.Errorf("Unix socket client is listed as %q", list)
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := fi.Mode().Perm(); perm != 0o700 {
+		t.Errorf("socket permissions = %o, want 700", perm)
+	}
+}
+
+func TestUnixSocketKeepsOtherFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kvd.sock")
+	if err := os.WriteFile(path, []byte("data"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Server{UnixSocket: path, Commands: commands.NewRegistry(db.New(), config.New())}
+	if err := s.ListenAndServe(); err == nil || !strings.Contains(err.Error(), "is not a socket") {
+		t.Errorf("ListenAndServe = %v, want an error about the regular file", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "data" {
+		t.Error("the regular file was replaced")
+	}
+}